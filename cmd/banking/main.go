@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/ibihim/banking-csv-cli/pkg/cmd"
 )
@@ -9,5 +10,6 @@ import (
 func main() {
 	if err := cmd.BankingCommand().Execute(); err != nil {
 		fmt.Println(err)
+		os.Exit(1)
 	}
 }
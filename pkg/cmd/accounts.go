@@ -0,0 +1,277 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ibihim/banking-csv-cli/pkg/transactions"
+)
+
+const (
+	ibanFlag           = "iban"
+	bankFlag           = "bank"
+	nicknameFlag       = "nickname"
+	ownerFlag          = "owner"
+	currencyFlag       = "currency"
+	typeFlag           = "type"
+	openingBalanceFlag = "opening-balance"
+	openingDateFlag    = "opening-date"
+)
+
+// accountsCommand returns the `accounts` command and its subcommands.
+func accountsCommand() *cobra.Command {
+	accountsCmd := &cobra.Command{
+		Use:   "accounts",
+		Short: "Manage the accounts that transactions are booked on",
+	}
+	accountsCmd.PersistentFlags().String(dbFlag, defaultDBPath, "Path to the database file, or a postgres:// DSN")
+
+	addCmd := &cobra.Command{
+		Use:   "add <iban>",
+		Short: "Add an account",
+		Long: `Add an account.
+
+The IBAN is the account number of the transactions of the account. Accounts
+without an IBAN, like cash, use any unique identifier instead.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			account := &transactions.Account{
+				IBAN:     transactions.NormalizeIBAN(args[0]),
+				Currency: "EUR",
+				Type:     transactions.AccountChecking,
+			}
+			if err := accountFromFlags(cmd, account); err != nil {
+				return err
+			}
+
+			return runDatastoreCommand(cmd, func(db TransactionDatastore) error {
+				if err := checkAccountNames(db, account); err != nil {
+					return err
+				}
+				if _, err := db.AddAccount(account); err != nil {
+					return fmt.Errorf("failed to add account: %w", err)
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "added account %d: %s\n", account.ID, account.Name())
+
+				return nil
+			})
+		},
+	}
+	addAccountFlags(addCmd)
+	accountsCmd.AddCommand(addCmd)
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the accounts",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			output, err := cmd.Flags().GetString(outputFlag)
+			if err != nil {
+				return fmt.Errorf("failed to get outputFlag: %w", err)
+			}
+
+			return runDatastoreCommand(cmd, func(db TransactionDatastore) error {
+				accounts, err := db.GetAccounts()
+				if err != nil {
+					return fmt.Errorf("failed to load accounts: %w", err)
+				}
+
+				return writeAccounts(cmd.OutOrStdout(), accounts, output)
+			})
+		},
+	}
+	listCmd.Flags().String(outputFlag, outputTable, fmt.Sprintf("The output format, one of: %s, %s", outputTable, outputJSON))
+	accountsCmd.AddCommand(listCmd)
+
+	editCmd := &cobra.Command{
+		Use:   "edit <iban|nickname>",
+		Short: "Change the flags that are given of an account",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDatastoreCommand(cmd, func(db TransactionDatastore) error {
+				account, err := db.GetAccount(args[0])
+				if err != nil {
+					return fmt.Errorf("failed to load account %q: %w", args[0], err)
+				}
+
+				if cmd.Flags().Changed(ibanFlag) {
+					iban, err := cmd.Flags().GetString(ibanFlag)
+					if err != nil {
+						return fmt.Errorf("failed to get ibanFlag: %w", err)
+					}
+					account.IBAN = transactions.NormalizeIBAN(iban)
+				}
+				if err := accountFromFlags(cmd, account); err != nil {
+					return err
+				}
+				if err := checkAccountNames(db, account); err != nil {
+					return err
+				}
+
+				if err := db.UpdateAccount(account); err != nil {
+					return fmt.Errorf("failed to update account: %w", err)
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "updated account %d: %s\n", account.ID, account.Name())
+
+				return nil
+			})
+		},
+	}
+	editCmd.Flags().String(ibanFlag, "", "The IBAN of the account")
+	addAccountFlags(editCmd)
+	accountsCmd.AddCommand(editCmd)
+
+	return accountsCmd
+}
+
+// addAccountFlags adds the flags that set the fields of an account, see
+// accountFromFlags.
+func addAccountFlags(cmd *cobra.Command) {
+	types := make([]string, 0, len(transactions.AccountTypes))
+	for _, t := range transactions.AccountTypes {
+		types = append(types, string(t))
+	}
+
+	cmd.Flags().String(bankFlag, "", "The name of the bank")
+	cmd.Flags().String(nicknameFlag, "", "A short, unique name of the account, like Giro")
+	cmd.Flags().String(ownerFlag, "", "The owner of the account")
+	cmd.Flags().String(currencyFlag, "EUR", "The currency of the account")
+	cmd.Flags().String(typeFlag, string(transactions.AccountChecking), fmt.Sprintf("The type of the account, one of: %s", strings.Join(types, ", ")))
+	cmd.Flags().String(openingBalanceFlag, "", "The balance at the opening date, e.g. 1234.56")
+	cmd.Flags().String(openingDateFlag, "", "The date of the opening balance, as YYYY-MM-DD")
+}
+
+// accountFromFlags sets the fields of the account whose flags were given.
+func accountFromFlags(cmd *cobra.Command, a *transactions.Account) error {
+	for flag, field := range map[string]*string{
+		bankFlag:     &a.Bank,
+		nicknameFlag: &a.Nickname,
+		ownerFlag:    &a.Owner,
+		currencyFlag: &a.Currency,
+	} {
+		if !cmd.Flags().Changed(flag) {
+			continue
+		}
+		s, err := cmd.Flags().GetString(flag)
+		if err != nil {
+			return fmt.Errorf("failed to get %sFlag: %w", flag, err)
+		}
+		*field = strings.TrimSpace(s)
+	}
+	a.Currency = strings.ToUpper(a.Currency)
+
+	if cmd.Flags().Changed(typeFlag) {
+		s, err := cmd.Flags().GetString(typeFlag)
+		if err != nil {
+			return fmt.Errorf("failed to get typeFlag: %w", err)
+		}
+		if a.Type, err = transactions.ParseAccountType(s); err != nil {
+			return err
+		}
+	}
+
+	if cmd.Flags().Changed(openingDateFlag) {
+		s, err := cmd.Flags().GetString(openingDateFlag)
+		if err != nil {
+			return fmt.Errorf("failed to get openingDateFlag: %w", err)
+		}
+		a.OpeningDate = time.Time{}
+		if s != "" {
+			if a.OpeningDate, err = time.Parse("2006-01-02", s); err != nil {
+				return fmt.Errorf("invalid --%s: %w", openingDateFlag, err)
+			}
+		}
+	}
+
+	balance := a.OpeningBalance.String()
+	if cmd.Flags().Changed(openingBalanceFlag) {
+		var err error
+		if balance, err = cmd.Flags().GetString(openingBalanceFlag); err != nil {
+			return fmt.Errorf("failed to get openingBalanceFlag: %w", err)
+		}
+	}
+	// The balance is parsed again, as the currency may have changed.
+	var err error
+	if a.OpeningBalance, err = transactions.ParseMoney(balance, a.Currency); err != nil {
+		return fmt.Errorf("invalid --%s: %w", openingBalanceFlag, err)
+	}
+
+	return nil
+}
+
+// checkAccountNames returns an error if the nickname of the account is the
+// IBAN of another account, or its IBAN the nickname of another one. Accounts
+// are looked up by both, so either would be ambiguous.
+func checkAccountNames(db TransactionDatastore, a *transactions.Account) error {
+	accounts, err := db.GetAccounts()
+	if err != nil {
+		return fmt.Errorf("failed to load accounts: %w", err)
+	}
+
+	for _, other := range accounts {
+		if other.ID == a.ID {
+			continue
+		}
+		if a.Nickname != "" && transactions.NormalizeIBAN(a.Nickname) == other.IBAN {
+			return fmt.Errorf("nickname %q is the IBAN of account %d", a.Nickname, other.ID)
+		}
+		if other.Nickname != "" && transactions.NormalizeIBAN(other.Nickname) == a.IBAN {
+			return fmt.Errorf("IBAN %s is the nickname of account %d", a.IBAN, other.ID)
+		}
+	}
+
+	return nil
+}
+
+// runDatastoreCommand runs fn with the datastore of the --db flag.
+func runDatastoreCommand(cmd *cobra.Command, fn func(db TransactionDatastore) error) error {
+	dbPath, err := cmd.Flags().GetString(dbFlag)
+	if err != nil {
+		return fmt.Errorf("failed to get dbFlag: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	db, err := openDatastore(ctx, dbPath, true)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return fn(db)
+}
+
+// writeAccounts writes the accounts, either as table or as JSON.
+func writeAccounts(w io.Writer, accounts []*transactions.Account, output string) error {
+	switch output {
+	case outputJSON:
+		if accounts == nil {
+			accounts = []*transactions.Account{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(accounts)
+	case outputTable:
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tNICKNAME\tIBAN\tBANK\tOWNER\tTYPE\tOPENING BALANCE\tOPENING DATE")
+		for _, a := range accounts {
+			openingDate := ""
+			if !a.OpeningDate.IsZero() {
+				openingDate = a.OpeningDate.Format("2006-01-02")
+			}
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\t%s %s\t%s\n",
+				a.ID, a.Nickname, a.IBAN, a.Bank, a.Owner, a.Type, a.OpeningBalance, a.Currency, openingDate,
+			)
+		}
+		return tw.Flush()
+	}
+
+	return fmt.Errorf("unknown output %q, expected one of: %s, %s", output, outputTable, outputJSON)
+}
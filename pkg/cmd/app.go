@@ -8,8 +8,7 @@ import (
 	"github.com/ibihim/banking-csv-cli/pkg/transactions"
 )
 
-func RunApp(ts []*transactions.Transaction) error {
-	summary := transactions.NewSummary(ts)
+func RunApp(summary *transactions.Sum) error {
 	table := table.NewTable(summary)
 
 	if _, err := tea.NewProgram(table).Run(); err != nil {
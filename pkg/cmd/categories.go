@@ -0,0 +1,200 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ibihim/banking-csv-cli/pkg/transactions"
+)
+
+// categoriesCommand returns the `categories` command and its subcommands.
+func categoriesCommand() *cobra.Command {
+	categoriesCmd := &cobra.Command{
+		Use:   "categories",
+		Short: "Categorize transactions along a tree, like Wohnen/Miete",
+		Long: fmt.Sprintf(`Categorize transactions along a tree, like Wohnen/Miete.
+
+Each transaction has one category, transactions without one are %s.
+Like labels, categories attach to the fingerprints of transactions, so they
+survive reverting and importing a file again. Transactions are selected by
+their ids, by the query flags, or both.
+
+"categories seed" adds a taxonomy of German households.`, transactions.Uncategorized),
+	}
+	categoriesCmd.PersistentFlags().String(dbFlag, defaultDBPath, "Path to the database file, or a postgres:// DSN")
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the categories and the number of their transactions",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			output, err := cmd.Flags().GetString(outputFlag)
+			if err != nil {
+				return fmt.Errorf("failed to get outputFlag: %w", err)
+			}
+
+			return runDatastoreCommand(cmd, func(db TransactionDatastore) error {
+				categories, err := db.GetCategories()
+				if err != nil {
+					return fmt.Errorf("failed to load categories: %w", err)
+				}
+
+				return writeCategories(cmd.OutOrStdout(), categories, output)
+			})
+		},
+	}
+	listCmd.Flags().String(outputFlag, outputTable, fmt.Sprintf("The output format, one of: %s, %s", outputTable, outputJSON))
+	categoriesCmd.AddCommand(listCmd)
+
+	addCmd := &cobra.Command{
+		Use:   "add <category>",
+		Short: "Add a category and its missing parents, like Wohnen/Garten",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDatastoreCommand(cmd, func(db TransactionDatastore) error {
+				added, err := db.AddCategory(args[0])
+				if err != nil {
+					return fmt.Errorf("failed to add category: %w", err)
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "added %d categories\n", added)
+
+				return nil
+			})
+		},
+	}
+	categoriesCmd.AddCommand(addCmd)
+
+	seedCmd := &cobra.Command{
+		Use:   "seed",
+		Short: "Add the categories of a German household that are missing",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDatastoreCommand(cmd, func(db TransactionDatastore) error {
+				total := 0
+				for _, path := range transactions.DefaultCategories {
+					added, err := db.AddCategory(path)
+					if err != nil {
+						return fmt.Errorf("failed to add category %q: %w", path, err)
+					}
+					total += added
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "added %d categories\n", total)
+
+				return nil
+			})
+		},
+	}
+	categoriesCmd.AddCommand(seedCmd)
+
+	setCmd := &cobra.Command{
+		Use:   "set <category> [id]...",
+		Short: "Set the category of transactions, replacing their former one",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, err := parseCategoryFlag(args[0])
+			if err != nil {
+				return err
+			}
+
+			return runDatastoreCommand(cmd, func(db TransactionDatastore) error {
+				ids, err := selectTransactions(cmd, db, args[1:])
+				if err != nil {
+					return err
+				}
+				if path == transactions.Uncategorized {
+					return uncategorize(cmd.OutOrStdout(), db, ids)
+				}
+				categorized, err := db.CategorizeTransactions(path, ids, true)
+				if err != nil {
+					return fmt.Errorf("failed to categorize transactions: %w", err)
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "set the category of %d of %d transactions to %s\n", categorized, len(ids), path)
+
+				return nil
+			})
+		},
+	}
+	addQueryFlags(setCmd)
+	categoriesCmd.AddCommand(setCmd)
+
+	unsetCmd := &cobra.Command{
+		Use:   "unset [id]...",
+		Short: "Remove the category of transactions",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDatastoreCommand(cmd, func(db TransactionDatastore) error {
+				ids, err := selectTransactions(cmd, db, args)
+				if err != nil {
+					return err
+				}
+
+				return uncategorize(cmd.OutOrStdout(), db, ids)
+			})
+		},
+	}
+	addQueryFlags(unsetCmd)
+	categoriesCmd.AddCommand(unsetCmd)
+
+	deleteCmd := &cobra.Command{
+		Use:   "delete <category>",
+		Short: "Delete a category and its subcategories, their transactions become uncategorized",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, err := parseCategoryFlag(args[0])
+			if err != nil {
+				return err
+			}
+
+			return runDatastoreCommand(cmd, func(db TransactionDatastore) error {
+				if err := db.DeleteCategory(path); err != nil {
+					return fmt.Errorf("failed to delete category: %w", err)
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "deleted category %s\n", path)
+
+				return nil
+			})
+		},
+	}
+	categoriesCmd.AddCommand(deleteCmd)
+
+	return categoriesCmd
+}
+
+// uncategorize removes the category of the transactions.
+func uncategorize(w io.Writer, db TransactionDatastore, ids []int64) error {
+	uncategorized, err := db.UncategorizeTransactions(ids)
+	if err != nil {
+		return fmt.Errorf("failed to uncategorize transactions: %w", err)
+	}
+	fmt.Fprintf(w, "removed the category of %d of %d transactions\n", uncategorized, len(ids))
+
+	return nil
+}
+
+// writeCategories writes the categories, either as an indented table or as
+// JSON.
+func writeCategories(w io.Writer, categories []*transactions.Category, output string) error {
+	switch output {
+	case outputJSON:
+		if categories == nil {
+			categories = []*transactions.Category{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(categories)
+	case outputTable:
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tCATEGORY\tTRANSACTIONS")
+		for _, c := range categories {
+			depth := strings.Count(c.Path, transactions.CategorySeparator)
+			fmt.Fprintf(tw, "%d\t%s%s\t%d\n", c.ID, strings.Repeat("  ", depth), c.Name, c.Transactions)
+		}
+		return tw.Flush()
+	}
+
+	return fmt.Errorf("unknown output %q, expected one of: %s, %s", output, outputTable, outputJSON)
+}
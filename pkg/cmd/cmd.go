@@ -2,31 +2,49 @@ package cmd
 
 import (
 	"context"
-	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/tabwriter"
 	"time"
 
-	_ "github.com/golang-migrate/migrate/v4/source/file"
 	"github.com/spf13/cobra"
 	"k8s.io/klog"
 
-	"github.com/ibihim/banking-csv-cli/pkg/sql"
+	"github.com/ibihim/banking-csv-cli/pkg/exporter"
+	"github.com/ibihim/banking-csv-cli/pkg/importer"
+	"github.com/ibihim/banking-csv-cli/pkg/transactions"
 )
 
 const (
 	defaultDBPath = "./transactions.db"
 
-	filenameFlag   = "filename"
-	dbFlag         = "db"
-	migrationsFlag = "migrations"
+	filenameFlag    = "filename"
+	formatFlag      = "format"
+	accountFlag     = "account"
+	encodingFlag    = "encoding"
+	lenientFlag     = "lenient"
+	onDuplicateFlag = "on-duplicate"
+	forceFlag       = "force"
+	dryRunFlag      = "dry-run"
+	outputFlag      = "output"
+
+	outputTable = "table"
+	outputJSON  = "json"
+	dbFlag      = "db"
+	allFlag     = "all"
 )
 
 func BankingCommand() *cobra.Command {
 	rootCmd := &cobra.Command{
 		Use:   "banking",
 		Short: "A tool to parse banking csv files",
+		// Errors of a command are not caused by its usage most of the time.
+		SilenceUsage: true,
 		PersistentPreRun: func(cmd *cobra.Command, args []string) {
 			flag.CommandLine.VisitAll(func(flag *flag.Flag) {
 				klog.V(4).Infof("Flag: --%s=%q", flag.Name, flag.Value)
@@ -47,30 +65,126 @@ func BankingCommand() *cobra.Command {
 			if err != nil {
 				return fmt.Errorf("failed to get dbFlag: %w", err)
 			}
+			query, err := queryFromFlags(cmd)
+			if err != nil {
+				return err
+			}
+			byAccount, err := cmd.Flags().GetBool(byAccountFlag)
+			if err != nil {
+				return fmt.Errorf("failed to get byAccountFlag: %w", err)
+			}
+			byCategory, err := cmd.Flags().GetBool(byCategoryFlag)
+			if err != nil {
+				return fmt.Errorf("failed to get byCategoryFlag: %w", err)
+			}
 
 			// Init db
 			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
 			defer cancel()
-			db := sql.NewDatabase(&sql.DatabaseOptions{
-				URL: dbPath,
-			})
-			if err := db.Connect(ctx); err != nil {
-				return fmt.Errorf("failed on db connect: %w", err)
+			db, err := openDatastore(ctx, dbPath, true)
+			if err != nil {
+				return err
 			}
 			defer db.Close()
 
 			// Load transactions
-			ts, err := db.GetTransactions()
+			if err := resolveAccount(db, query); err != nil {
+				return err
+			}
+			page, err := db.QueryTransactions(query)
 			if err != nil {
 				return fmt.Errorf("failed to load transactions: %w", err)
 			}
 
-			return RunApp(ts)
+			if byCategory {
+				return RunApp(transactions.NewCategorySummary(page.Transactions))
+			}
+			if !byAccount {
+				return RunApp(transactions.NewSummary(page.Transactions))
+			}
+			accounts, err := db.GetAccounts()
+			if err != nil {
+				return fmt.Errorf("failed to load accounts: %w", err)
+			}
+
+			return RunApp(transactions.NewAccountSummary(page.Transactions, accounts))
 		},
 	}
-	appCmd.Flags().String(dbFlag, defaultDBPath, "Path to the database file")
+	appCmd.Flags().String(dbFlag, defaultDBPath, "Path to the database file, or a postgres:// DSN")
+	appCmd.Flags().Bool(byAccountFlag, false, "Group the transactions by account first")
+	appCmd.Flags().Bool(byCategoryFlag, false, "Group the transactions along the category tree first")
+	appCmd.MarkFlagsMutuallyExclusive(byAccountFlag, byCategoryFlag)
+	addQueryFlags(appCmd)
 	rootCmd.AddCommand(appCmd)
 
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List transactions",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dbPath, err := cmd.Flags().GetString(dbFlag)
+			if err != nil {
+				return fmt.Errorf("failed to get dbFlag: %w", err)
+			}
+			output, err := cmd.Flags().GetString(outputFlag)
+			if err != nil {
+				return fmt.Errorf("failed to get outputFlag: %w", err)
+			}
+			query, err := queryFromFlags(cmd)
+			if err != nil {
+				return err
+			}
+			sort, err := cmd.Flags().GetString(sortFlag)
+			if err != nil {
+				return fmt.Errorf("failed to get sortFlag: %w", err)
+			}
+			if query.Sort, err = transactions.ParseSortOrder(sort); err != nil {
+				return err
+			}
+			if query.Limit, err = cmd.Flags().GetInt(limitFlag); err != nil {
+				return fmt.Errorf("failed to get limitFlag: %w", err)
+			}
+			if query.Offset, err = cmd.Flags().GetInt(offsetFlag); err != nil {
+				return fmt.Errorf("failed to get offsetFlag: %w", err)
+			}
+			if query.Cursor, err = cmd.Flags().GetString(cursorFlag); err != nil {
+				return fmt.Errorf("failed to get cursorFlag: %w", err)
+			}
+
+			// Init db
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+			defer cancel()
+			db, err := openDatastore(ctx, dbPath, true)
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			if err := resolveAccount(db, query); err != nil {
+				return err
+			}
+			page, err := db.QueryTransactions(query)
+			if err != nil {
+				return fmt.Errorf("failed to query transactions: %w", err)
+			}
+
+			return writeTransactions(cmd.OutOrStdout(), page, output)
+		},
+	}
+	listCmd.Flags().String(dbFlag, defaultDBPath, "Path to the database file, or a postgres:// DSN")
+	addQueryFlags(listCmd)
+	listCmd.Flags().String(sortFlag, string(transactions.SortDate), "The order of the transactions, e.g. date, -date, amount, -amount, beneficiary, -beneficiary")
+	listCmd.Flags().Int(limitFlag, 50, "The maximum number of transactions, 0 for all")
+	listCmd.Flags().Int(offsetFlag, 0, "The number of transactions to skip")
+	listCmd.Flags().String(cursorFlag, "", "Continue after the page that returned the cursor")
+	listCmd.Flags().String(outputFlag, outputTable, fmt.Sprintf("The output format, one of: %s, %s", outputTable, outputJSON))
+	rootCmd.AddCommand(listCmd)
+
+	rootCmd.AddCommand(accountsCommand())
+	rootCmd.AddCommand(reconcileCommand())
+	rootCmd.AddCommand(labelsCommand())
+	rootCmd.AddCommand(rulesCommand())
+	rootCmd.AddCommand(categoriesCommand())
+
 	// dbCmd represents the `db` subcommand
 	dbCmd := &cobra.Command{
 		Use:   "db",
@@ -79,90 +193,365 @@ func BankingCommand() *cobra.Command {
 	rootCmd.AddCommand(dbCmd)
 
 	loadCmd := &cobra.Command{
-		Use:   "load",
+		Use:   "load [file|directory|pattern]...",
 		Short: "Load transactions into the database",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			dbPath, err := cmd.Flags().GetString(dbFlag)
+			if err != nil {
+				return fmt.Errorf("failed to get dbFlag: %w", err)
+			}
 			filename, err := cmd.Flags().GetString(filenameFlag)
 			if err != nil {
 				return err
 			}
+			format, err := cmd.Flags().GetString(formatFlag)
+			if err != nil {
+				return fmt.Errorf("failed to get formatFlag: %w", err)
+			}
+			account, err := cmd.Flags().GetString(accountFlag)
+			if err != nil {
+				return fmt.Errorf("failed to get accountFlag: %w", err)
+			}
+			encoding, err := cmd.Flags().GetString(encodingFlag)
+			if err != nil {
+				return fmt.Errorf("failed to get encodingFlag: %w", err)
+			}
+			lenient, err := cmd.Flags().GetBool(lenientFlag)
+			if err != nil {
+				return fmt.Errorf("failed to get lenientFlag: %w", err)
+			}
+			onDuplicate, err := cmd.Flags().GetString(onDuplicateFlag)
+			if err != nil {
+				return fmt.Errorf("failed to get onDuplicateFlag: %w", err)
+			}
+			policy, err := parseDuplicatePolicy(onDuplicate)
+			if err != nil {
+				return err
+			}
+			force, err := cmd.Flags().GetBool(forceFlag)
+			if err != nil {
+				return fmt.Errorf("failed to get forceFlag: %w", err)
+			}
+			dryRun, err := cmd.Flags().GetBool(dryRunFlag)
+			if err != nil {
+				return fmt.Errorf("failed to get dryRunFlag: %w", err)
+			}
+			output, err := cmd.Flags().GetString(outputFlag)
+			if err != nil {
+				return fmt.Errorf("failed to get outputFlag: %w", err)
+			}
+			if output != outputTable && output != outputJSON {
+				return fmt.Errorf("unknown output %q, expected one of: %s, %s", output, outputTable, outputJSON)
+			}
 
-			reader, err := os.Open(filename)
+			rs, err := loadRules(cmd)
 			if err != nil {
-				return fmt.Errorf("failed to open file: %w", err)
+				return err
 			}
-			defer reader.Close()
 
-			ts, err := ParseTransactions(reader)
+			if filename != "" {
+				args = append([]string{filename}, args...)
+			}
+			if len(args) == 0 {
+				return fmt.Errorf("no files given, pass them as arguments or with --%s", filenameFlag)
+			}
+			filenames, err := expandFilenames(args)
 			if err != nil {
-				return fmt.Errorf("failed to parse transactions: %w", err)
+				return err
+			}
+
+			opts := &loadOptions{
+				format:   format,
+				account:  account,
+				encoding: encoding,
+				lenient:  lenient,
+				force:    force,
+				policy:   policy,
+				dryRun:   dryRun,
+				rules:    rs,
+			}
+			files := parseFiles(filenames, opts)
+			for _, f := range files {
+				if f.statement == nil {
+					continue
+				}
+				for _, e := range f.statement.Rejected {
+					fmt.Fprintf(cmd.ErrOrStderr(), "%s: %v\n", f.filename, e)
+				}
 			}
 
 			// Init db
 			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
 			defer cancel()
-			db := sql.NewDatabase(&sql.DatabaseOptions{
-				URL: defaultDBPath,
-			})
-			if err := db.Connect(ctx); err != nil {
-				return fmt.Errorf("failed on db connect: %w", err)
+			var db TransactionDatastore
+			if dryRun {
+				db, err = openCurrentDatastore(ctx, dbPath)
+			} else {
+				db, err = openDatastore(ctx, dbPath, true)
+			}
+			if err != nil {
+				return err
 			}
 			defer db.Close()
 
-			for _, t := range ts {
-				ok, err := db.HasTransaction(t)
-				if err != nil {
-					return fmt.Errorf("failed to check if transaction exists: %w", err)
-				}
-				if ok {
-					b, err := json.Marshal(t)
-					if err != nil {
-						return fmt.Errorf("failed to marshal transaction: %w", err)
-					}
-					klog.Warningf("duplicate transaction: %s", string(b))
+			loadErr := loadFiles(db, files, opts)
+			if dryRun {
+				if err := writeLoadPreview(cmd.OutOrStdout(), files, output); err != nil {
+					return fmt.Errorf("failed to write preview: %w", err)
 				}
+			} else if err := writeLoadSummary(cmd.OutOrStdout(), files); err != nil {
+				return fmt.Errorf("failed to write summary: %w", err)
+			}
+			if loadErr != nil {
+				return loadErr
+			}
+
+			return loadError(files)
+		},
+	}
+
+	loadCmd.Flags().String(dbFlag, defaultDBPath, "Path to the database file, or a postgres:// DSN")
+	loadCmd.Flags().String(filenameFlag, "", "The path to a bank statement file, directory or pattern")
+	loadCmd.Flags().String(formatFlag, importer.FormatAuto, fmt.Sprintf("The format of the files, one of: %s, %s", importer.FormatAuto, strings.Join(importer.Names(), ", ")))
+	loadCmd.Flags().String(accountFlag, "", "The account of the transactions, if a file does not contain it")
+	loadCmd.Flags().String(onDuplicateFlag, string(transactions.DuplicateSkip), fmt.Sprintf("What to do with known transactions, one of: %s", joinDuplicatePolicies()))
+	loadCmd.Flags().Bool(dryRunFlag, false, "Show which transactions are new, duplicate or conflicting, without importing them or migrating the database")
+	loadCmd.Flags().String(outputFlag, outputTable, fmt.Sprintf("The output of --%s, one of: %s, %s", dryRunFlag, outputTable, outputJSON))
+	loadCmd.Flags().Bool(forceFlag, false, "Import a file even if it was imported before")
+	loadCmd.Flags().Bool(lenientFlag, false, "Import the valid records of a file and write the rejected ones to <filename>.rejected.csv")
+	loadCmd.Flags().String(rulesFlag, defaultRulesPath, "Path to the YAML file of the rules that label and categorize the imported transactions")
+	loadCmd.Flags().String(encodingFlag, importer.EncodingAuto, fmt.Sprintf("The encoding of the files, one of: %s, %s", importer.EncodingAuto, strings.Join(importer.Encodings(), ", ")))
+	dbCmd.AddCommand(loadCmd)
 
-				_, err = db.AddTransaction(t)
+	exportCmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export transactions from the database",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dbPath, err := cmd.Flags().GetString(dbFlag)
+			if err != nil {
+				return fmt.Errorf("failed to get dbFlag: %w", err)
+			}
+			filename, err := cmd.Flags().GetString(filenameFlag)
+			if err != nil {
+				return fmt.Errorf("failed to get filenameFlag: %w", err)
+			}
+			format, err := cmd.Flags().GetString(formatFlag)
+			if err != nil {
+				return fmt.Errorf("failed to get formatFlag: %w", err)
+			}
+
+			// Init db
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+			defer cancel()
+			db, err := openDatastore(ctx, dbPath, false)
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			ts, err := db.GetTransactions()
+			if err != nil {
+				return fmt.Errorf("failed to load transactions: %w", err)
+			}
+
+			writer := cmd.OutOrStdout()
+			if filename != "" {
+				f, err := os.Create(filename)
 				if err != nil {
-					return fmt.Errorf("failed to add transaction (%+v): %w", t, err)
+					return fmt.Errorf("failed to create file: %w", err)
 				}
+				defer f.Close()
+				writer = f
+			}
+
+			if err := exporter.Write(writer, format, ts); err != nil {
+				return fmt.Errorf("failed to export transactions: %w", err)
 			}
 
 			return nil
 		},
 	}
 
-	loadCmd.Flags().String(filenameFlag, "", "The path to the csv file")
-	dbCmd.AddCommand(loadCmd)
+	exportCmd.Flags().String(dbFlag, defaultDBPath, "Path to the database file, or a postgres:// DSN")
+	exportCmd.Flags().String(filenameFlag, "", "The path to the exported file, stdout if empty")
+	exportCmd.Flags().String(formatFlag, "ofx", fmt.Sprintf("The format of the file, one of: %s", strings.Join(exporter.Formats(), ", ")))
+	dbCmd.AddCommand(exportCmd)
+
+	watchCmd := &cobra.Command{
+		Use:   "watch <directory>",
+		Short: "Import the statement files dropped into a directory",
+		Long: fmt.Sprintf(`Import the statement files dropped into a directory.
 
-	migrateCmd := &cobra.Command{
-		Use:   "migrate",
-		Short: "Migrate the database",
+Imported files are moved to the %q subdirectory, files that failed to import
+are moved to the %q subdirectory, next to a log with the reason.`, archiveDir, errorDir),
+		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			dbPath, err := cmd.Flags().GetString(dbFlag)
 			if err != nil {
 				return fmt.Errorf("failed to get dbFlag: %w", err)
 			}
-			migrationsPath, err := cmd.Flags().GetString(migrationsFlag)
+			account, err := cmd.Flags().GetString(accountFlag)
 			if err != nil {
-				return fmt.Errorf("failed to get migrationsFlag: %w", err)
+				return fmt.Errorf("failed to get accountFlag: %w", err)
+			}
+			encoding, err := cmd.Flags().GetString(encodingFlag)
+			if err != nil {
+				return fmt.Errorf("failed to get encodingFlag: %w", err)
+			}
+			lenient, err := cmd.Flags().GetBool(lenientFlag)
+			if err != nil {
+				return fmt.Errorf("failed to get lenientFlag: %w", err)
+			}
+			onDuplicate, err := cmd.Flags().GetString(onDuplicateFlag)
+			if err != nil {
+				return fmt.Errorf("failed to get onDuplicateFlag: %w", err)
+			}
+			policy, err := parseDuplicatePolicy(onDuplicate)
+			if err != nil {
+				return err
+			}
+			rs, err := loadRules(cmd)
+			if err != nil {
+				return err
 			}
 
-			if err := validateMigrateOptions(migrationsPath); err != nil {
-				return fmt.Errorf("failed to validate migrationsPath: %w", err)
+			// Init db
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+			defer cancel()
+			db, err := openDatastore(ctx, dbPath, true)
+			if err != nil {
+				return err
 			}
+			defer db.Close()
 
-			if err := completeMigrateOptions(dbPath); err != nil {
-				return fmt.Errorf("failed to complate migrationOptions: %w", err)
+			w := &watcher{
+				dir: args[0],
+				db:  db,
+				opts: &loadOptions{
+					format:   importer.FormatAuto,
+					account:  account,
+					encoding: encoding,
+					lenient:  lenient,
+					policy:   policy,
+					rules:    rs,
+				},
 			}
 
-			return RunMigrate(dbPath, migrationsPath)
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			return w.run(ctx)
 		},
 	}
 
-	migrateCmd.Flags().String(dbFlag, defaultDBPath, "Path to the database file")
-	migrateCmd.Flags().String(migrationsFlag, "pkg/sql/migrations", "Path to the database file")
-	dbCmd.AddCommand(migrateCmd)
+	watchCmd.Flags().String(dbFlag, defaultDBPath, "Path to the database file, or a postgres:// DSN")
+	watchCmd.Flags().String(accountFlag, "", "The account of the transactions, if a file does not contain it")
+	watchCmd.Flags().String(onDuplicateFlag, string(transactions.DuplicateSkip), fmt.Sprintf("What to do with known transactions, one of: %s", joinDuplicatePolicies()))
+	watchCmd.Flags().Bool(lenientFlag, false, "Import the valid records of a file and move the rejected ones to the error directory")
+	watchCmd.Flags().String(rulesFlag, defaultRulesPath, "Path to the YAML file of the rules that label and categorize the imported transactions")
+	watchCmd.Flags().String(encodingFlag, importer.EncodingAuto, fmt.Sprintf("The encoding of the files, one of: %s, %s", importer.EncodingAuto, strings.Join(importer.Encodings(), ", ")))
+	dbCmd.AddCommand(watchCmd)
+
+	importsCmd := &cobra.Command{
+		Use:   "imports",
+		Short: "Imported files",
+	}
+	dbCmd.AddCommand(importsCmd)
+
+	importsListCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the imported files",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dbPath, err := cmd.Flags().GetString(dbFlag)
+			if err != nil {
+				return fmt.Errorf("failed to get dbFlag: %w", err)
+			}
+
+			// Init db
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+			defer cancel()
+			db, err := openDatastore(ctx, dbPath, false)
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			imps, err := db.GetImports()
+			if err != nil {
+				return fmt.Errorf("failed to load imports: %w", err)
+			}
+
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "ID\tIMPORTED AT\tFORMAT\tINSERTED\tSKIPPED\tDUPLICATES\tREJECTED\tFILE")
+			for _, imp := range imps {
+				fmt.Fprintf(w, "%d\t%s\t%s\t%d\t%d\t%d\t%d\t%s\n",
+					imp.ID, imp.ImportedAt.Local().Format("2006-01-02 15:04:05"), imp.Format,
+					imp.Inserted, imp.Skipped, imp.Duplicates, imp.Rejected, imp.Filename,
+				)
+			}
+
+			return w.Flush()
+		},
+	}
+	importsListCmd.Flags().String(dbFlag, defaultDBPath, "Path to the database file, or a postgres:// DSN")
+	importsCmd.AddCommand(importsListCmd)
+
+	importsRevertCmd := &cobra.Command{
+		Use:   "revert <id>",
+		Short: "Delete the transactions of an import",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dbPath, err := cmd.Flags().GetString(dbFlag)
+			if err != nil {
+				return fmt.Errorf("failed to get dbFlag: %w", err)
+			}
+			id, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid import id %q: %w", args[0], err)
+			}
+
+			// Init db
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+			defer cancel()
+			db, err := openDatastore(ctx, dbPath, false)
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			deleted, err := db.RevertImport(id)
+			if err != nil {
+				return fmt.Errorf("failed to revert import %d: %w", id, err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "reverted import %d, deleted %d transactions\n", id, deleted)
+
+			return nil
+		},
+	}
+	importsRevertCmd.Flags().String(dbFlag, defaultDBPath, "Path to the database file, or a postgres:// DSN")
+	importsCmd.AddCommand(importsRevertCmd)
+
+	dbCmd.AddCommand(migrateCommand())
+	dbCmd.AddCommand(duplicatesCommand())
 
 	return rootCmd
 }
+
+func parseDuplicatePolicy(s string) (transactions.DuplicatePolicy, error) {
+	for _, p := range transactions.DuplicatePolicies {
+		if string(p) == s {
+			return p, nil
+		}
+	}
+
+	return "", fmt.Errorf("unknown duplicate policy %q, expected one of: %s", s, joinDuplicatePolicies())
+}
+
+func joinDuplicatePolicies() string {
+	names := make([]string, 0, len(transactions.DuplicatePolicies))
+	for _, p := range transactions.DuplicatePolicies {
+		names = append(names, string(p))
+	}
+
+	return strings.Join(names, ", ")
+}
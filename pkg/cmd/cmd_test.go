@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+)
+
+// runCommand runs the banking command with the arguments and returns what
+// it wrote to stdout.
+func runCommand(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+
+	var stdout, stderr bytes.Buffer
+	cmd := BankingCommand()
+	cmd.SetArgs(args)
+	cmd.SetOut(&stdout)
+	cmd.SetErr(&stderr)
+	err := cmd.Execute()
+	if stderr.Len() > 0 {
+		t.Logf("%s", stderr.String())
+	}
+
+	return stdout.String(), err
+}
+
+// mustRunCommand runs the banking command and fails the test on an error.
+func mustRunCommand(t *testing.T, args ...string) string {
+	t.Helper()
+
+	out, err := runCommand(t, args...)
+	if err != nil {
+		t.Fatalf("banking %q: %v", args, err)
+	}
+
+	return out
+}
+
+// testDatabase returns the path of a database file in a temporary directory.
+func testDatabase(t *testing.T) string {
+	return filepath.Join(t.TempDir(), "transactions.db")
+}
@@ -0,0 +1,359 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ibihim/banking-csv-cli/pkg/memory"
+	"github.com/ibihim/banking-csv-cli/pkg/transactions"
+)
+
+// useMemoryDatabase makes all commands of a test share one in-memory
+// datastore, like one process would.
+func useMemoryDatabase(t *testing.T) {
+	db := memory.NewDatabase()
+	newMemoryDatabase = func() *memory.Database { return db }
+	t.Cleanup(func() { newMemoryDatabase = memory.NewDatabase })
+}
+
+// trimLines removes the trailing spaces tabwriter leaves in empty columns.
+func trimLines(s string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " ")
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// commandStep is a command of a scenario and its expected output, or a part
+// of its expected error. The --db flag is added to the arguments.
+type commandStep struct {
+	args    []string
+	want    string
+	wantErr string
+}
+
+// runSteps runs the steps against the datastore of the URL.
+func runSteps(t *testing.T, url string, steps []commandStep) {
+	t.Helper()
+
+	for _, step := range steps {
+		args := append(append([]string(nil), step.args...), "--db", url)
+		if step.wantErr != "" {
+			if _, err := runCommand(t, args...); err == nil || !strings.Contains(err.Error(), step.wantErr) {
+				t.Errorf("banking %s: got error %v, want %q", strings.Join(step.args, " "), err, step.wantErr)
+			}
+			continue
+		}
+		if got := trimLines(mustRunCommand(t, args...)); got != step.want {
+			t.Errorf("banking %s:\ngot\n%s\nwant\n%s", strings.Join(step.args, " "), got, step.want)
+		}
+	}
+}
+
+// testDatastores runs the steps against the in-memory datastore and a SQLite
+// database, which must behave the same.
+func testDatastores(t *testing.T, steps []commandStep) {
+	t.Run("memory", func(t *testing.T) {
+		useMemoryDatabase(t)
+		runSteps(t, memoryURL, steps)
+	})
+	t.Run("sqlite", func(t *testing.T) {
+		runSteps(t, testDatabase(t), steps)
+	})
+}
+
+func TestLoadAndList(t *testing.T) {
+	testDatastores(t, []commandStep{
+		{
+			args: []string{"db", "load", "testdata/march.csv"},
+			want: `FILE                FORMAT     IMPORT  INSERTED  SKIPPED  DUPLICATES  REJECTED  STATUS
+testdata/march.csv  sparkasse  1       3         0        0           0         ok
+`,
+		},
+		{
+			args: []string{"db", "load", "--force", "testdata/march-corrected.csv"},
+			want: `FILE                          FORMAT     IMPORT  INSERTED  SKIPPED  DUPLICATES  REJECTED  STATUS
+testdata/march-corrected.csv  sparkasse  2       2         1        1           0         ok
+`,
+		},
+		{
+			args: []string{"list", "--sort", "-amount", "--min-amount", "-1000"},
+			want: `ID  VALUTA DATE  ACCOUNT                 AMOUNT       BENEFICIARY             PURPOSE      CATEGORY  LABELS
+3   2024-03-05   DE02120300000000202051  3100.00 EUR  Arbeitgeber AG          Lohn Maerz
+5   2024-03-06   DE02120300000000202051  -42.17 EUR   Supermarkt GmbH         Supermarkt
+1   2024-03-01   DE02120300000000202051  -850.00 EUR  Hausverwaltung Schmidt  Miete Maerz
+`,
+		},
+		{
+			args: []string{"list", "--search", "strom", "--limit", "1"},
+			want: `ID  VALUTA DATE  ACCOUNT                 AMOUNT        BENEFICIARY             PURPOSE        CATEGORY  LABELS
+2   2024-03-01   DE02120300000000202051  -1234.56 EUR  Stadtwerke Musterstadt  Stromabschlag
+
+more transactions: --cursor=ZGF0ZTo0YjZjZjM2OToyOnMyMDI0LTAzLTAx
+`,
+		},
+		{
+			args: []string{"list", "--search", "strom", "--cursor", "ZGF0ZTo0YjZjZjM2OToyOnMyMDI0LTAzLTAx"},
+			want: `ID  VALUTA DATE  ACCOUNT                 AMOUNT        BENEFICIARY             PURPOSE              CATEGORY  LABELS
+4   2024-03-01   DE02120300000000202051  -1234.56 EUR  Stadtwerke Musterstadt  Stromabschlag Maerz
+`,
+		},
+	})
+}
+
+func TestRevertImport(t *testing.T) {
+	testDatastores(t, []commandStep{
+		{
+			args: []string{"db", "load", "testdata/march.csv"},
+			want: `FILE                FORMAT     IMPORT  INSERTED  SKIPPED  DUPLICATES  REJECTED  STATUS
+testdata/march.csv  sparkasse  1       3         0        0           0         ok
+`,
+		},
+		{
+			args: []string{"labels", "add", "Steuer", "1", "3"},
+			want: "labeled 2 of 2 transactions with \"Steuer\"\n",
+		},
+		{
+			args: []string{"db", "imports", "revert", "1"},
+			want: "reverted import 1, deleted 3 transactions\n",
+		},
+		{
+			args: []string{"list"},
+			want: "ID  VALUTA DATE  ACCOUNT  AMOUNT  BENEFICIARY  PURPOSE  CATEGORY  LABELS\n",
+		},
+		{
+			args: []string{"labels", "list"},
+			want: `ID  LABEL   TRANSACTIONS
+1   Steuer  0
+`,
+		},
+		// Labels attach to fingerprints, so they are back with the
+		// transactions.
+		{
+			args: []string{"db", "load", "testdata/march.csv"},
+			want: `FILE                FORMAT     IMPORT  INSERTED  SKIPPED  DUPLICATES  REJECTED  STATUS
+testdata/march.csv  sparkasse  2       3         0        0           0         ok
+`,
+		},
+		{
+			args: []string{"list", "--label", "Steuer"},
+			want: `ID  VALUTA DATE  ACCOUNT                 AMOUNT       BENEFICIARY             PURPOSE      CATEGORY  LABELS
+4   2024-03-01   DE02120300000000202051  -850.00 EUR  Hausverwaltung Schmidt  Miete Maerz            Steuer
+6   2024-03-05   DE02120300000000202051  3100.00 EUR  Arbeitgeber AG          Lohn Maerz             Steuer
+`,
+		},
+	})
+}
+
+func TestLabelsAndCategories(t *testing.T) {
+	testDatastores(t, []commandStep{
+		{
+			args: []string{"db", "load", "testdata/march.csv"},
+			want: `FILE                FORMAT     IMPORT  INSERTED  SKIPPED  DUPLICATES  REJECTED  STATUS
+testdata/march.csv  sparkasse  1       3         0        0           0         ok
+`,
+		},
+		{
+			args: []string{"labels", "add", "Steuer", "--search", "maerz"},
+			want: "labeled 2 of 2 transactions with \"Steuer\"\n",
+		},
+		{
+			args: []string{"labels", "rename", "Steuer", "Steuer 2024"},
+			want: "renamed label \"Steuer\" to \"Steuer 2024\"\n",
+		},
+		{
+			args: []string{"labels", "remove", "Steuer 2024", "1"},
+			want: "removed \"Steuer 2024\" from 1 of 1 transactions\n",
+		},
+		{
+			args:    []string{"labels", "remove", "Steuer 2024", "3", "--all"},
+			wantErr: "--all and ids are mutually exclusive",
+		},
+		{
+			args:    []string{"labels", "remove", "Steuer 2024", "--all", "--search", "lohn"},
+			wantErr: "--all and the query flags are mutually exclusive",
+		},
+		// Labels without transactions are not created.
+		{
+			args:    []string{"labels", "add", "Urlaub", "--search", "ryokan"},
+			wantErr: "no transactions match the query flags",
+		},
+		{
+			args: []string{"labels", "add", "Urlaub", "42"},
+			want: "labeled 0 of 1 transactions with \"Urlaub\"\n",
+		},
+		{
+			args: []string{"labels", "list"},
+			want: `ID  LABEL        TRANSACTIONS
+1   Steuer 2024  1
+`,
+		},
+		{
+			args: []string{"categories", "add", "Einkommen/Gehalt"},
+			want: "added 2 categories\n",
+		},
+		{
+			args: []string{"categories", "set", "Einkommen/Gehalt", "3"},
+			want: "set the category of 1 of 1 transactions to Einkommen/Gehalt\n",
+		},
+		{
+			args: []string{"categories", "list"},
+			want: `ID  CATEGORY   TRANSACTIONS
+1   Einkommen  0
+2     Gehalt   1
+`,
+		},
+		{
+			args: []string{"list", "--category", "Einkommen"},
+			want: `ID  VALUTA DATE  ACCOUNT                 AMOUNT       BENEFICIARY     PURPOSE     CATEGORY          LABELS
+3   2024-03-05   DE02120300000000202051  3100.00 EUR  Arbeitgeber AG  Lohn Maerz  Einkommen/Gehalt  Steuer 2024
+`,
+		},
+		{
+			args: []string{"categories", "unset", "3"},
+			want: "removed the category of 1 of 1 transactions\n",
+		},
+		{
+			args: []string{"list", "--category", transactions.Uncategorized},
+			want: `ID  VALUTA DATE  ACCOUNT                 AMOUNT        BENEFICIARY             PURPOSE        CATEGORY  LABELS
+1   2024-03-01   DE02120300000000202051  -850.00 EUR   Hausverwaltung Schmidt  Miete Maerz
+2   2024-03-01   DE02120300000000202051  -1234.56 EUR  Stadtwerke Musterstadt  Stromabschlag
+3   2024-03-05   DE02120300000000202051  3100.00 EUR   Arbeitgeber AG          Lohn Maerz               Steuer 2024
+`,
+		},
+	})
+}
+
+func TestRulesApply(t *testing.T) {
+	testDatastores(t, []commandStep{
+		{
+			args: []string{"db", "load", "testdata/march.csv"},
+			want: `FILE                FORMAT     IMPORT  INSERTED  SKIPPED  DUPLICATES  REJECTED  STATUS
+testdata/march.csv  sparkasse  1       3         0        0           0         ok
+`,
+		},
+		// Testing a rule shows its transactions, without labeling them.
+		{
+			args: []string{"rules", "test", "utilities", "--rules", "testdata/rules.yaml"},
+			want: `ID  VALUTA DATE  ACCOUNT                 AMOUNT        BENEFICIARY             PURPOSE        CATEGORY  LABELS
+2   2024-03-01   DE02120300000000202051  -1234.56 EUR  Stadtwerke Musterstadt  Stromabschlag
+`,
+		},
+		{
+			args: []string{"rules", "apply", "--rules", "testdata/rules.yaml"},
+			want: `TYPE      NAME                MATCHED  CHANGED
+label     Fixkosten           1        1
+category  Wohnen/Miete        1        1
+category  Wohnen/Nebenkosten  1        1
+`,
+		},
+		// Applying the rules again changes nothing.
+		{
+			args: []string{"rules", "apply", "--rules", "testdata/rules.yaml"},
+			want: `TYPE      NAME                MATCHED  CHANGED
+label     Fixkosten           1        0
+category  Wohnen/Miete        1        0
+category  Wohnen/Nebenkosten  1        0
+`,
+		},
+		{
+			args: []string{"list", "--category", "Wohnen"},
+			want: `ID  VALUTA DATE  ACCOUNT                 AMOUNT        BENEFICIARY             PURPOSE        CATEGORY            LABELS
+1   2024-03-01   DE02120300000000202051  -850.00 EUR   Hausverwaltung Schmidt  Miete Maerz    Wohnen/Miete
+2   2024-03-01   DE02120300000000202051  -1234.56 EUR  Stadtwerke Musterstadt  Stromabschlag  Wohnen/Nebenkosten  Fixkosten
+`,
+		},
+		{
+			args: []string{"categories", "list"},
+			want: `ID  CATEGORY       TRANSACTIONS
+1   Wohnen         0
+2     Miete        1
+3     Nebenkosten  1
+`,
+		},
+	})
+}
+
+func TestListAmountCurrency(t *testing.T) {
+	testDatastores(t, []commandStep{
+		{
+			args: []string{"db", "load", "testdata/march.csv", "testdata/tokyo.csv"},
+			want: `FILE                FORMAT     IMPORT  INSERTED  SKIPPED  DUPLICATES  REJECTED  STATUS
+testdata/march.csv  sparkasse  1       3         0        0           0         ok
+testdata/tokyo.csv  sparkasse  2       2         0        0           0         ok
+`,
+		},
+		// Amounts are euros by default, so yen do not match.
+		{
+			args: []string{"list", "--min-amount", "-1000", "--max-amount", "-100"},
+			want: `ID  VALUTA DATE  ACCOUNT                 AMOUNT       BENEFICIARY             PURPOSE      CATEGORY  LABELS
+1   2024-03-01   DE02120300000000202051  -850.00 EUR  Hausverwaltung Schmidt  Miete Maerz
+`,
+		},
+		{
+			args: []string{"list", "--min-amount", "-5000", "--currency", "jpy"},
+			want: `ID  VALUTA DATE  ACCOUNT                 AMOUNT     BENEFICIARY  PURPOSE  CATEGORY  LABELS
+5   2024-03-07   DE02120300000000202051  -1200 JPY  Ramen Shop   Ramen
+`,
+		},
+	})
+}
+
+func TestAccounts(t *testing.T) {
+	testDatastores(t, []commandStep{
+		{
+			args: []string{"accounts", "add", "DE02 1203 0000 0000 2020 51", "--nickname", "Giro", "--opening-balance", "1000", "--opening-date", "2024-02-29"},
+			want: "added account 1: Giro\n",
+		},
+		{
+			args: []string{"accounts", "add", "DE89370400440532013000", "--nickname", "Tagesgeld"},
+			want: "added account 2: Tagesgeld\n",
+		},
+		{
+			args: []string{"accounts", "edit", "Giro", "--nickname", "Girokonto"},
+			want: "updated account 1: Girokonto\n",
+		},
+		// Nicknames and IBANs must not be mistaken for each other.
+		{
+			args:    []string{"accounts", "edit", "Tagesgeld", "--nickname", "de02120300000000202051"},
+			wantErr: "is the IBAN of account 1",
+		},
+		{
+			args:    []string{"accounts", "add", "tagesgeld"},
+			wantErr: "is the nickname of account 2",
+		},
+		{
+			args: []string{"accounts", "list"},
+			want: `ID  NICKNAME   IBAN                    BANK  OWNER  TYPE      OPENING BALANCE  OPENING DATE
+1   Girokonto  DE02120300000000202051               checking  1000.00 EUR      2024-02-29
+2   Tagesgeld  DE89370400440532013000               checking  0.00 EUR
+`,
+		},
+		{
+			args: []string{"db", "load", "testdata/march.csv"},
+			want: `FILE                FORMAT     IMPORT  INSERTED  SKIPPED  DUPLICATES  REJECTED  STATUS
+testdata/march.csv  sparkasse  1       3         0        0           0         ok
+`,
+		},
+		// An account is selected by its nickname or its IBAN.
+		{
+			args: []string{"list", "--account", "Girokonto", "--min-amount", "0"},
+			want: `ID  VALUTA DATE  ACCOUNT                 AMOUNT       BENEFICIARY     PURPOSE     CATEGORY  LABELS
+3   2024-03-05   DE02120300000000202051  3100.00 EUR  Arbeitgeber AG  Lohn Maerz
+`,
+		},
+		{
+			args: []string{"list", "--account", "de02 1203 0000 0000 2020 51", "--min-amount", "0"},
+			want: `ID  VALUTA DATE  ACCOUNT                 AMOUNT       BENEFICIARY     PURPOSE     CATEGORY  LABELS
+3   2024-03-05   DE02120300000000202051  3100.00 EUR  Arbeitgeber AG  Lohn Maerz
+`,
+		},
+		{
+			args: []string{"list", "--account", "Tagesgeld"},
+			want: `ID  VALUTA DATE  ACCOUNT  AMOUNT  BENEFICIARY  PURPOSE  CATEGORY  LABELS
+`,
+		},
+	})
+}
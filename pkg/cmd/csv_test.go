@@ -0,0 +1,24 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseTransactions(t *testing.T) {
+	const file = `Auftragskonto;Buchungstag;Valutadatum;Beguenstigter/Zahlungspflichtiger;Betrag;Waehrung
+DE02120300000000202051;01.03.24;01.03.24;Hausverwaltung Schmidt;-850,00;EUR
+`
+	ts, err := ParseTransactions(strings.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ts) != 1 || ts[0].Beneficiary != "Hausverwaltung Schmidt" || ts[0].Amount.Minor != -85000 {
+		t.Errorf("got %+v", ts)
+	}
+
+	_, err = ParseTransactions(strings.NewReader(file + "DE02120300000000202051;32.03.24;01.03.24;Niemand;1,00;EUR\n"))
+	if err == nil {
+		t.Error("expected an error for a broken record")
+	}
+}
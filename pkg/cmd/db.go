@@ -2,18 +2,23 @@ package cmd
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
-	"strings"
+	"strconv"
+	"text/tabwriter"
 	"time"
 
-	"github.com/golang-migrate/migrate/v4"
+	"github.com/spf13/cobra"
+
 	"github.com/ibihim/banking-csv-cli/pkg/sql"
 )
 
 func completeMigrateOptions(dbPath string) error {
+	if sql.IsPostgresURL(dbPath) {
+		return nil
+	}
+
 	_, err := os.Stat(dbPath)
 	if err == nil {
 		return nil
@@ -40,67 +45,194 @@ func completeMigrateOptions(dbPath string) error {
 	return nil
 }
 
-func validateMigrateOptions(migrationsPath string) error {
-	info, err := os.Stat(migrationsPath)
-	if err != nil {
-		return fmt.Errorf("failed to stat database file: %w", err)
+// RunMigrate connects to the database of dbPath and runs fn with it.
+func RunMigrate(dbPath string, fn func(db *sql.Database) error) error {
+	if err := completeMigrateOptions(dbPath); err != nil {
+		return fmt.Errorf("failed to complete migrateOptions: %w", err)
 	}
 
-	if !info.IsDir() {
-		return fmt.Errorf("migrations path is not a directory: %s", migrationsPath)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	db := sql.NewDatabase(&sql.DatabaseOptions{
+		URL: dbPath,
+	})
+	if err := db.Connect(ctx); err != nil {
+		return fmt.Errorf("failed on db connect: %w", err)
 	}
+	defer db.Close()
 
-	// Read the contents of the migrations directory.
-	files, err := os.ReadDir(migrationsPath)
-	if err != nil {
-		return fmt.Errorf("error reading migrations directory: %v", err)
-	}
+	return fn(db)
+}
 
-	// Check if the files within the migrations directory end with .up.sql or .down.sql.
-	for _, file := range files {
-		if file.IsDir() {
-			return fmt.Errorf("migrations directory must not contain subdirectories, found: %s", file.Name())
-		}
+// migrateCommand returns the `db migrate` command and its subcommands.
+func migrateCommand() *cobra.Command {
+	migrateCmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Migrate the database to the latest version",
+		Long: `Migrate the database to the latest version.
+
+The migrations are part of the binary. The app and db load commands migrate
+the database on their own, if it is behind.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runMigrateCommand(cmd, func(db *sql.Database) error {
+				return db.MigrateUp(0)
+			})
+		},
+	}
+	migrateCmd.PersistentFlags().String(dbFlag, defaultDBPath, "Path to the database file, or a postgres:// DSN")
+
+	upCmd := &cobra.Command{
+		Use:   "up [steps]",
+		Short: "Apply the given number of migrations, all of them by default",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			steps, err := parseSteps(args)
+			if err != nil {
+				return err
+			}
+
+			return runMigrateCommand(cmd, func(db *sql.Database) error {
+				return db.MigrateUp(steps)
+			})
+		},
+	}
+	migrateCmd.AddCommand(upCmd)
+
+	downCmd := &cobra.Command{
+		Use:   "down [steps]",
+		Short: "Revert the given number of migrations, the last one by default",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			steps, err := parseSteps(args)
+			if err != nil {
+				return err
+			}
+			all, err := cmd.Flags().GetBool(allFlag)
+			if err != nil {
+				return fmt.Errorf("failed to get allFlag: %w", err)
+			}
+			switch {
+			case all && steps != 0:
+				return fmt.Errorf("--%s and steps are mutually exclusive", allFlag)
+			case !all && steps == 0:
+				steps = 1
+			}
+
+			return runMigrateCommand(cmd, func(db *sql.Database) error {
+				return db.MigrateDown(steps)
+			})
+		},
+	}
+	downCmd.Flags().Bool(allFlag, false, "Revert all migrations, which deletes all data")
+	migrateCmd.AddCommand(downCmd)
+
+	statusCmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show the applied and pending migrations",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runMigrateCommand(cmd, func(db *sql.Database) error {
+				status, err := db.MigrationStatus()
+				if err != nil {
+					return err
+				}
+
+				return writeMigrationStatus(cmd, status)
+			})
+		},
+	}
+	migrateCmd.AddCommand(statusCmd)
+
+	gotoCmd := &cobra.Command{
+		Use:   "goto <version>",
+		Short: "Apply or revert migrations until the database has the given version",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			version, err := strconv.ParseUint(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid version %q: %w", args[0], err)
+			}
+
+			return runMigrateCommand(cmd, func(db *sql.Database) error {
+				return db.MigrateTo(uint(version))
+			})
+		},
+	}
+	migrateCmd.AddCommand(gotoCmd)
+
+	forceCmd := &cobra.Command{
+		Use:   "force <version>",
+		Short: "Set the version of the database without migrating, after fixing a failed migration",
+		Long: `Set the version of the database without migrating.
+
+A migration that fails halfway leaves the database dirty. Once the schema is
+fixed by hand, force sets the version it corresponds to. A version of -1
+means that no migration is applied, it has to follow "--" to not be taken
+for a flag: db migrate force -- -1`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			version, err := strconv.Atoi(args[0])
+			if err != nil {
+				return fmt.Errorf("invalid version %q: %w", args[0], err)
+			}
+
+			return runMigrateCommand(cmd, func(db *sql.Database) error {
+				return db.ForceVersion(version)
+			})
+		},
+	}
+	migrateCmd.AddCommand(forceCmd)
 
-		name := file.Name()
-		if strings.HasSuffix(name, ".up.sql") || strings.HasSuffix(name, ".down.sql") {
-			continue
-		}
+	return migrateCmd
+}
 
-		return errors.New("migration files must have .up.sql or .down.sql suffix, found: " + name)
+// runMigrateCommand runs fn with the database of the --db flag.
+func runMigrateCommand(cmd *cobra.Command, fn func(db *sql.Database) error) error {
+	dbPath, err := cmd.Flags().GetString(dbFlag)
+	if err != nil {
+		return fmt.Errorf("failed to get dbFlag: %w", err)
 	}
 
-	return nil
+	return RunMigrate(dbPath, fn)
 }
 
-func RunMigrate(dbPath, migrationsPath string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
-	defer cancel()
-	db := sql.NewDatabase(&sql.DatabaseOptions{
-		URL: defaultDBPath,
-	})
-	if err := db.Connect(ctx); err != nil {
-		return fmt.Errorf("failed on db connect: %w", err)
+func parseSteps(args []string) (int, error) {
+	if len(args) == 0 {
+		return 0, nil
 	}
-	defer db.Close()
 
-	driver, err := db.Driver()
-	if err != nil {
-		return fmt.Errorf("failed to get db driver: %w", err)
+	steps, err := strconv.Atoi(args[0])
+	if err != nil || steps < 1 {
+		return 0, fmt.Errorf("invalid number of steps %q, expected a positive number", args[0])
 	}
 
-	m, err := migrate.NewWithDatabaseInstance(
-		fmt.Sprintf("file://%s", migrationsPath),
-		"sqlite3",
-		driver,
-	)
-	if err != nil {
-		return fmt.Errorf("failed to create migration instance: %w", err)
+	return steps, nil
+}
+
+func writeMigrationStatus(cmd *cobra.Command, status *sql.MigrationStatus) error {
+	w := cmd.OutOrStdout()
+	switch {
+	case status.Dirty:
+		fmt.Fprintf(w, "version %d, dirty: the migration failed, fix the schema and run `db migrate force <version>`\n\n", status.Version)
+	case status.Behind():
+		fmt.Fprintf(w, "version %d, behind: latest version is %d\n\n", status.Version, status.Latest())
+	default:
+		fmt.Fprintf(w, "version %d, up to date\n\n", status.Version)
 	}
 
-	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
-		return fmt.Errorf("failed to run migrations: %w", err)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tNAME\tSTATUS")
+	for _, m := range status.Migrations {
+		state := "pending"
+		switch {
+		case m.Applied:
+			state = "applied"
+		case status.Dirty && m.Version == status.Version:
+			state = "dirty"
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\n", m.Version, m.Name, state)
 	}
 
-	return nil
+	return tw.Flush()
 }
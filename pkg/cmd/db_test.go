@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ibihim/banking-csv-cli/pkg/sql"
+)
+
+// migrationStatus returns the migration status of the database file.
+func migrationStatus(t *testing.T, db string) *sql.MigrationStatus {
+	t.Helper()
+
+	var status *sql.MigrationStatus
+	err := RunMigrate(db, func(db *sql.Database) error {
+		var err error
+		status, err = db.MigrationStatus()
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return status
+}
+
+func TestMigrateCommands(t *testing.T) {
+	db := testDatabase(t)
+
+	for _, step := range []struct {
+		args        []string
+		wantVersion uint
+		wantApplied int
+	}{
+		{args: []string{"up"}, wantVersion: 1792335800, wantApplied: 12},
+		{args: []string{"down", "1"}, wantVersion: 1792332200, wantApplied: 11},
+		{args: []string{"goto", "1792317800"}, wantVersion: 1792317800, wantApplied: 7},
+		{args: []string{"up", "2"}, wantVersion: 1792325000, wantApplied: 9},
+		{args: []string{"force", "--", "-1"}, wantVersion: 0, wantApplied: 0},
+		{args: []string{"status"}, wantVersion: 0, wantApplied: 0},
+	} {
+		args := append([]string{"db", "migrate", "--db", db}, step.args...)
+		out := mustRunCommand(t, args...)
+
+		status := migrationStatus(t, db)
+		applied := 0
+		for _, m := range status.Migrations {
+			if m.Applied {
+				applied++
+			}
+		}
+		if status.Version != step.wantVersion || status.Dirty || applied != step.wantApplied {
+			t.Errorf("%q: got version %d, dirty %t, %d applied, want version %d, %d applied",
+				step.args, status.Version, status.Dirty, applied, step.wantVersion, step.wantApplied)
+		}
+
+		if step.args[0] == "status" && !strings.HasPrefix(out, "version 0, behind: latest version is 1792335800\n") {
+			t.Errorf("status:\n%s", out)
+		}
+	}
+
+	for _, args := range [][]string{
+		{"down", "1", "--all"},
+		{"down", "0"},
+		{"goto", "latest"},
+		{"force", "dirty"},
+	} {
+		args := append([]string{"db", "migrate", "--db", db}, args...)
+		if _, err := runCommand(t, args...); err == nil {
+			t.Errorf("%q succeeded", args)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ibihim/banking-csv-cli/pkg/sql"
+)
+
+// duplicatesCommand returns the `db duplicates` command and its
+// subcommands.
+func duplicatesCommand() *cobra.Command {
+	duplicatesCmd := &cobra.Command{
+		Use:   "duplicates",
+		Short: "Review transactions that were imported twice before duplicates were detected",
+		Long: `Review transactions that were imported twice before duplicates were detected.
+
+Older versions imported a file again without checking for known
+transactions. When such a database is migrated, transactions identical to an
+earlier one are marked as legacy duplicates. Each one is either deleted, or
+kept as a genuine transaction, like two coffees of the same price on one day.`,
+	}
+	duplicatesCmd.PersistentFlags().String(dbFlag, defaultDBPath, "Path to the database file, or a postgres:// DSN")
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the legacy duplicates and the transactions they duplicate",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDuplicatesCommand(cmd, func(db *sql.Database) error {
+				duplicates, err := db.GetLegacyDuplicates()
+				if err != nil {
+					return fmt.Errorf("failed to load legacy duplicates: %w", err)
+				}
+
+				w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+				fmt.Fprintln(w, "ID\tDUPLICATE OF\tACCOUNT\tVALUTA\tBENEFICIARY\tAMOUNT")
+				for _, dup := range duplicates {
+					t := dup.Transaction
+					fmt.Fprintf(w, "%d\t%d\t%s\t%s\t%s\t%s %s\n",
+						t.ID, dup.DuplicateOf, t.Account, t.ValutaDate.Format("2006-01-02"),
+						t.Beneficiary, t.Amount, t.Amount.Currency,
+					)
+				}
+
+				return w.Flush()
+			})
+		},
+	}
+	duplicatesCmd.AddCommand(listCmd)
+
+	deleteCmd := &cobra.Command{
+		Use:   "delete [id]...",
+		Short: "Delete legacy duplicates",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDuplicatesCommand(cmd, func(db *sql.Database) error {
+				ids, err := selectDuplicates(cmd, db, args)
+				if err != nil {
+					return err
+				}
+				deleted, err := db.DeleteLegacyDuplicates(ids)
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "deleted %d of %d transactions\n", deleted, len(ids))
+
+				return nil
+			})
+		},
+	}
+	deleteCmd.Flags().Bool(allFlag, false, "Delete all legacy duplicates")
+	duplicatesCmd.AddCommand(deleteCmd)
+
+	keepCmd := &cobra.Command{
+		Use:   "keep [id]...",
+		Short: "Keep legacy duplicates as genuine transactions",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDuplicatesCommand(cmd, func(db *sql.Database) error {
+				ids, err := selectDuplicates(cmd, db, args)
+				if err != nil {
+					return err
+				}
+				kept, err := db.KeepLegacyDuplicates(ids)
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "kept %d of %d transactions\n", kept, len(ids))
+
+				return nil
+			})
+		},
+	}
+	keepCmd.Flags().Bool(allFlag, false, "Keep all legacy duplicates")
+	duplicatesCmd.AddCommand(keepCmd)
+
+	return duplicatesCmd
+}
+
+// runDuplicatesCommand runs fn with the database of the --db flag. The
+// in-memory datastore never has legacy duplicates.
+func runDuplicatesCommand(cmd *cobra.Command, fn func(db *sql.Database) error) error {
+	return runDatastoreCommand(cmd, func(ds TransactionDatastore) error {
+		db, ok := ds.(*sql.Database)
+		if !ok {
+			return errors.New("legacy duplicates exist only in databases")
+		}
+
+		return fn(db)
+	})
+}
+
+// selectDuplicates returns the ids of the arguments, or of all legacy
+// duplicates with --all.
+func selectDuplicates(cmd *cobra.Command, db *sql.Database, args []string) ([]int64, error) {
+	all, err := cmd.Flags().GetBool(allFlag)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get allFlag: %w", err)
+	}
+
+	switch {
+	case all && len(args) > 0:
+		return nil, fmt.Errorf("--%s and ids are mutually exclusive", allFlag)
+	case !all && len(args) == 0:
+		return nil, fmt.Errorf("no transactions selected, name their ids or use --%s", allFlag)
+	}
+
+	var ids []int64
+	if all {
+		duplicates, err := db.GetLegacyDuplicates()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load legacy duplicates: %w", err)
+		}
+		for _, dup := range duplicates {
+			ids = append(ids, dup.Transaction.ID)
+		}
+		return ids, nil
+	}
+
+	for _, arg := range args {
+		id, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid transaction id %q: %w", arg, err)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
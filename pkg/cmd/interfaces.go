@@ -1,9 +1,180 @@
 package cmd
 
-import "github.com/ibihim/banking-csv-cli/pkg/transactions"
+import (
+	"context"
+	"fmt"
+	"os"
 
+	"k8s.io/klog"
+
+	"github.com/ibihim/banking-csv-cli/pkg/memory"
+	"github.com/ibihim/banking-csv-cli/pkg/sql"
+	"github.com/ibihim/banking-csv-cli/pkg/transactions"
+)
+
+// TransactionDatastore stores transactions, balances, the imports that
+// added them, accounts, labels and categories. It is implemented by
+// sql.Database and memory.Database.
 type TransactionDatastore interface {
+	// AddTransaction adds a single transaction and returns its id.
 	AddTransaction(transaction *transactions.Transaction) (int64, error)
-	GetTransactions() ([]transactions.Transaction, error)
-	TransactionExists(transaction *transactions.Transaction) (bool, error)
+	// GetTransactions returns all transactions.
+	GetTransactions() ([]*transactions.Transaction, error)
+	// QueryTransactions returns the transactions selected by a query.
+	QueryTransactions(query *transactions.Query) (*transactions.Page, error)
+	// HasTransaction returns true if the transaction is known by its
+	// fingerprint.
+	HasTransaction(transaction *transactions.Transaction) (bool, error)
+	// AddBalance adds a balance, replacing one of the same account, type and
+	// date.
+	AddBalance(balance *transactions.Balance) error
+	// GetBalances returns the balances of an account, ordered by date.
+	GetBalances(account string) ([]*transactions.Balance, error)
+
+	// Import adds the batches atomically, in order.
+	Import(batches []*transactions.ImportBatch, policy transactions.DuplicatePolicy) error
+	// PreviewImport does what Import does, without writing anything.
+	PreviewImport(batches []*transactions.ImportBatch, policy transactions.DuplicatePolicy) error
+	// GetImports returns all imports, oldest first.
+	GetImports() ([]*transactions.Import, error)
+	// GetImportByChecksum returns the latest import of a file, or nil.
+	GetImportByChecksum(checksum string) (*transactions.Import, error)
+	// RevertImport deletes the transactions of an import and returns their
+	// number.
+	RevertImport(id int64) (int64, error)
+
+	// AddAccount adds an account and returns its id.
+	AddAccount(account *transactions.Account) (int64, error)
+	// UpdateAccount replaces the account of the same id.
+	UpdateAccount(account *transactions.Account) error
+	// GetAccounts returns all accounts, ordered by nickname and IBAN.
+	GetAccounts() ([]*transactions.Account, error)
+	// GetAccount returns the account with an IBAN or nickname, or
+	// transactions.ErrNoAccount.
+	GetAccount(ref string) (*transactions.Account, error)
+
+	// GetLabels returns all labels, ordered by name.
+	GetLabels() ([]*transactions.Label, error)
+	// LabelTransactions adds a label to transactions, creating the label if
+	// needed and any of the transactions exists, and returns the number of
+	// newly labeled transactions.
+	LabelTransactions(name string, ids []int64) (int64, error)
+	// UnlabelTransactions removes a label from transactions and returns the
+	// number of transactions that had it.
+	UnlabelTransactions(name string, ids []int64) (int64, error)
+	// RenameLabel renames a label, or returns transactions.ErrNoLabel.
+	RenameLabel(name, newName string) error
+	// DeleteLabel removes a label from all transactions and deletes it.
+	DeleteLabel(name string) error
+
+	// GetCategories returns all categories, ordered like a tree.
+	GetCategories() ([]*transactions.Category, error)
+	// AddCategory adds a category and its missing parents, and returns the
+	// number of added categories.
+	AddCategory(path string) (int, error)
+	// CategorizeTransactions sets the category of transactions, replacing
+	// other categories only if replace is set, and returns the number of
+	// changed transactions.
+	CategorizeTransactions(path string, ids []int64, replace bool) (int64, error)
+	// UncategorizeTransactions removes the category of transactions and
+	// returns the number of transactions that had one.
+	UncategorizeTransactions(ids []int64) (int64, error)
+	// DeleteCategory deletes a category and its subcategories.
+	DeleteCategory(path string) error
+
+	// Close releases the datastore.
+	Close() error
+}
+
+// memoryURL selects the in-memory datastore instead of a database file. Its
+// content is lost when the command ends.
+const memoryURL = "memory:"
+
+var (
+	_ TransactionDatastore = &sql.Database{}
+	_ TransactionDatastore = &memory.Database{}
+)
+
+// newMemoryDatabase returns the datastore of memoryURL. Tests replace it to
+// share one datastore between commands.
+var newMemoryDatabase = memory.NewDatabase
+
+// openDatastore returns the connected datastore of the URL. If autoMigrate is
+// set, the schema of a database is migrated to the latest version.
+func openDatastore(ctx context.Context, url string, autoMigrate bool) (TransactionDatastore, error) {
+	if url == memoryURL {
+		return newMemoryDatabase(), nil
+	}
+	if autoMigrate {
+		if err := completeMigrateOptions(url); err != nil {
+			return nil, fmt.Errorf("failed to complete migrateOptions: %w", err)
+		}
+	}
+
+	db := sql.NewDatabase(&sql.DatabaseOptions{
+		URL: url,
+	})
+	if err := db.Connect(ctx); err != nil {
+		return nil, fmt.Errorf("failed on db connect: %w", err)
+	}
+
+	if autoMigrate {
+		status, err := db.AutoMigrate()
+		if err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to migrate database: %w", err)
+		}
+		if status.Behind() {
+			klog.Infof("migrated database from version %d to %d", status.Version, status.Latest())
+		}
+
+		n, err := db.BackfillFingerprints()
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
+		if n > 0 {
+			klog.Warningf("found %d transactions imported twice before duplicates were detected, review them with `db duplicates list`", n)
+		}
+	}
+
+	return db, nil
+}
+
+// openCurrentDatastore returns the connected datastore of the URL, like
+// openDatastore, but leaves a database as it is. It fails if the database
+// does not exist or its schema is behind, so previews write nothing.
+func openCurrentDatastore(ctx context.Context, url string) (TransactionDatastore, error) {
+	if url == memoryURL {
+		return newMemoryDatabase(), nil
+	}
+	if !sql.IsPostgresURL(url) {
+		// Connecting would create the file.
+		if _, err := os.Stat(url); err != nil {
+			return nil, fmt.Errorf("failed to open database: %w", err)
+		}
+	}
+
+	db := sql.NewDatabase(&sql.DatabaseOptions{
+		URL: url,
+	})
+	if err := db.Connect(ctx); err != nil {
+		return nil, fmt.Errorf("failed on db connect: %w", err)
+	}
+
+	status, err := db.MigrationStatus()
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	switch {
+	case status.Dirty:
+		db.Close()
+		return nil, fmt.Errorf("the migration to version %d failed, fix the schema and run `db migrate force <version>`", status.Version)
+	case status.Behind():
+		db.Close()
+		return nil, fmt.Errorf("the database has version %d, but version %d is required, run `db migrate` first", status.Version, status.Latest())
+	}
+
+	return db, nil
 }
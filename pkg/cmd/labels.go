@@ -0,0 +1,230 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ibihim/banking-csv-cli/pkg/transactions"
+)
+
+// labelsCommand returns the `labels` command and its subcommands.
+func labelsCommand() *cobra.Command {
+	labelsCmd := &cobra.Command{
+		Use:   "labels",
+		Short: "Label transactions, like \"Urlaub 2023\" or \"Steuer\"",
+		Long: `Label transactions, like "Urlaub 2023" or "Steuer".
+
+Labels attach to the fingerprints of transactions, so they survive reverting
+and importing a file again. Transactions are selected by their ids, by the
+query flags, or both.`,
+	}
+	labelsCmd.PersistentFlags().String(dbFlag, defaultDBPath, "Path to the database file, or a postgres:// DSN")
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the labels and the number of their transactions",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			output, err := cmd.Flags().GetString(outputFlag)
+			if err != nil {
+				return fmt.Errorf("failed to get outputFlag: %w", err)
+			}
+
+			return runDatastoreCommand(cmd, func(db TransactionDatastore) error {
+				labels, err := db.GetLabels()
+				if err != nil {
+					return fmt.Errorf("failed to load labels: %w", err)
+				}
+
+				return writeLabels(cmd.OutOrStdout(), labels, output)
+			})
+		},
+	}
+	listCmd.Flags().String(outputFlag, outputTable, fmt.Sprintf("The output format, one of: %s, %s", outputTable, outputJSON))
+	labelsCmd.AddCommand(listCmd)
+
+	addCmd := &cobra.Command{
+		Use:   "add <label> [id]...",
+		Short: "Add a label to transactions, creating the label if needed",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name, err := transactions.ParseLabelName(args[0])
+			if err != nil {
+				return err
+			}
+
+			return runDatastoreCommand(cmd, func(db TransactionDatastore) error {
+				ids, err := selectTransactions(cmd, db, args[1:])
+				if err != nil {
+					return err
+				}
+				labeled, err := db.LabelTransactions(name, ids)
+				if err != nil {
+					return fmt.Errorf("failed to label transactions: %w", err)
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "labeled %d of %d transactions with %q\n", labeled, len(ids), name)
+
+				return nil
+			})
+		},
+	}
+	addQueryFlags(addCmd)
+	labelsCmd.AddCommand(addCmd)
+
+	removeCmd := &cobra.Command{
+		Use:   "remove <label> [id]...",
+		Short: "Remove a label from transactions, or delete it with --all",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			all, err := cmd.Flags().GetBool(allFlag)
+			if err != nil {
+				return fmt.Errorf("failed to get allFlag: %w", err)
+			}
+			name, err := transactions.ParseLabelName(args[0])
+			if err != nil {
+				return err
+			}
+
+			if all {
+				if len(args) > 1 {
+					return fmt.Errorf("--%s and ids are mutually exclusive", allFlag)
+				}
+				q, err := queryFromFlags(cmd)
+				if err != nil {
+					return err
+				}
+				if *q != (transactions.Query{}) {
+					return fmt.Errorf("--%s and the query flags are mutually exclusive", allFlag)
+				}
+			}
+
+			return runDatastoreCommand(cmd, func(db TransactionDatastore) error {
+				if all {
+					if err := db.DeleteLabel(name); err != nil {
+						return fmt.Errorf("failed to delete label: %w", err)
+					}
+					fmt.Fprintf(cmd.OutOrStdout(), "deleted label %q\n", name)
+					return nil
+				}
+
+				ids, err := selectTransactions(cmd, db, args[1:])
+				if err != nil {
+					return err
+				}
+				unlabeled, err := db.UnlabelTransactions(name, ids)
+				if err != nil {
+					return fmt.Errorf("failed to unlabel transactions: %w", err)
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "removed %q from %d of %d transactions\n", name, unlabeled, len(ids))
+
+				return nil
+			})
+		},
+	}
+	addQueryFlags(removeCmd)
+	removeCmd.Flags().Bool(allFlag, false, "Remove the label from all transactions and delete it")
+	labelsCmd.AddCommand(removeCmd)
+
+	renameCmd := &cobra.Command{
+		Use:   "rename <label> <new label>",
+		Short: "Rename a label",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name, err := transactions.ParseLabelName(args[0])
+			if err != nil {
+				return err
+			}
+			newName, err := transactions.ParseLabelName(args[1])
+			if err != nil {
+				return err
+			}
+
+			return runDatastoreCommand(cmd, func(db TransactionDatastore) error {
+				if err := db.RenameLabel(name, newName); err != nil {
+					return fmt.Errorf("failed to rename label: %w", err)
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "renamed label %q to %q\n", name, newName)
+
+				return nil
+			})
+		},
+	}
+	labelsCmd.AddCommand(renameCmd)
+
+	return labelsCmd
+}
+
+// selectTransactions returns the ids of the arguments and of the
+// transactions selected by the query flags.
+func selectTransactions(cmd *cobra.Command, db TransactionDatastore, args []string) ([]int64, error) {
+	seen := map[int64]bool{}
+	var ids []int64
+	for _, arg := range args {
+		id, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid transaction id %q: %w", arg, err)
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	q, err := queryFromFlags(cmd)
+	if err != nil {
+		return nil, err
+	}
+	if *q == (transactions.Query{}) {
+		if len(ids) == 0 {
+			return nil, errors.New("no transactions selected, name their ids or use the query flags")
+		}
+		return ids, nil
+	}
+
+	if err := resolveAccount(db, q); err != nil {
+		return nil, err
+	}
+	page, err := db.QueryTransactions(q)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query transactions: %w", err)
+	}
+	for _, t := range page.Transactions {
+		if !seen[t.ID] {
+			seen[t.ID] = true
+			ids = append(ids, t.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, errors.New("no transactions match the query flags")
+	}
+
+	return ids, nil
+}
+
+// writeLabels writes the labels, either as table or as JSON.
+func writeLabels(w io.Writer, labels []*transactions.Label, output string) error {
+	switch output {
+	case outputJSON:
+		if labels == nil {
+			labels = []*transactions.Label{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(labels)
+	case outputTable:
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tLABEL\tTRANSACTIONS")
+		for _, l := range labels {
+			fmt.Fprintf(tw, "%d\t%s\t%d\n", l.ID, l.Name, l.Transactions)
+		}
+		return tw.Flush()
+	}
+
+	return fmt.Errorf("unknown output %q, expected one of: %s, %s", output, outputTable, outputJSON)
+}
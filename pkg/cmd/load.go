@@ -0,0 +1,473 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"k8s.io/klog"
+
+	"github.com/ibihim/banking-csv-cli/pkg/importer"
+	"github.com/ibihim/banking-csv-cli/pkg/rules"
+	"github.com/ibihim/banking-csv-cli/pkg/transactions"
+)
+
+// loadOptions configure how files are loaded into the database.
+type loadOptions struct {
+	format   string
+	account  string
+	encoding string
+	lenient  bool
+	force    bool
+	policy   transactions.DuplicatePolicy
+	// dryRun previews the import without writing anything.
+	dryRun bool
+	// rules label and categorize the imported transactions.
+	rules []*rules.Rule
+}
+
+// loadedFile is a file that is loaded into the database.
+type loadedFile struct {
+	filename  string
+	checksum  string
+	statement *importer.Statement
+	batch     *transactions.ImportBatch
+	// err is set if the file could not be loaded.
+	err error
+	// skipped is the reason a file was not imported, without it being an
+	// error, like being imported before.
+	skipped string
+}
+
+// rejectedFilename returns the name of the sidecar file the rejected
+// records of a file are written to.
+func rejectedFilename(filename string) string {
+	return strings.TrimSuffix(filename, filepath.Ext(filename)) + ".rejected.csv"
+}
+
+// expandFilenames turns files, directories and glob patterns into a sorted
+// list of files. Directories are searched recursively, hidden files and
+// rejected records of earlier imports are left out. Like in a shell, glob
+// patterns match hidden files only if they start with a dot.
+func expandFilenames(args []string) ([]string, error) {
+	seen := map[string]bool{}
+	var filenames []string
+	add := func(filename string) {
+		if !seen[filename] {
+			seen[filename] = true
+			filenames = append(filenames, filename)
+		}
+	}
+
+	for _, arg := range args {
+		matches := []string{arg}
+		if strings.ContainsAny(arg, "*?[") {
+			var err error
+			matches, err = filepath.Glob(arg)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern %q: %w", arg, err)
+			}
+			if !strings.HasPrefix(filepath.Base(arg), ".") {
+				visible := matches[:0]
+				for _, match := range matches {
+					if !strings.HasPrefix(filepath.Base(match), ".") {
+						visible = append(visible, match)
+					}
+				}
+				matches = visible
+			}
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("no files match %q", arg)
+			}
+		}
+
+		for _, match := range matches {
+			info, err := os.Stat(match)
+			if err != nil {
+				return nil, err
+			}
+			if !info.IsDir() {
+				add(match)
+				continue
+			}
+
+			err = filepath.WalkDir(match, func(path string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if path != match && strings.HasPrefix(d.Name(), ".") {
+					if d.IsDir() {
+						return filepath.SkipDir
+					}
+					return nil
+				}
+				if d.Type().IsRegular() && !strings.HasSuffix(path, ".rejected.csv") {
+					add(path)
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to read directory %s: %w", match, err)
+			}
+		}
+	}
+	sort.Strings(filenames)
+
+	return filenames, nil
+}
+
+// parseFiles parses the files in parallel. The order of the files is kept.
+func parseFiles(filenames []string, opts *loadOptions) []*loadedFile {
+	files := make([]*loadedFile, len(filenames))
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, runtime.NumCPU())
+	for i, filename := range filenames {
+		wg.Add(1)
+		go func(i int, filename string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			files[i] = parseFile(filename, opts)
+		}(i, filename)
+	}
+	wg.Wait()
+
+	return files
+}
+
+// parseFile reads a file with the importer of its format.
+func parseFile(filename string, opts *loadOptions) *loadedFile {
+	f := &loadedFile{filename: filename}
+
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		f.err = fmt.Errorf("failed to read file: %w", err)
+		return f
+	}
+	checksum := sha256.Sum256(content)
+	f.checksum = hex.EncodeToString(checksum[:])
+
+	reader, encoding, err := importer.Decode(bytes.NewReader(content), opts.encoding)
+	if err != nil {
+		f.err = fmt.Errorf("failed to decode file: %w", err)
+		return f
+	}
+	klog.V(2).Infof("decoded %s as %s", filename, encoding)
+
+	f.statement, err = importer.Parse(reader, opts.format)
+	if err != nil {
+		f.err = fmt.Errorf("failed to parse transactions: %w", err)
+		return f
+	}
+	klog.V(2).Infof("parsed %d transactions of format %q from %s", len(f.statement.Transactions), f.statement.Format, filename)
+
+	if len(f.statement.Rejected) > 0 && !opts.lenient {
+		f.err = fmt.Errorf("rejected %d records, nothing was imported, use --%s to import the others", len(f.statement.Rejected), lenientFlag)
+		return f
+	}
+
+	for _, t := range f.statement.Transactions {
+		if t.Account == "" {
+			t.Account = opts.account
+		}
+	}
+	for _, b := range f.statement.Balances {
+		if b.Account == "" {
+			b.Account = opts.account
+		}
+	}
+
+	return f
+}
+
+// loadFiles writes the parsed files to the database, in order and within a
+// single database transaction. Files that failed to parse are left out, as
+// are files that were imported before, unless forced.
+func loadFiles(db TransactionDatastore, files []*loadedFile, opts *loadOptions) error {
+	seen := map[string]string{}
+	var batches []*transactions.ImportBatch
+	for _, f := range files {
+		if f.err != nil {
+			continue
+		}
+
+		if other, ok := seen[f.checksum]; ok && !opts.force {
+			f.skipped = fmt.Sprintf("same content as %s", other)
+			continue
+		}
+		known, err := db.GetImportByChecksum(f.checksum)
+		if err != nil {
+			return fmt.Errorf("failed to look up import: %w", err)
+		}
+		if known != nil && !opts.force {
+			f.skipped = fmt.Sprintf("already imported as import %d, use --%s to import it again", known.ID, forceFlag)
+			continue
+		}
+		seen[f.checksum] = f.filename
+
+		f.batch = &transactions.ImportBatch{
+			Import: &transactions.Import{
+				Filename: f.filename,
+				Checksum: f.checksum,
+				Format:   f.statement.Format,
+				Rejected: len(f.statement.Rejected),
+			},
+			Transactions: f.statement.Transactions,
+			Balances:     f.statement.Balances,
+		}
+		batches = append(batches, f.batch)
+	}
+
+	importFn := db.Import
+	if opts.dryRun {
+		importFn = db.PreviewImport
+	}
+	if err := importFn(batches, opts.policy); err != nil {
+		for _, f := range files {
+			f.batch = nil
+		}
+		return err
+	}
+
+	if !opts.dryRun && len(opts.rules) > 0 {
+		var imported []*transactions.Transaction
+		for _, batch := range batches {
+			for _, t := range batch.Transactions {
+				// Skipped transactions were not given an id.
+				if t.ID != 0 {
+					imported = append(imported, t)
+				}
+			}
+		}
+		// The transactions are imported already, so a failure of the rules
+		// does not fail the load.
+		outcomes, err := applyRules(db, opts.rules, imported)
+		if err != nil {
+			klog.Errorf("failed to apply rules to the imported transactions, retry with `rules apply`: %v", err)
+		}
+		for _, o := range outcomes {
+			klog.Infof("rules assigned %s %q to %d imported transactions", o.kind, o.name, o.changed)
+		}
+	}
+
+	for _, f := range files {
+		if f.batch == nil {
+			continue
+		}
+
+		for _, t := range f.batch.Result.Duplicates {
+			b, err := json.Marshal(t)
+			if err != nil {
+				return fmt.Errorf("failed to marshal transaction: %w", err)
+			}
+			if opts.policy == transactions.DuplicateInsert {
+				klog.Warningf("inserted duplicate transaction: %s", string(b))
+			} else {
+				klog.V(2).Infof("skipped known transaction: %s", string(b))
+			}
+		}
+
+		if len(f.statement.Rejected) > 0 && !opts.dryRun {
+			if err := writeRejected(rejectedFilename(f.filename), f.statement); err != nil {
+				f.err = fmt.Errorf("failed to write rejected records: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// writeLoadSummary writes a table with the outcome of each file.
+func writeLoadSummary(w io.Writer, files []*loadedFile) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "FILE\tFORMAT\tIMPORT\tINSERTED\tSKIPPED\tDUPLICATES\tREJECTED\tSTATUS")
+	for _, f := range files {
+		format, rejected := "-", 0
+		if f.statement != nil {
+			format, rejected = f.statement.Format, len(f.statement.Rejected)
+		}
+
+		var (
+			id                          = "-"
+			inserted, skipped, dupCount int
+			status                      = "ok"
+		)
+		if f.batch != nil {
+			id = fmt.Sprint(f.batch.Import.ID)
+			inserted = f.batch.Result.Inserted
+			skipped = f.batch.Result.Skipped
+			dupCount = len(f.batch.Result.Duplicates)
+		}
+		switch {
+		case f.err != nil:
+			status = f.err.Error()
+		case f.skipped != "":
+			status = f.skipped
+		case f.batch == nil:
+			status = "not imported"
+		case rejected > 0:
+			status = "see " + rejectedFilename(f.filename)
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%d\t%d\t%d\t%s\n", f.filename, format, id, inserted, skipped, dupCount, rejected, status)
+	}
+
+	return tw.Flush()
+}
+
+// previewRow is a transaction of a previewed import, as written by
+// writeLoadPreview.
+type previewRow struct {
+	File        string                    `json:"file"`
+	Status      transactions.RowStatus    `json:"status"`
+	ConflictID  int64                     `json:"conflictId,omitempty"`
+	Transaction *transactions.Transaction `json:"transaction"`
+}
+
+// previewFile is a file of a previewed import, as written by
+// writeLoadPreview.
+type previewFile struct {
+	File       string `json:"file"`
+	Format     string `json:"format,omitempty"`
+	New        int    `json:"new"`
+	Duplicates int    `json:"duplicates"`
+	Conflicts  int    `json:"conflicts"`
+	Rejected   int    `json:"rejected"`
+	Error      string `json:"error,omitempty"`
+	Skipped    string `json:"skipped,omitempty"`
+}
+
+// writeLoadPreview writes which transactions of the files are new,
+// duplicate or conflicting, either as table or as JSON.
+func writeLoadPreview(w io.Writer, files []*loadedFile, output string) error {
+	rows := []previewRow{}
+	summary := []previewFile{}
+	for _, f := range files {
+		pf := previewFile{File: f.filename, Skipped: f.skipped}
+		if f.err != nil {
+			pf.Error = f.err.Error()
+		}
+		if f.statement != nil {
+			pf.Format = f.statement.Format
+			pf.Rejected = len(f.statement.Rejected)
+		}
+		if f.batch != nil {
+			for _, row := range f.batch.Result.Rows {
+				switch row.Status {
+				case transactions.RowNew:
+					pf.New++
+				case transactions.RowDuplicate:
+					pf.Duplicates++
+				case transactions.RowConflict:
+					pf.Conflicts++
+				}
+				rows = append(rows, previewRow{
+					File:        f.filename,
+					Status:      row.Status,
+					ConflictID:  row.ConflictID,
+					Transaction: row.Transaction,
+				})
+			}
+		}
+		summary = append(summary, pf)
+	}
+
+	switch output {
+	case outputJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(struct {
+			Files        []previewFile `json:"files"`
+			Transactions []previewRow  `json:"transactions"`
+		}{summary, rows})
+	case outputTable:
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "FILE\tSTATUS\tBOOKING DATE\tAMOUNT\tBENEFICIARY\tPURPOSE")
+		for _, row := range rows {
+			status := string(row.Status)
+			if row.Status == transactions.RowConflict {
+				status = fmt.Sprintf("conflict with %d", row.ConflictID)
+			}
+			t := row.Transaction
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s %s\t%s\t%s\n",
+				row.File, status, t.BookingDate.Format("2006-01-02"), t.Amount, t.Amount.Currency,
+				truncate(t.Beneficiary, 30), truncate(strings.Join(strings.Fields(t.Purpose), " "), 40),
+			)
+		}
+		fmt.Fprintln(tw)
+		fmt.Fprintln(tw, "FILE\tFORMAT\tNEW\tDUPLICATES\tCONFLICTS\tREJECTED\tSTATUS")
+		for _, pf := range summary {
+			status := "ok"
+			switch {
+			case pf.Error != "":
+				status = pf.Error
+			case pf.Skipped != "":
+				status = pf.Skipped
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%d\t%d\t%s\n", pf.File, pf.Format, pf.New, pf.Duplicates, pf.Conflicts, pf.Rejected, status)
+		}
+		return tw.Flush()
+	}
+
+	return fmt.Errorf("unknown output %q, expected one of: %s, %s", output, outputTable, outputJSON)
+}
+
+// truncate shortens a text to at most n runes.
+func truncate(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n-1]) + "…"
+	}
+	return s
+}
+
+// loadError summarizes the files that were not imported completely.
+func loadError(files []*loadedFile) error {
+	failed, rejected := 0, 0
+	for _, f := range files {
+		switch {
+		case f.err != nil:
+			failed++
+		case f.batch != nil && len(f.statement.Rejected) > 0:
+			rejected++
+		}
+	}
+
+	switch {
+	case failed > 0 && rejected > 0:
+		return fmt.Errorf("%d of %d files failed, %d files have rejected records", failed, len(files), rejected)
+	case failed > 0:
+		return fmt.Errorf("%d of %d files failed", failed, len(files))
+	case rejected > 0:
+		return fmt.Errorf("%d of %d files have rejected records", rejected, len(files))
+	}
+
+	return nil
+}
+
+// writeRejected writes the rejected records of a statement to a sidecar file.
+func writeRejected(filename string, statement *importer.Statement) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := importer.WriteRejected(f, statement); err != nil {
+		return err
+	}
+
+	return f.Close()
+}
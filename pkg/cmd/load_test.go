@@ -0,0 +1,317 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/ibihim/banking-csv-cli/pkg/importer"
+	"github.com/ibihim/banking-csv-cli/pkg/memory"
+	"github.com/ibihim/banking-csv-cli/pkg/rules"
+	"github.com/ibihim/banking-csv-cli/pkg/sql"
+	"github.com/ibihim/banking-csv-cli/pkg/transactions"
+)
+
+func TestLoadDryRun(t *testing.T) {
+	db := testDatabase(t)
+	mustRunCommand(t, "db", "load", "--db", db, "testdata/march.csv")
+	before := mustRunCommand(t, "list", "--db", db)
+	imports := mustRunCommand(t, "db", "imports", "list", "--db", db)
+
+	// A copy of the file shows how batches see the new transactions of the
+	// batches before them.
+	corrected, err := os.ReadFile("testdata/march-corrected.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	again := filepath.Join(t.TempDir(), "again.csv")
+	if err := os.WriteFile(again, corrected, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	out := mustRunCommand(t, "db", "load", "--db", db, "--dry-run", "--force", "--output", "json",
+		"testdata/march-corrected.csv", again)
+
+	var preview struct {
+		Transactions []struct {
+			Status      string `json:"status"`
+			ConflictID  int64  `json:"conflictId"`
+			Transaction struct {
+				ID       int64
+				ImportID int64
+			} `json:"transaction"`
+		} `json:"transactions"`
+	}
+	if err := json.Unmarshal([]byte(out), &preview); err != nil {
+		t.Fatalf("failed to decode %s: %v", out, err)
+	}
+
+	var got []string
+	for _, row := range preview.Transactions {
+		// Nothing was inserted, so nothing has an id.
+		if row.Transaction.ID != 0 || row.Transaction.ImportID != 0 {
+			t.Errorf("transaction has id %d of import %d", row.Transaction.ID, row.Transaction.ImportID)
+		}
+		status := row.Status
+		if row.ConflictID != 0 {
+			status = fmt.Sprintf("%s with %d", status, row.ConflictID)
+		}
+		got = append(got, status)
+	}
+	// The changed purpose conflicts with the second transaction.
+	want := []string{"duplicate", "conflict with 2", "new", "duplicate", "duplicate", "duplicate"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	if after := mustRunCommand(t, "list", "--db", db); after != before {
+		t.Errorf("transactions changed:\n%s", after)
+	}
+	if after := mustRunCommand(t, "db", "imports", "list", "--db", db); after != imports {
+		t.Errorf("imports changed:\n%s", after)
+	}
+}
+
+func TestLoadDryRunLeavesDatabase(t *testing.T) {
+	t.Run("missing", func(t *testing.T) {
+		db := testDatabase(t)
+		if _, err := runCommand(t, "db", "load", "--db", db, "--dry-run", "testdata/march.csv"); err == nil {
+			t.Error("previewed into a missing database")
+		}
+		if _, err := os.Stat(db); !os.IsNotExist(err) {
+			t.Errorf("created the database: %v", err)
+		}
+	})
+
+	t.Run("behind", func(t *testing.T) {
+		db := testDatabase(t)
+		if err := RunMigrate(db, func(db *sql.Database) error { return db.MigrateUp(1) }); err != nil {
+			t.Fatal(err)
+		}
+
+		_, err := runCommand(t, "db", "load", "--db", db, "--dry-run", "testdata/march.csv")
+		if err == nil || !strings.Contains(err.Error(), "run `db migrate` first") {
+			t.Errorf("got error %v", err)
+		}
+
+		out := mustRunCommand(t, "db", "migrate", "status", "--db", db)
+		if !strings.Contains(out, "behind") {
+			t.Errorf("migrated the database:\n%s", out)
+		}
+	})
+}
+
+// failingLabels is a datastore that fails to label transactions.
+type failingLabels struct {
+	TransactionDatastore
+}
+
+func (failingLabels) LabelTransactions(string, []int64) (int64, error) {
+	return 0, errors.New("labels are broken")
+}
+
+func TestLoadFilesRulesFail(t *testing.T) {
+	statement, err := os.ReadFile("../importer/testdata/sparkasse.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(t.TempDir(), "sparkasse.csv")
+	if err := os.WriteFile(filename, statement, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	rs, err := rules.LoadFile("testdata/rules.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db := failingLabels{memory.NewDatabase()}
+	opts := &loadOptions{
+		format:   importer.FormatAuto,
+		encoding: importer.EncodingAuto,
+		lenient:  true,
+		policy:   transactions.DuplicateSkip,
+		rules:    rs,
+	}
+	files := parseFiles([]string{filename}, opts)
+
+	// The transactions are imported, even though the rules failed.
+	if err := loadFiles(db, files, opts); err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	if files[0].err != nil || files[0].batch == nil || files[0].batch.Result.Inserted != 3 {
+		t.Errorf("got file %+v", files[0])
+	}
+	ts, err := db.GetTransactions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ts) != 3 {
+		t.Errorf("got %d transactions, want 3", len(ts))
+	}
+	if _, err := os.Stat(rejectedFilename(filename)); err != nil {
+		t.Errorf("rejected records were not written: %v", err)
+	}
+}
+
+// writeFiles creates the files, with their directories, in dir.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestExpandFilenames(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"march.csv":             "",
+		"march.rejected.csv":    "",
+		"april.csv":             "",
+		"notes.txt":             "",
+		".hidden.csv":           "",
+		"2023/jan.csv":          "",
+		"2023/jan.rejected.csv": "",
+		"2023/.git/config":      "",
+		"2023/camt/feb.xml":     "",
+		".archive/imported.csv": "",
+		"other/may.sta":         "",
+		"other/deep/june.ofx":   "",
+	})
+
+	for _, tc := range []struct {
+		name    string
+		args    []string
+		want    []string
+		wantErr string
+	}{
+		{
+			name: "files",
+			args: []string{"march.csv", "april.csv"},
+			want: []string{"april.csv", "march.csv"},
+		},
+		{
+			// Files that are given are loaded, even hidden ones.
+			name: "hidden file",
+			args: []string{".hidden.csv"},
+			want: []string{".hidden.csv"},
+		},
+		{
+			name: "glob",
+			args: []string{"*.csv"},
+			want: []string{"april.csv", "march.csv", "march.rejected.csv"},
+		},
+		{
+			name: "glob of hidden files",
+			args: []string{".h*"},
+			want: []string{".hidden.csv"},
+		},
+		{
+			name: "directory",
+			args: []string{"2023"},
+			want: []string{"2023/camt/feb.xml", "2023/jan.csv"},
+		},
+		{
+			name: "directory without hidden and rejected files",
+			args: []string{"."},
+			want: []string{"2023/camt/feb.xml", "2023/jan.csv", "april.csv", "march.csv", "notes.txt", "other/deep/june.ofx", "other/may.sta"},
+		},
+		{
+			name: "glob of directories",
+			args: []string{"o*"},
+			want: []string{"other/deep/june.ofx", "other/may.sta"},
+		},
+		{
+			name: "duplicates",
+			args: []string{"march.csv", "m*.csv", "."},
+			want: []string{"2023/camt/feb.xml", "2023/jan.csv", "april.csv", "march.csv", "march.rejected.csv", "notes.txt", "other/deep/june.ofx", "other/may.sta"},
+		},
+		{
+			name:    "glob without match",
+			args:    []string{"*.qif"},
+			wantErr: "no files match",
+		},
+		{
+			name:    "missing file",
+			args:    []string{"may.csv"},
+			wantErr: "no such file or directory",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var args []string
+			for _, arg := range tc.args {
+				args = append(args, filepath.Join(dir, arg))
+			}
+
+			got, err := expandFilenames(args)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i, filename := range got {
+				if got[i], err = filepath.Rel(dir, filename); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseFiles(t *testing.T) {
+	march, err := os.ReadFile("testdata/march.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Enough files to be parsed in parallel, every third one is broken.
+	dir := t.TempDir()
+	var filenames, want []string
+	for i := 0; i < 3*runtime.NumCPU()+1; i++ {
+		filename := filepath.Join(dir, fmt.Sprintf("%02d.csv", i))
+		content := string(march)
+		outcome := "3 transactions"
+		if i%3 == 1 {
+			content = "kein Kontoauszug\n"
+			outcome = "error"
+		}
+		writeFiles(t, dir, map[string]string{filepath.Base(filename): content})
+		filenames = append(filenames, filename)
+		want = append(want, filename+": "+outcome)
+	}
+	filenames = append(filenames, filepath.Join(dir, "missing.csv"))
+	want = append(want, filepath.Join(dir, "missing.csv")+": error")
+
+	files := parseFiles(filenames, &loadOptions{format: importer.FormatAuto, encoding: importer.EncodingAuto})
+
+	var got []string
+	for _, f := range files {
+		outcome := "error"
+		if f.err == nil {
+			outcome = fmt.Sprintf("%d transactions", len(f.statement.Transactions))
+		}
+		got = append(got, f.filename+": "+outcome)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,178 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ibihim/banking-csv-cli/pkg/transactions"
+)
+
+const (
+	fromFlag        = "from"
+	toFlag          = "to"
+	beneficiaryFlag = "beneficiary"
+	minAmountFlag   = "min-amount"
+	maxAmountFlag   = "max-amount"
+	searchFlag      = "search"
+	labelFlag       = "label"
+	categoryFlag    = "category"
+	byCategoryFlag  = "by-category"
+	sortFlag        = "sort"
+	limitFlag       = "limit"
+	offsetFlag      = "offset"
+	cursorFlag      = "cursor"
+	byAccountFlag   = "by-account"
+)
+
+// addQueryFlags adds the flags that filter transactions, see queryFromFlags.
+func addQueryFlags(cmd *cobra.Command) {
+	cmd.Flags().String(fromFlag, "", "The first valuta date, as YYYY-MM-DD")
+	cmd.Flags().String(toFlag, "", "The last valuta date, as YYYY-MM-DD")
+	cmd.Flags().String(accountFlag, "", "The account under view, by IBAN or nickname")
+	cmd.Flags().String(beneficiaryFlag, "", "A part of the beneficiary")
+	cmd.Flags().String(minAmountFlag, "", "The smallest amount, e.g. -100.50")
+	cmd.Flags().String(maxAmountFlag, "", "The largest amount, e.g. 0")
+	cmd.Flags().String(currencyFlag, "EUR", fmt.Sprintf("The currency of --%s and --%s, only transactions in it match them", minAmountFlag, maxAmountFlag))
+	cmd.Flags().String(searchFlag, "", "A text within the beneficiary, purpose, booking text or details")
+	cmd.Flags().String(labelFlag, "", "The name of a label of the transactions")
+	cmd.Flags().String(categoryFlag, "", fmt.Sprintf("The category of the transactions, including its subcategories, or %s", transactions.Uncategorized))
+}
+
+// queryFromFlags returns the query of the flags added by addQueryFlags.
+func queryFromFlags(cmd *cobra.Command) (*transactions.Query, error) {
+	q := &transactions.Query{}
+
+	for flag, date := range map[string]*time.Time{fromFlag: &q.From, toFlag: &q.To} {
+		s, err := cmd.Flags().GetString(flag)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get %sFlag: %w", flag, err)
+		}
+		if s == "" {
+			continue
+		}
+		if *date, err = time.Parse("2006-01-02", s); err != nil {
+			return nil, fmt.Errorf("invalid --%s: %w", flag, err)
+		}
+	}
+
+	currency, err := cmd.Flags().GetString(currencyFlag)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get currencyFlag: %w", err)
+	}
+	currency = strings.ToUpper(currency)
+	for flag, amount := range map[string]**transactions.Money{minAmountFlag: &q.MinAmount, maxAmountFlag: &q.MaxAmount} {
+		s, err := cmd.Flags().GetString(flag)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get %sFlag: %w", flag, err)
+		}
+		if s == "" {
+			continue
+		}
+		m, err := transactions.ParseMoney(s, currency)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --%s: %w", flag, err)
+		}
+		*amount = &m
+	}
+
+	if q.Account, err = cmd.Flags().GetString(accountFlag); err != nil {
+		return nil, fmt.Errorf("failed to get accountFlag: %w", err)
+	}
+	if q.Beneficiary, err = cmd.Flags().GetString(beneficiaryFlag); err != nil {
+		return nil, fmt.Errorf("failed to get beneficiaryFlag: %w", err)
+	}
+	if q.Text, err = cmd.Flags().GetString(searchFlag); err != nil {
+		return nil, fmt.Errorf("failed to get searchFlag: %w", err)
+	}
+	if q.Label, err = cmd.Flags().GetString(labelFlag); err != nil {
+		return nil, fmt.Errorf("failed to get labelFlag: %w", err)
+	}
+	category, err := cmd.Flags().GetString(categoryFlag)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get categoryFlag: %w", err)
+	}
+	if q.Category, err = parseCategoryFlag(category); err != nil {
+		return nil, fmt.Errorf("invalid --%s: %w", categoryFlag, err)
+	}
+
+	return q, nil
+}
+
+// parseCategoryFlag returns the category path of a flag, without spaces
+// around the names.
+func parseCategoryFlag(s string) (string, error) {
+	if s == "" || s == transactions.Uncategorized {
+		return s, nil
+	}
+
+	names, err := transactions.ParseCategoryPath(s)
+	if err != nil {
+		return "", err
+	}
+
+	return transactions.CategoryPath(names), nil
+}
+
+// resolveAccount replaces the nickname of an account in the query by its
+// IBAN. Unknown accounts are left as they are, transactions may refer to
+// accounts that were never added.
+func resolveAccount(db TransactionDatastore, q *transactions.Query) error {
+	if q.Account == "" {
+		return nil
+	}
+
+	a, err := db.GetAccount(q.Account)
+	switch {
+	case errors.Is(err, transactions.ErrNoAccount):
+		return nil
+	case err != nil:
+		return fmt.Errorf("failed to load account %q: %w", q.Account, err)
+	}
+	q.Account = a.IBAN
+
+	return nil
+}
+
+// writeTransactions writes a page of transactions, either as table or as
+// JSON.
+func writeTransactions(w io.Writer, page *transactions.Page, output string) error {
+	switch output {
+	case outputJSON:
+		ts := page.Transactions
+		if ts == nil {
+			ts = []*transactions.Transaction{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(struct {
+			Transactions []*transactions.Transaction `json:"transactions"`
+			NextCursor   string                      `json:"nextCursor,omitempty"`
+		}{ts, page.NextCursor})
+	case outputTable:
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tVALUTA DATE\tACCOUNT\tAMOUNT\tBENEFICIARY\tPURPOSE\tCATEGORY\tLABELS")
+		for _, t := range page.Transactions {
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s %s\t%s\t%s\t%s\t%s\n",
+				t.ID, t.ValutaDate.Format("2006-01-02"), t.Account, t.Amount, t.Amount.Currency,
+				truncate(t.Beneficiary, 30), truncate(strings.Join(strings.Fields(t.Purpose), " "), 40),
+				t.Category, strings.Join(t.Labels, ", "),
+			)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+		if page.NextCursor != "" {
+			fmt.Fprintf(w, "\nmore transactions: --%s=%s\n", cursorFlag, page.NextCursor)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("unknown output %q, expected one of: %s, %s", output, outputTable, outputJSON)
+}
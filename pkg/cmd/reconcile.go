@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ibihim/banking-csv-cli/pkg/transactions"
+)
+
+const balancesFlag = "balances"
+
+// reconcileCommand returns the `reconcile` command.
+func reconcileCommand() *cobra.Command {
+	reconcileCmd := &cobra.Command{
+		Use:   "reconcile [iban|nickname]...",
+		Short: "Compare the computed balances of accounts with the balances stated by the bank",
+		Long: `Compare the computed balances of accounts with the balances stated by the bank.
+
+The balances are computed from the opening balance of an account, or from the
+earliest balance of its statements, like the closing balances of CAMT and
+MT940 files. Where the computed balance drifts from a stated one, the date
+range between both balances is reported, along with transactions that would
+close the gap if they were duplicates.
+
+Without arguments, all accounts are reconciled.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dbPath, err := cmd.Flags().GetString(dbFlag)
+			if err != nil {
+				return fmt.Errorf("failed to get dbFlag: %w", err)
+			}
+			showBalances, err := cmd.Flags().GetBool(balancesFlag)
+			if err != nil {
+				return fmt.Errorf("failed to get balancesFlag: %w", err)
+			}
+
+			// Init db
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+			defer cancel()
+			db, err := openDatastore(ctx, dbPath, true)
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			var accounts []*transactions.Account
+			for _, arg := range args {
+				a, err := db.GetAccount(arg)
+				switch {
+				case errors.Is(err, transactions.ErrNoAccount):
+					// The account was never added, only its statements.
+					a = &transactions.Account{IBAN: arg}
+				case err != nil:
+					return fmt.Errorf("failed to load account %q: %w", arg, err)
+				}
+				accounts = append(accounts, a)
+			}
+			if len(args) == 0 {
+				if accounts, err = db.GetAccounts(); err != nil {
+					return fmt.Errorf("failed to load accounts: %w", err)
+				}
+				if len(accounts) == 0 {
+					return errors.New("there are no accounts, add them with `accounts add` or name the account numbers")
+				}
+			}
+
+			w := cmd.OutOrStdout()
+			unreconciled := 0
+			for i, a := range accounts {
+				if i > 0 {
+					fmt.Fprintln(w)
+				}
+
+				page, err := db.QueryTransactions(&transactions.Query{Account: a.IBAN})
+				if err != nil {
+					return fmt.Errorf("failed to load transactions of %s: %w", a.Name(), err)
+				}
+				balances, err := db.GetBalances(a.IBAN)
+				if err != nil {
+					return fmt.Errorf("failed to load balances of %s: %w", a.Name(), err)
+				}
+
+				r, err := transactions.Reconcile(a, page.Transactions, balances)
+				if errors.Is(err, transactions.ErrNoStartingBalance) || errors.Is(err, transactions.ErrCurrencyMismatch) {
+					fmt.Fprintf(w, "%s: %v\n", a.Name(), err)
+					continue
+				} else if err != nil {
+					return fmt.Errorf("failed to reconcile %s: %w", a.Name(), err)
+				}
+
+				if err := writeReconciliation(w, a, r, showBalances); err != nil {
+					return err
+				}
+				if len(r.Gaps) > 0 {
+					unreconciled++
+				}
+			}
+
+			if unreconciled > 0 {
+				return fmt.Errorf("%d of %d accounts do not reconcile", unreconciled, len(accounts))
+			}
+
+			return nil
+		},
+	}
+	reconcileCmd.Flags().String(dbFlag, defaultDBPath, "Path to the database file, or a postgres:// DSN")
+	reconcileCmd.Flags().Bool(balancesFlag, false, "Show the running balance after each transaction")
+
+	return reconcileCmd
+}
+
+// writeReconciliation writes the result of reconciling an account.
+func writeReconciliation(w io.Writer, a *transactions.Account, r *transactions.Reconciliation, showBalances bool) error {
+	fmt.Fprintf(w, "%s: starting at %s balance of %s %s on %s\n",
+		a.Name(), r.Start.Type, r.Start.Amount, r.Start.Amount.Currency, r.Start.Date.Format("2006-01-02"))
+
+	if showBalances {
+		fmt.Fprintln(w)
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tBOOKING DATE\tAMOUNT\tBALANCE\tBENEFICIARY\tPURPOSE")
+		for _, b := range r.Balances {
+			t := b.Transaction
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s %s\t%s\t%s\n",
+				t.ID, t.BookingDate.Format("2006-01-02"), t.Amount, b.Balance, b.Balance.Currency,
+				truncate(t.Beneficiary, 30), truncate(strings.Join(strings.Fields(t.Purpose), " "), 40),
+			)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if len(r.Checks) > 0 {
+		fmt.Fprintln(w)
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "DATE\tTYPE\tSTATED\tCOMPUTED\tDIFFERENCE")
+		for _, c := range r.Checks {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n",
+				c.Stated.Date.Format("2006-01-02"), c.Stated.Type, c.Stated.Amount, c.Computed, c.Difference)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if len(r.Gaps) == 0 {
+		fmt.Fprintf(w, "\n%s reconciles\n", a.Name())
+		return nil
+	}
+
+	fmt.Fprintln(w)
+	for _, g := range r.Gaps {
+		fmt.Fprintf(w, "gap of %s %s between %s and %s: transactions are missing or duplicated\n",
+			g.Difference, g.Difference.Currency, g.From.Format("2006-01-02"), g.To.Format("2006-01-02"))
+		for _, t := range g.Suspects {
+			fmt.Fprintf(w, "  possible duplicate: %d %s %s %s %s\n",
+				t.ID, t.BookingDate.Format("2006-01-02"), t.Amount, truncate(t.Beneficiary, 30), truncate(strings.Join(strings.Fields(t.Purpose), " "), 40))
+		}
+	}
+
+	return nil
+}
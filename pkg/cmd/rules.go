@@ -0,0 +1,215 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ibihim/banking-csv-cli/pkg/rules"
+	"github.com/ibihim/banking-csv-cli/pkg/transactions"
+)
+
+const (
+	defaultRulesPath = "./rules.yaml"
+
+	rulesFlag = "rules"
+)
+
+// rulesCommand returns the `rules` command and its subcommands.
+func rulesCommand() *cobra.Command {
+	rulesCmd := &cobra.Command{
+		Use:   "rules",
+		Short: "Label and categorize transactions by the rules of a YAML file",
+		Long: `Label and categorize transactions by the rules of a YAML file.
+
+A rule matches transactions by regular expressions on their creditor id,
+mandate reference, booking text, beneficiary, purpose and IBAN, and by ranges
+of their amount. Conditions are combined with all, any and not:
+
+  rules:
+    - name: rent
+      category: Wohnen/Miete
+      match:
+        all:
+          - beneficiary: "(?i)vermieter"
+          - amount: {max: -500}
+    - name: streaming
+      labels: [Abo, Freizeit]
+      match:
+        any:
+          - creditorId: "^DE12ZZZ00000123456$"
+          - purpose: "(?i)netflix|spotify"
+
+A transaction gets the category of the first rule that matches it, and only
+if it has none yet. Missing categories are added.
+
+The rules are applied to the transactions of each import, and to all
+transactions with "rules apply".`,
+	}
+	rulesCmd.PersistentFlags().String(dbFlag, defaultDBPath, "Path to the database file, or a postgres:// DSN")
+	rulesCmd.PersistentFlags().String(rulesFlag, defaultRulesPath, "Path to the YAML file of the rules")
+
+	applyCmd := &cobra.Command{
+		Use:   "apply",
+		Short: "Label and categorize the transactions that match the rules",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rs, err := loadRules(cmd)
+			if err != nil {
+				return err
+			}
+			if len(rs) == 0 {
+				return fmt.Errorf("there are no rules, write them to %s or pass --%s", defaultRulesPath, rulesFlag)
+			}
+			q, err := queryFromFlags(cmd)
+			if err != nil {
+				return err
+			}
+
+			return runDatastoreCommand(cmd, func(db TransactionDatastore) error {
+				if err := resolveAccount(db, q); err != nil {
+					return err
+				}
+				page, err := db.QueryTransactions(q)
+				if err != nil {
+					return fmt.Errorf("failed to query transactions: %w", err)
+				}
+
+				outcomes, err := applyRules(db, rs, page.Transactions)
+				if err != nil {
+					return err
+				}
+
+				tw := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+				fmt.Fprintln(tw, "TYPE\tNAME\tMATCHED\tCHANGED")
+				for _, o := range outcomes {
+					fmt.Fprintf(tw, "%s\t%s\t%d\t%d\n", o.kind, o.name, o.matched, o.changed)
+				}
+				return tw.Flush()
+			})
+		},
+	}
+	addQueryFlags(applyCmd)
+	rulesCmd.AddCommand(applyCmd)
+
+	testCmd := &cobra.Command{
+		Use:   "test <rule>",
+		Short: "Show the transactions a rule matches, without labeling them",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			output, err := cmd.Flags().GetString(outputFlag)
+			if err != nil {
+				return fmt.Errorf("failed to get outputFlag: %w", err)
+			}
+			rs, err := loadRules(cmd)
+			if err != nil {
+				return err
+			}
+			rule := rules.Find(rs, args[0])
+			if rule == nil {
+				return fmt.Errorf("rule %q does not exist", args[0])
+			}
+			q, err := queryFromFlags(cmd)
+			if err != nil {
+				return err
+			}
+
+			return runDatastoreCommand(cmd, func(db TransactionDatastore) error {
+				if err := resolveAccount(db, q); err != nil {
+					return err
+				}
+				page, err := db.QueryTransactions(q)
+				if err != nil {
+					return fmt.Errorf("failed to query transactions: %w", err)
+				}
+
+				matched := &transactions.Page{}
+				for _, t := range page.Transactions {
+					if rule.Matches(t) {
+						matched.Transactions = append(matched.Transactions, t)
+					}
+				}
+
+				return writeTransactions(cmd.OutOrStdout(), matched, output)
+			})
+		},
+	}
+	addQueryFlags(testCmd)
+	testCmd.Flags().String(outputFlag, outputTable, fmt.Sprintf("The output format, one of: %s, %s", outputTable, outputJSON))
+	rulesCmd.AddCommand(testCmd)
+
+	return rulesCmd
+}
+
+// loadRules reads the rules of the --rules flag. A missing rules file is an
+// error only if the flag was given.
+func loadRules(cmd *cobra.Command) ([]*rules.Rule, error) {
+	filename, err := cmd.Flags().GetString(rulesFlag)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rulesFlag: %w", err)
+	}
+
+	rs, err := rules.LoadFile(filename)
+	if errors.Is(err, fs.ErrNotExist) && !cmd.Flags().Changed(rulesFlag) {
+		return nil, nil
+	}
+
+	return rs, err
+}
+
+// ruleOutcome is the outcome of applying rules for a label or a category.
+type ruleOutcome struct {
+	// kind is either "label" or "category".
+	kind string
+	name string
+	// matched is the number of transactions the rules assign it to.
+	matched int
+	// changed is the number of transactions that did not have it yet.
+	changed int64
+}
+
+// applyRules labels and categorizes the transactions that match the rules.
+// Transactions that have a category keep it. Labels and categories are
+// applied ordered by name, so new ones get their ids in that order. It
+// returns the outcome for each of them.
+func applyRules(db TransactionDatastore, rs []*rules.Rule, ts []*transactions.Transaction) ([]*ruleOutcome, error) {
+	var labels, categories []*ruleOutcome
+	labelIDs := rules.Labels(rs, ts)
+	for _, name := range sortedKeys(labelIDs) {
+		ids := labelIDs[name]
+		n, err := db.LabelTransactions(name, ids)
+		if err != nil {
+			return nil, fmt.Errorf("failed to label transactions with %q: %w", name, err)
+		}
+		labels = append(labels, &ruleOutcome{kind: "label", name: name, matched: len(ids), changed: n})
+	}
+	categoryIDs := rules.Categories(rs, ts)
+	for _, path := range sortedKeys(categoryIDs) {
+		ids := categoryIDs[path]
+		if _, err := db.AddCategory(path); err != nil {
+			return nil, fmt.Errorf("failed to add category %s: %w", path, err)
+		}
+		n, err := db.CategorizeTransactions(path, ids, false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to categorize transactions as %s: %w", path, err)
+		}
+		categories = append(categories, &ruleOutcome{kind: "category", name: path, matched: len(ids), changed: n})
+	}
+
+	return append(labels, categories...), nil
+}
+
+// sortedKeys returns the keys of the ids of labels or categories, sorted.
+func sortedKeys(m map[string][]int64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
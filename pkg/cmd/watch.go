@@ -0,0 +1,204 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"k8s.io/klog"
+)
+
+const (
+	archiveDir = "archive"
+	errorDir   = "error"
+
+	// settleDuration is the time a file must not change before it is
+	// imported, so files that are still being downloaded are left alone.
+	settleDuration = 2 * time.Second
+)
+
+// watchedExtensions are the extensions of the files that are imported.
+var watchedExtensions = map[string]bool{
+	".csv":   true,
+	".xml":   true,
+	".sta":   true,
+	".mt940": true,
+	".940":   true,
+	".ofx":   true,
+	".qfx":   true,
+	".qif":   true,
+}
+
+// watcher imports the statement files dropped into a directory.
+type watcher struct {
+	dir  string
+	db   TransactionDatastore
+	opts *loadOptions
+}
+
+// isStatementFile returns true if the file is a statement that should be
+// imported. Hidden files, like temporary files of downloads, and rejected
+// records are left alone.
+func isStatementFile(path string) bool {
+	name := filepath.Base(path)
+	if strings.HasPrefix(name, ".") || strings.HasSuffix(name, ".rejected.csv") {
+		return false
+	}
+
+	return watchedExtensions[strings.ToLower(filepath.Ext(name))]
+}
+
+// run imports the files that are already in the directory and then waits
+// for new ones until the context is done.
+func (w *watcher) run(ctx context.Context) error {
+	for _, dir := range []string{archiveDir, errorDir} {
+		if err := os.MkdirAll(filepath.Join(w.dir, dir), 0755); err != nil {
+			return fmt.Errorf("failed to create %s directory: %w", dir, err)
+		}
+	}
+
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create watcher: %w", err)
+	}
+	defer fsw.Close()
+
+	if err := fsw.Add(w.dir); err != nil {
+		return fmt.Errorf("failed to watch %s: %w", w.dir, err)
+	}
+	klog.Infof("watching %s", w.dir)
+
+	entries, err := os.ReadDir(w.dir)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", w.dir, err)
+	}
+	for _, entry := range entries {
+		path := filepath.Join(w.dir, entry.Name())
+		if entry.Type().IsRegular() && isStatementFile(path) {
+			w.importFile(path)
+		}
+	}
+
+	// Files are imported once they did not change for settleDuration.
+	pending := map[string]time.Time{}
+	ticker := time.NewTicker(settleDuration / 4)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-fsw.Events:
+			if !ok {
+				return nil
+			}
+			if event.Op&(fsnotify.Create|fsnotify.Write) == 0 || !isStatementFile(event.Name) {
+				continue
+			}
+			pending[event.Name] = time.Now()
+		case err, ok := <-fsw.Errors:
+			if !ok {
+				return nil
+			}
+			klog.Errorf("watch error: %v", err)
+		case now := <-ticker.C:
+			for path, changed := range pending {
+				if now.Sub(changed) < settleDuration {
+					continue
+				}
+				delete(pending, path)
+
+				info, err := os.Stat(path)
+				if err != nil || !info.Mode().IsRegular() {
+					// The file was moved away in the meantime.
+					continue
+				}
+				w.importFile(path)
+			}
+		}
+	}
+}
+
+// importFile imports a file and moves it to the archive directory, or to the
+// error directory along with a log, if it failed.
+func (w *watcher) importFile(path string) {
+	files := parseFiles([]string{path}, w.opts)
+	if err := loadFiles(w.db, files, w.opts); err != nil {
+		files[0].err = err
+	}
+	f := files[0]
+
+	if f.err != nil {
+		klog.Errorf("failed to import %s: %v", path, f.err)
+		if err := w.moveToErrors(f); err != nil {
+			klog.Errorf("failed to move %s to %s: %v", path, errorDir, err)
+		}
+		return
+	}
+
+	if f.skipped != "" {
+		klog.Infof("skipped %s: %s", path, f.skipped)
+	} else {
+		result := f.batch.Result
+		klog.Infof("imported %s as import %d: inserted %d, skipped %d, duplicates %d, rejected %d",
+			path, f.batch.Import.ID, result.Inserted, result.Skipped, len(result.Duplicates), len(f.statement.Rejected))
+	}
+
+	if f.statement != nil && len(f.statement.Rejected) > 0 && f.batch != nil {
+		// The rejected records need attention, so they go to the errors.
+		rejected := rejectedFilename(path)
+		if _, err := moveFile(rejected, filepath.Join(w.dir, errorDir)); err != nil {
+			klog.Errorf("failed to move %s to %s: %v", rejected, errorDir, err)
+		}
+	}
+
+	if _, err := moveFile(path, filepath.Join(w.dir, archiveDir)); err != nil {
+		klog.Errorf("failed to move %s to %s: %v", path, archiveDir, err)
+	}
+}
+
+// moveToErrors moves a failed file to the error directory and writes a log
+// with the reason next to it.
+func (w *watcher) moveToErrors(f *loadedFile) error {
+	target, err := moveFile(f.filename, filepath.Join(w.dir, errorDir))
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %s: %v\n", time.Now().Format(time.RFC3339), f.filename, f.err)
+	if f.statement != nil {
+		for _, e := range f.statement.Rejected {
+			fmt.Fprintf(&b, "%s: %v\n", f.filename, e)
+		}
+	}
+
+	return os.WriteFile(target+".log", []byte(b.String()), 0644)
+}
+
+// moveFile moves a file into a directory. If the directory already has a
+// file of the same name, a timestamp is added to the name, and a counter if
+// that is taken too.
+func moveFile(path, dir string) (string, error) {
+	name := filepath.Base(path)
+	ext := filepath.Ext(name)
+	stamp := time.Now().Format("20060102150405")
+
+	target := filepath.Join(dir, name)
+	for i := 1; ; i++ {
+		if _, err := os.Stat(target); os.IsNotExist(err) {
+			break
+		}
+		suffix := stamp
+		if i > 1 {
+			suffix = fmt.Sprintf("%s-%d", stamp, i)
+		}
+		target = filepath.Join(dir, fmt.Sprintf("%s-%s%s", strings.TrimSuffix(name, ext), suffix, ext))
+	}
+
+	return target, os.Rename(path, target)
+}
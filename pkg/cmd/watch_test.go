@@ -0,0 +1,171 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/ibihim/banking-csv-cli/pkg/importer"
+	"github.com/ibihim/banking-csv-cli/pkg/memory"
+	"github.com/ibihim/banking-csv-cli/pkg/transactions"
+)
+
+func TestIsStatementFile(t *testing.T) {
+	for path, want := range map[string]bool{
+		"inbox/march.csv":          true,
+		"inbox/MARCH.CSV":          true,
+		"inbox/camt053.xml":        true,
+		"inbox/statement.sta":      true,
+		"inbox/statement.mt940":    true,
+		"inbox/statement.940":      true,
+		"inbox/statement.ofx":      true,
+		"inbox/statement.qfx":      true,
+		"inbox/statement.qif":      true,
+		"inbox/.march.csv":         false,
+		"inbox/march.csv.part":     false,
+		"inbox/march.crdownload":   false,
+		"inbox/march.rejected.csv": false,
+		"inbox/notes.txt":          false,
+		"inbox/march":              false,
+	} {
+		if got := isStatementFile(path); got != want {
+			t.Errorf("%s: got %t, want %t", path, got, want)
+		}
+	}
+}
+
+// listDir returns the names of the files of a directory and their
+// contents, like "march.csv: content".
+func listDir(t *testing.T, dir string) []string {
+	t.Helper()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var files []string
+	for _, e := range entries {
+		content, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, e.Name()+": "+string(content))
+	}
+	sort.Strings(files)
+
+	return files
+}
+
+func TestMoveFile(t *testing.T) {
+	dir := t.TempDir()
+	archive := filepath.Join(dir, archiveDir)
+	if err := os.Mkdir(archive, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	// The same name is moved three times within one second.
+	var targets []string
+	for _, content := range []string{"first", "second", "third"} {
+		path := filepath.Join(dir, "march.csv")
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		target, err := moveFile(path, archive)
+		if err != nil {
+			t.Fatal(err)
+		}
+		targets = append(targets, filepath.Base(target))
+	}
+
+	if targets[0] != "march.csv" {
+		t.Errorf("first file was renamed to %s", targets[0])
+	}
+	for _, target := range targets[1:] {
+		if !strings.HasPrefix(target, "march-") || filepath.Ext(target) != ".csv" {
+			t.Errorf("got name %s, want march-<timestamp>.csv", target)
+		}
+	}
+	if got := listDir(t, archive); len(got) != 3 {
+		t.Errorf("got files %q, want all three", got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "march.csv")); !os.IsNotExist(err) {
+		t.Errorf("file was not moved: %v", err)
+	}
+}
+
+func TestWatcherImportFile(t *testing.T) {
+	dir := t.TempDir()
+	for _, d := range []string{archiveDir, errorDir} {
+		if err := os.Mkdir(filepath.Join(dir, d), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tokyo, err := os.ReadFile("testdata/tokyo.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sparkasse, err := os.ReadFile("../importer/testdata/sparkasse.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFiles(t, dir, map[string]string{
+		"tokyo.csv":     string(tokyo),
+		"broken.csv":    "kein Kontoauszug\n",
+		"sparkasse.csv": string(sparkasse),
+	})
+
+	db := memory.NewDatabase()
+	w := &watcher{dir: dir, db: db, opts: &loadOptions{
+		format:   importer.FormatAuto,
+		encoding: importer.EncodingAuto,
+		lenient:  true,
+		policy:   transactions.DuplicateSkip,
+	}}
+	for _, name := range []string{"tokyo.csv", "broken.csv", "sparkasse.csv"} {
+		w.importFile(filepath.Join(dir, name))
+	}
+
+	// Only the directories are left.
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if !e.IsDir() {
+			t.Errorf("%s was left behind", e.Name())
+		}
+	}
+
+	if got, want := listDir(t, filepath.Join(dir, archiveDir)), []string{
+		"sparkasse.csv: " + string(sparkasse),
+		"tokyo.csv: " + string(tokyo),
+	}; !reflect.DeepEqual(got, want) {
+		t.Errorf("archive:\ngot  %q\nwant %q", got, want)
+	}
+
+	var names []string
+	for _, f := range listDir(t, filepath.Join(dir, errorDir)) {
+		name, content, _ := strings.Cut(f, ": ")
+		names = append(names, name)
+		if name == "broken.csv.log" && !strings.Contains(content, "failed to detect format") {
+			t.Errorf("log does not give the reason:\n%s", content)
+		}
+	}
+	sort.Strings(names)
+	if want := []string{"broken.csv", "broken.csv.log", "sparkasse.rejected.csv"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("errors: got %q, want %q", names, want)
+	}
+
+	// The transactions of both good files were imported, without the
+	// rejected record.
+	ts, err := db.GetTransactions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ts) != 5 {
+		t.Errorf("got %d transactions, want 5", len(ts))
+	}
+}
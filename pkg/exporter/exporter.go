@@ -0,0 +1,54 @@
+package exporter
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/ibihim/banking-csv-cli/pkg/transactions"
+)
+
+// writeFunc writes transactions in a format.
+type writeFunc func(w io.Writer, ts []*transactions.Transaction) error
+
+var writers = map[string]writeFunc{
+	"ofx": WriteOFX,
+	"qif": WriteQIF,
+}
+
+// Formats returns the names of the supported formats.
+func Formats() []string {
+	formats := make([]string, 0, len(writers))
+	for format := range writers {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+
+	return formats
+}
+
+// Write writes the transactions in the given format.
+func Write(w io.Writer, format string, ts []*transactions.Transaction) error {
+	write, ok := writers[format]
+	if !ok {
+		return fmt.Errorf("unknown format %q, expected one of: %s", format, strings.Join(Formats(), ", "))
+	}
+
+	return write(w, ts)
+}
+
+// groupByAccount groups the transactions by account, in order of their
+// first appearance.
+func groupByAccount(ts []*transactions.Transaction) ([]string, map[string][]*transactions.Transaction) {
+	var accounts []string
+	grouped := map[string][]*transactions.Transaction{}
+	for _, t := range ts {
+		if _, ok := grouped[t.Account]; !ok {
+			accounts = append(accounts, t.Account)
+		}
+		grouped[t.Account] = append(grouped[t.Account], t)
+	}
+
+	return accounts, grouped
+}
@@ -0,0 +1,102 @@
+package exporter
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ibihim/banking-csv-cli/pkg/importer"
+	"github.com/ibihim/banking-csv-cli/pkg/transactions"
+)
+
+// testTransactions are written by the tests, of two accounts.
+func testTransactions() []*transactions.Transaction {
+	day := func(d int) time.Time { return time.Date(2024, time.March, d, 0, 0, 0, 0, time.UTC) }
+
+	return []*transactions.Transaction{
+		{ID: 1, Account: "DE02120300000000202051", BookingDate: day(1), ValutaDate: day(1), Beneficiary: "Hausverwaltung Schmidt & Söhne", Purpose: "Miete März", AccountNumber: "DE89370400440532013000", BIC: "COBADEFFXXX", Amount: transactions.NewMoney(-85000, "EUR")},
+		{ID: 2, Account: "DE02120300000000202051", BookingDate: day(4), ValutaDate: day(1), Beneficiary: "Stadtwerke", Purpose: "Strom <Abschlag>", CustomerRef: "E2E-1", Amount: transactions.NewMoney(-123456, "EUR")},
+		{ID: 3, Account: "DE89370400440532013000", BookingDate: day(5), ValutaDate: day(5), Beneficiary: "Arbeitgeber AG", Purpose: "Lohn", Amount: transactions.NewMoney(310000, "EUR")},
+		{ID: 4, Account: "DE02120300000000202051", BookingDate: day(31), ValutaDate: day(31), Beneficiary: "Bank", Purpose: "Zinsen", Amount: transactions.NewMoney(1, "EUR")},
+	}
+}
+
+// exportAndParse writes the transactions in a format and parses them again.
+func exportAndParse(t *testing.T, format string, ts []*transactions.Transaction) *importer.Statement {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := Write(&buf, format, ts); err != nil {
+		t.Fatal(err)
+	}
+	s, err := importer.Parse(bytes.NewReader(buf.Bytes()), importer.FormatAuto)
+	if err != nil {
+		t.Fatalf("failed to parse:\n%s\n%v", buf.String(), err)
+	}
+	if s.Format != format {
+		t.Errorf("detected %q, want %q", s.Format, format)
+	}
+	if len(s.Rejected) > 0 {
+		t.Errorf("rejected %v", s.Rejected)
+	}
+
+	return s
+}
+
+// summarize returns the fields that survive an export, like
+// "DE02120300000000202051 2024-03-01 2024-03-01 Stadtwerke -850.00 EUR Miete".
+func summarize(t *transactions.Transaction) string {
+	return fmt.Sprintf("%s %s %s %s %s %s %s",
+		t.Account, t.BookingDate.Format("2006-01-02"), t.ValutaDate.Format("2006-01-02"),
+		t.Beneficiary, t.Amount, t.Amount.Currency, t.Purpose)
+}
+
+func TestOFXRoundTrip(t *testing.T) {
+	s := exportAndParse(t, "ofx", testTransactions())
+
+	var got []string
+	for _, tr := range s.Transactions {
+		got = append(got, summarize(tr)+" "+tr.FITID)
+	}
+	// Transactions without FITID are identified by their id.
+	want := []string{
+		"DE02120300000000202051 2024-03-01 2024-03-01 Hausverwaltung Schmidt & Söhne -850.00 EUR Miete März 1",
+		"DE02120300000000202051 2024-03-04 2024-03-01 Stadtwerke -1234.56 EUR Strom <Abschlag> 2",
+		"DE02120300000000202051 2024-03-31 2024-03-31 Bank 0.01 EUR Zinsen 4",
+		"DE89370400440532013000 2024-03-05 2024-03-05 Arbeitgeber AG 3100.00 EUR Lohn 3",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("transactions:\ngot  %q\nwant %q", got, want)
+	}
+}
+
+func TestQIFRoundTrip(t *testing.T) {
+	s := exportAndParse(t, "qif", testTransactions())
+
+	var got []string
+	for _, tr := range s.Transactions {
+		got = append(got, summarize(tr))
+	}
+	// QIF has a single date and no currency.
+	want := []string{
+		"DE02120300000000202051 2024-03-01 2024-03-01 Hausverwaltung Schmidt & Söhne -850.00  Miete März",
+		"DE02120300000000202051 2024-03-04 2024-03-04 Stadtwerke -1234.56  Strom <Abschlag>",
+		"DE02120300000000202051 2024-03-31 2024-03-31 Bank 0.01  Zinsen",
+		"DE89370400440532013000 2024-03-05 2024-03-05 Arbeitgeber AG 3100.00  Lohn",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("transactions:\ngot  %q\nwant %q", got, want)
+	}
+}
+
+func TestWriteOFXMixedCurrencies(t *testing.T) {
+	ts := testTransactions()
+	ts[1].Amount = transactions.NewMoney(-3500, "JPY")
+
+	var buf bytes.Buffer
+	if err := WriteOFX(&buf, ts); err == nil {
+		t.Error("wrote an account with two currencies")
+	}
+}
@@ -0,0 +1,130 @@
+package exporter
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ibihim/banking-csv-cli/pkg/transactions"
+)
+
+const ofxDateLayout = "20060102"
+
+// ofxNameLength is the maximum length of the NAME element.
+const ofxNameLength = 32
+
+// WriteOFX writes the transactions as OFX 2.2 bank statements, one for each
+// account. The ledger balance, which OFX requires, is the sum of the
+// written transactions, so each account needs to be in a single currency.
+func WriteOFX(w io.Writer, ts []*transactions.Transaction) error {
+	accounts, grouped := groupByAccount(ts)
+	balances := make(map[string]transactions.Money, len(accounts))
+	for _, account := range accounts {
+		var balance transactions.Money
+		for _, t := range grouped[account] {
+			var err error
+			if balance, err = balance.Add(t.Amount); err != nil {
+				return fmt.Errorf("failed to compute the balance of account %s: %w", account, err)
+			}
+		}
+		balances[account] = balance
+	}
+
+	bw := bufio.NewWriter(w)
+	p := func(format string, args ...interface{}) {
+		fmt.Fprintf(bw, format, args...)
+	}
+
+	now := time.Now()
+	p("<?xml version=\"1.0\" encoding=\"UTF-8\" standalone=\"no\"?>\n")
+	p("<?OFX OFXHEADER=\"200\" VERSION=\"220\" SECURITY=\"NONE\" OLDFILEUID=\"NONE\" NEWFILEUID=\"NONE\"?>\n")
+	p("<OFX>\n")
+	p("<SIGNONMSGSRSV1><SONRS>\n")
+	p("<STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS>\n")
+	p("<DTSERVER>%s</DTSERVER><LANGUAGE>DEU</LANGUAGE>\n", now.Format("20060102150405"))
+	p("</SONRS></SIGNONMSGSRSV1>\n")
+	p("<BANKMSGSRSV1>\n")
+
+	for i, account := range accounts {
+		ts := grouped[account]
+		start, end := ts[0].BookingDate, ts[0].BookingDate
+		for _, t := range ts {
+			if t.BookingDate.Before(start) {
+				start = t.BookingDate
+			}
+			if t.BookingDate.After(end) {
+				end = t.BookingDate
+			}
+		}
+
+		p("<STMTTRNRS>\n")
+		p("<TRNUID>%d</TRNUID>\n", i)
+		p("<STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS>\n")
+		p("<STMTRS>\n")
+		p("<CURDEF>%s</CURDEF>\n", escape(ts[0].Amount.Currency))
+		p("<BANKACCTFROM><BANKID></BANKID><ACCTID>%s</ACCTID><ACCTTYPE>CHECKING</ACCTTYPE></BANKACCTFROM>\n", escape(account))
+		p("<BANKTRANLIST>\n")
+		p("<DTSTART>%s</DTSTART><DTEND>%s</DTEND>\n", start.Format(ofxDateLayout), end.Format(ofxDateLayout))
+		for _, t := range ts {
+			writeOFXTransaction(p, t)
+		}
+		p("</BANKTRANLIST>\n")
+		p("<LEDGERBAL><BALAMT>%s</BALAMT><DTASOF>%s</DTASOF></LEDGERBAL>\n", balances[account], end.Format(ofxDateLayout))
+		p("</STMTRS>\n")
+		p("</STMTTRNRS>\n")
+	}
+
+	p("</BANKMSGSRSV1>\n")
+	p("</OFX>\n")
+
+	return bw.Flush()
+}
+
+func writeOFXTransaction(p func(string, ...interface{}), t *transactions.Transaction) {
+	trnType := "CREDIT"
+	if t.Amount.IsNegative() {
+		trnType = "DEBIT"
+	}
+
+	// Transactions that were not imported from OFX are identified by their
+	// database id.
+	fitID := t.FITID
+	if fitID == "" {
+		fitID = strconv.FormatInt(t.ID, 10)
+	}
+
+	name := []rune(t.Beneficiary)
+	if len(name) > ofxNameLength {
+		name = name[:ofxNameLength]
+	}
+
+	p("<STMTTRN>\n")
+	p("<TRNTYPE>%s</TRNTYPE>\n", trnType)
+	p("<DTPOSTED>%s</DTPOSTED>\n", t.BookingDate.Format(ofxDateLayout))
+	p("<DTUSER>%s</DTUSER>\n", t.ValutaDate.Format(ofxDateLayout))
+	p("<TRNAMT>%s</TRNAMT>\n", t.Amount)
+	p("<FITID>%s</FITID>\n", escape(fitID))
+	if t.CustomerRef != "" {
+		p("<REFNUM>%s</REFNUM>\n", escape(t.CustomerRef))
+	}
+	if len(name) > 0 {
+		p("<NAME>%s</NAME>\n", escape(string(name)))
+	}
+	if t.AccountNumber != "" {
+		p("<BANKACCTTO><BANKID>%s</BANKID><ACCTID>%s</ACCTID><ACCTTYPE>CHECKING</ACCTTYPE></BANKACCTTO>\n", escape(t.BIC), escape(t.AccountNumber))
+	}
+	if t.Purpose != "" {
+		p("<MEMO>%s</MEMO>\n", escape(t.Purpose))
+	}
+	p("</STMTTRN>\n")
+}
+
+func escape(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
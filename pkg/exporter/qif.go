@@ -0,0 +1,51 @@
+package exporter
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ibihim/banking-csv-cli/pkg/transactions"
+)
+
+// qifClearedStatus are the values of the cleared status field. Other
+// details of a transaction are not written.
+var qifClearedStatus = map[string]bool{"*": true, "c": true, "X": true, "R": true}
+
+// WriteQIF writes the transactions as QIF bank sections, one for each
+// account.
+func WriteQIF(w io.Writer, ts []*transactions.Transaction) error {
+	bw := bufio.NewWriter(w)
+
+	accounts, grouped := groupByAccount(ts)
+	for _, account := range accounts {
+		fmt.Fprintf(bw, "!Account\nN%s\nTBank\n^\n", qifLine(account))
+		fmt.Fprint(bw, "!Type:Bank\n")
+
+		for _, t := range grouped[account] {
+			fmt.Fprintf(bw, "D%s\n", t.BookingDate.Format("01/02/2006"))
+			fmt.Fprintf(bw, "T%s\n", t.Amount)
+			if qifClearedStatus[t.AdditionalDetails] {
+				fmt.Fprintf(bw, "C%s\n", t.AdditionalDetails)
+			}
+			if t.CustomerRef != "" {
+				fmt.Fprintf(bw, "N%s\n", qifLine(t.CustomerRef))
+			}
+			if t.Beneficiary != "" {
+				fmt.Fprintf(bw, "P%s\n", qifLine(t.Beneficiary))
+			}
+			if t.Purpose != "" {
+				fmt.Fprintf(bw, "M%s\n", qifLine(t.Purpose))
+			}
+			fmt.Fprint(bw, "^\n")
+		}
+	}
+
+	return bw.Flush()
+}
+
+// qifLine removes line breaks, since every line is a field.
+func qifLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
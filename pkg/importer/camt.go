@@ -0,0 +1,400 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/ibihim/banking-csv-cli/pkg/transactions"
+)
+
+// camtImporter reads ISO 20022 bank to customer statements (camt.053) and
+// account reports (camt.052). The elements are matched by their local name,
+// so all versions of the schemas are understood.
+type camtImporter struct{}
+
+var _ Importer = &camtImporter{}
+
+type camtDocument struct {
+	Statements []camtStatement `xml:"BkToCstmrStmt>Stmt"`
+	Reports    []camtStatement `xml:"BkToCstmrAcctRpt>Rpt"`
+}
+
+type camtStatement struct {
+	IBAN     string        `xml:"Acct>Id>IBAN"`
+	Other    string        `xml:"Acct>Id>Othr>Id"`
+	Balances []camtBalance `xml:"Bal"`
+	Entries  []camtEntry   `xml:"Ntry"`
+}
+
+type camtBalance struct {
+	Code      string     `xml:"Tp>CdOrPrtry>Cd"`
+	Amount    camtAmount `xml:"Amt"`
+	CdtDbtInd string     `xml:"CdtDbtInd"`
+	Date      camtDate   `xml:"Dt"`
+}
+
+type camtEntry struct {
+	Amount         camtAmount               `xml:"Amt"`
+	CdtDbtInd      string                   `xml:"CdtDbtInd"`
+	Status         camtStatus               `xml:"Sts"`
+	BookingDate    camtDate                 `xml:"BookgDt"`
+	ValutaDate     camtDate                 `xml:"ValDt"`
+	BankTxCode     string                   `xml:"BkTxCd>Prtry>Cd"`
+	AdditionalInfo string                   `xml:"AddtlNtryInf"`
+	Details        []camtTransactionDetails `xml:"NtryDtls>TxDtls"`
+}
+
+// camtStatus is the status of an entry. Since version 8 of the schemas the
+// status is nested within a Cd element.
+type camtStatus struct {
+	Value string `xml:",chardata"`
+	Code  string `xml:"Cd"`
+}
+
+func (s camtStatus) String() string {
+	if s.Code != "" {
+		return s.Code
+	}
+	return strings.TrimSpace(s.Value)
+}
+
+type camtTransactionDetails struct {
+	EndToEndID   string       `xml:"Refs>EndToEndId"`
+	MandateID    string       `xml:"Refs>MndtId"`
+	PmtInfID     string       `xml:"Refs>PmtInfId"`
+	Amount       *camtAmount  `xml:"Amt"`
+	TxAmount     *camtAmount  `xml:"AmtDtls>TxAmt>Amt"`
+	InstdAmount  *camtAmount  `xml:"AmtDtls>InstdAmt>Amt"`
+	Charges      []camtAmount `xml:"Chrgs>Rcrd>Amt"`
+	ChargesV2    []camtAmount `xml:"Chrgs>Amt"`
+	Debtor       camtParty    `xml:"RltdPties>Dbtr"`
+	DebtorAcct   camtAccount  `xml:"RltdPties>DbtrAcct"`
+	UltDebtor    camtParty    `xml:"RltdPties>UltmtDbtr"`
+	Creditor     camtParty    `xml:"RltdPties>Cdtr"`
+	CreditorAcct camtAccount  `xml:"RltdPties>CdtrAcct"`
+	UltCreditor  camtParty    `xml:"RltdPties>UltmtCdtr"`
+	DebtorAgent  camtAgent    `xml:"RltdAgts>DbtrAgt>FinInstnId"`
+	CreditorAgt  camtAgent    `xml:"RltdAgts>CdtrAgt>FinInstnId"`
+	Unstructured []string     `xml:"RmtInf>Ustrd"`
+	StructRef    string       `xml:"RmtInf>Strd>CdtrRefInf>Ref"`
+	ReturnReason string       `xml:"RtrInf>Rsn>Cd"`
+	AdditionalTx string       `xml:"AddtlTxInf"`
+}
+
+// camtParty is a party of a transaction. Since version 8 of the schemas
+// the party is nested within a Pty element.
+type camtParty struct {
+	Name     string `xml:"Nm"`
+	PtyName  string `xml:"Pty>Nm"`
+	SchemeID string `xml:"Id>PrvtId>Othr>Id"`
+	PtyID    string `xml:"Pty>Id>PrvtId>Othr>Id"`
+}
+
+func (p camtParty) name() string {
+	if p.Name != "" {
+		return p.Name
+	}
+	return p.PtyName
+}
+
+func (p camtParty) id() string {
+	if p.SchemeID != "" {
+		return p.SchemeID
+	}
+	return p.PtyID
+}
+
+type camtAccount struct {
+	IBAN  string `xml:"Id>IBAN"`
+	Other string `xml:"Id>Othr>Id"`
+}
+
+func (a camtAccount) id() string {
+	if a.IBAN != "" {
+		return a.IBAN
+	}
+	return a.Other
+}
+
+type camtAgent struct {
+	BIC   string `xml:"BIC"`
+	BICFI string `xml:"BICFI"`
+}
+
+func (a camtAgent) bic() string {
+	if a.BIC != "" {
+		return a.BIC
+	}
+	return a.BICFI
+}
+
+type camtAmount struct {
+	Value    string `xml:",chardata"`
+	Currency string `xml:"Ccy,attr"`
+}
+
+func (a camtAmount) parse() (transactions.Money, error) {
+	return transactions.ParseMoney(a.Value, strings.TrimSpace(a.Currency))
+}
+
+// camtDate is either a date or a date time.
+type camtDate struct {
+	Date     string `xml:"Dt"`
+	DateTime string `xml:"DtTm"`
+}
+
+func (d camtDate) parse() (time.Time, error) {
+	s := strings.TrimSpace(d.Date)
+	if s == "" {
+		s = strings.TrimSpace(d.DateTime)
+	}
+	if len(s) > len("2006-01-02") {
+		s = s[:len("2006-01-02")]
+	}
+
+	return time.Parse("2006-01-02", s)
+}
+
+// Name returns the name of the importer.
+func (c *camtImporter) Name() string {
+	return "camt"
+}
+
+// Detect returns true if the head of the file is a camt.052 or camt.053
+// document.
+func (c *camtImporter) Detect(head []byte) bool {
+	return bytes.Contains(head, []byte("<BkToCstmrStmt")) ||
+		bytes.Contains(head, []byte("<BkToCstmrAcctRpt")) ||
+		bytes.Contains(head, []byte(":camt.053.")) ||
+		bytes.Contains(head, []byte(":camt.052."))
+}
+
+// Parse parses the statements of a camt document.
+func (c *camtImporter) Parse(r io.Reader) (*Statement, error) {
+	// The file has been transcoded to UTF-8 by Decode, regardless of the
+	// declared encoding.
+	decoder := xml.NewDecoder(r)
+	decoder.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+
+	var doc camtDocument
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to decode document: %w", err)
+	}
+
+	s := &Statement{}
+	for _, stmt := range append(doc.Statements, doc.Reports...) {
+		account := stmt.IBAN
+		if account == "" {
+			account = stmt.Other
+		}
+
+		for _, bal := range stmt.Balances {
+			b, err := parseCAMTBalance(account, bal)
+			if err != nil {
+				return nil, err
+			}
+			if b != nil {
+				s.Balances = append(s.Balances, b)
+			}
+		}
+
+		for _, entry := range stmt.Entries {
+			ts, err := parseCAMTEntry(account, entry)
+			if err != nil {
+				return nil, err
+			}
+			s.Transactions = append(s.Transactions, ts...)
+		}
+	}
+
+	return s, nil
+}
+
+func parseCAMTBalance(account string, bal camtBalance) (*transactions.Balance, error) {
+	var balanceType transactions.BalanceType
+	switch bal.Code {
+	case "OPBD", "PRCD":
+		balanceType = transactions.OpeningBalance
+	case "CLBD":
+		balanceType = transactions.ClosingBalance
+	default:
+		return nil, nil
+	}
+
+	date, err := bal.Date.parse()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s balance date: %w", bal.Code, err)
+	}
+
+	amount, err := bal.Amount.parse()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s balance amount: %w", bal.Code, err)
+	}
+	if bal.CdtDbtInd == "DBIT" {
+		amount = amount.Neg()
+	}
+
+	return &transactions.Balance{
+		Account: account,
+		Type:    balanceType,
+		Date:    date,
+		Amount:  amount,
+	}, nil
+}
+
+// parseCAMTEntry returns the transactions of an entry. Batch bookings are
+// split into their transactions, if each of them states its amount.
+func parseCAMTEntry(account string, entry camtEntry) ([]*transactions.Transaction, error) {
+	bookingDate, err := entry.BookingDate.parse()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse booking date (%+v): %w", entry.BookingDate, err)
+	}
+
+	valutaDate, err := entry.ValutaDate.parse()
+	if err != nil {
+		valutaDate = bookingDate
+	}
+
+	bookingText := entry.AdditionalInfo
+	if bookingText == "" {
+		bookingText = entry.BankTxCode
+	}
+
+	details := entry.Details
+	split := len(details) > 1
+	for _, d := range details {
+		if d.amount() == nil {
+			split = false
+		}
+	}
+	if !split {
+		if len(details) == 0 {
+			details = []camtTransactionDetails{{}}
+		}
+		details = details[:1]
+	}
+
+	var ts []*transactions.Transaction
+	for _, d := range details {
+		amount := entry.Amount
+		if split {
+			amount = *d.amount()
+		}
+
+		t, err := parseCAMTDetails(d, entry.CdtDbtInd, amount)
+		if err != nil {
+			return nil, err
+		}
+
+		t.Account = account
+		t.BookingDate = bookingDate
+		t.ValutaDate = valutaDate
+		t.BookingText = bookingText
+		t.AdditionalDetails = entry.Status.String()
+
+		ts = append(ts, t)
+	}
+
+	return ts, nil
+}
+
+func (d camtTransactionDetails) amount() *camtAmount {
+	if d.Amount != nil {
+		return d.Amount
+	}
+	return d.TxAmount
+}
+
+func parseCAMTDetails(d camtTransactionDetails, cdtDbtInd string, amt camtAmount) (*transactions.Transaction, error) {
+	debit := cdtDbtInd == "DBIT"
+
+	amount, err := amt.parse()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse amount (%q): %w", amt.Value, err)
+	}
+	if debit {
+		amount = amount.Neg()
+	}
+
+	t := &transactions.Transaction{
+		MandateRef:   d.MandateID,
+		CustomerRef:  notProvided(d.EndToEndID),
+		CollectorRef: notProvided(d.PmtInfID),
+		Purpose:      strings.Join(d.Unstructured, ""),
+		Amount:       amount,
+	}
+
+	if t.Purpose == "" {
+		t.Purpose = d.StructRef
+	}
+	if t.Purpose == "" {
+		t.Purpose = d.AdditionalTx
+	}
+
+	// The counterparty is the creditor of outgoing and the debtor of
+	// incoming payments. An ultimate party, e.g. the merchant behind a
+	// payment service provider, is preferred over the direct one.
+	if debit {
+		t.Beneficiary = firstNonEmpty(d.UltCreditor.name(), d.Creditor.name())
+		t.AccountNumber = d.CreditorAcct.id()
+		t.BIC = d.CreditorAgt.bic()
+	} else {
+		t.Beneficiary = firstNonEmpty(d.UltDebtor.name(), d.Debtor.name())
+		t.AccountNumber = d.DebtorAcct.id()
+		t.BIC = d.DebtorAgent.bic()
+	}
+
+	// The creditor identifier is only stated for direct debits.
+	if d.MandateID != "" {
+		t.CreditorID = d.Creditor.id()
+	}
+
+	// Returned direct debits state the original amount and the fees.
+	if d.ReturnReason != "" {
+		if d.InstdAmount != nil {
+			orig, err := d.InstdAmount.parse()
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse original amount (%q): %w", d.InstdAmount.Value, err)
+			}
+			if debit {
+				orig = orig.Neg()
+			}
+			t.OrigAmount = orig
+		}
+
+		for _, c := range append(d.Charges, d.ChargesV2...) {
+			fee, err := c.parse()
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse charges (%q): %w", c.Value, err)
+			}
+			if t.ChargebackFee, err = t.ChargebackFee.Add(fee); err != nil {
+				return nil, fmt.Errorf("failed to add charges (%q): %w", c.Value, err)
+			}
+		}
+	}
+
+	return t, nil
+}
+
+// notProvided removes the placeholders that are used for missing references.
+func notProvided(s string) string {
+	if s == "NOTPROVIDED" || s == "NONREF" {
+		return ""
+	}
+	return s
+}
+
+func firstNonEmpty(ss ...string) string {
+	for _, s := range ss {
+		if s != "" {
+			return s
+		}
+	}
+	return ""
+}
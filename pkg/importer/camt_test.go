@@ -0,0 +1,91 @@
+package importer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCAMT(t *testing.T) {
+	for _, tc := range []struct {
+		file             string
+		wantTransactions []string
+		wantBalances     []string
+	}{
+		{
+			file: "camt053.xml",
+			wantTransactions: []string{
+				"DE02120300000000202051 2024-03-01 2024-03-01 Hausverwaltung Schmidt -850.00 EUR",
+				// A batch is split if each of its transactions states
+				// its amount.
+				"DE02120300000000202051 2024-03-04 2024-03-04 Kita Sonnenschein -200.00 EUR",
+				"DE02120300000000202051 2024-03-04 2024-03-04 Fussballabteilung -70.00 EUR",
+				// Otherwise the entry is kept as a whole.
+				"DE02120300000000202051 2024-03-05 2024-03-05 Zeitung -90.00 EUR",
+				"DE02120300000000202051 2024-03-28 2024-03-29 Arbeitgeber AG 3100.00 EUR",
+			},
+			// Interim balances are skipped.
+			wantBalances: []string{
+				"DE02120300000000202051 opening 2024-02-29 1000.00 EUR",
+				"DE02120300000000202051 closing 2024-03-31 -120.00 EUR",
+			},
+		},
+		{
+			file: "camt052.xml",
+			wantTransactions: []string{
+				"1234567890 2024-03-02 2024-03-02 Telefon AG -45.50 CHF",
+			},
+			wantBalances: []string{
+				"1234567890 opening 2024-03-01 -10.00 CHF",
+			},
+		},
+	} {
+		t.Run(tc.file, func(t *testing.T) {
+			s := parseFile(t, tc.file, "camt")
+			checkStatement(t, s, tc.wantTransactions, nil)
+			if got := summarizeBalances(s); !reflect.DeepEqual(got, tc.wantBalances) {
+				t.Errorf("balances:\ngot  %q\nwant %q", got, tc.wantBalances)
+			}
+		})
+	}
+}
+
+func TestCAMTDetails(t *testing.T) {
+	s := parseFile(t, "camt053.xml", "camt")
+	if len(s.Transactions) != 5 {
+		t.Fatalf("got %d transactions, want 5", len(s.Transactions))
+	}
+
+	rent := s.Transactions[0]
+	if rent.AccountNumber != "DE89370400440532013000" || rent.BIC != "COBADEFFXXX" {
+		t.Errorf("rent: got account %q and BIC %q", rent.AccountNumber, rent.BIC)
+	}
+	if rent.CustomerRef != "" {
+		t.Errorf("rent: got customer reference %q, want none", rent.CustomerRef)
+	}
+	if rent.BookingText != "DAUERAUFTRAG" || rent.AdditionalDetails != "BOOK" {
+		t.Errorf("rent: got booking text %q and status %q", rent.BookingText, rent.AdditionalDetails)
+	}
+
+	salary := s.Transactions[4]
+	if salary.Purpose != "Lohn Maerz" || salary.CustomerRef != "LOHN-2024-03" {
+		t.Errorf("salary: got purpose %q and customer reference %q", salary.Purpose, salary.CustomerRef)
+	}
+	if salary.BookingText != "NTRF+166" || salary.AdditionalDetails != "BOOK" {
+		t.Errorf("salary: got booking text %q and status %q", salary.BookingText, salary.AdditionalDetails)
+	}
+	if salary.AccountNumber != "DE75512108001245126199" {
+		t.Errorf("salary: got account %q", salary.AccountNumber)
+	}
+
+	s = parseFile(t, "camt052.xml", "camt")
+	returned := s.Transactions[0]
+	if returned.CreditorID != "CH12ZZZ00000000001" || returned.MandateRef != "M-1" {
+		t.Errorf("return: got creditor %q and mandate %q", returned.CreditorID, returned.MandateRef)
+	}
+	if returned.OrigAmount.String() != "-42.50" || returned.ChargebackFee.String() != "3.00" {
+		t.Errorf("return: got original amount %s and fee %s", returned.OrigAmount, returned.ChargebackFee)
+	}
+	if returned.AdditionalDetails != "PDNG" {
+		t.Errorf("return: got status %q", returned.AdditionalDetails)
+	}
+}
@@ -0,0 +1,405 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ibihim/banking-csv-cli/pkg/transactions"
+)
+
+// column is a field of a Transaction that can be read from a CSV column.
+type column int
+
+const (
+	columnAccount column = iota
+	columnBookingDate
+	columnValutaDate
+	columnBookingText
+	columnPurpose
+	columnCreditorID
+	columnMandateRef
+	columnCustomerRef
+	columnCollectorRef
+	columnOrigAmount
+	columnChargebackFee
+	columnBeneficiary
+	columnAccountNumber
+	columnBIC
+	columnAmount
+	columnCurrency
+	columnAdditionalDetails
+
+	// columnPayer and columnPayee are used by banks that split the
+	// beneficiary into two columns, depending on the direction of the
+	// transaction.
+	columnPayer
+	columnPayee
+
+	// columnIndicator marks the amount as debit or credit, e.g. "S" or "H".
+	columnIndicator
+	// columnDebit and columnCredit are used by banks that split the amount
+	// into two columns, instead of using a sign.
+	columnDebit
+	columnCredit
+)
+
+// maxPreambleRecords is the number of records that are searched for the
+// header. Some banks put account information in front of the header.
+const maxPreambleRecords = 20
+
+// csvProfile describes the CSV export of a bank.
+type csvProfile struct {
+	// name is the name of the profile.
+	name string
+	// comma is the field separator.
+	comma rune
+	// dateLayouts are the layouts tried in order to parse a date.
+	dateLayouts []string
+	// amounts is the format of amounts.
+	amounts amountFormat
+	// currency is used if the file has no currency column.
+	currency string
+	// columns maps a column to the header names it is known by. The first
+	// alias is used when reporting a missing column.
+	columns map[column][]string
+	// required are the columns a file must provide to be parsed. They are
+	// also used to detect the profile.
+	required []column
+	// footer is set if the file ends with records that are shorter than the
+	// header, like balances, and stops parsing at the first of them.
+	footer bool
+}
+
+var _ Importer = &csvProfile{}
+
+// Name returns the name of the profile.
+func (p *csvProfile) Name() string {
+	return p.name
+}
+
+// Detect returns true if the head of the file contains a header that
+// provides the profile's required columns.
+func (p *csvProfile) Detect(head []byte) bool {
+	r := p.newReader(bytes.NewReader(head))
+
+	for i := 0; i < maxPreambleRecords; i++ {
+		record, err := r.Read()
+		if err != nil {
+			return false
+		}
+
+		if _, err := p.newColumnMapping(record); err == nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Parse parses a CSV file and returns its transactions.
+func (p *csvProfile) Parse(reader io.Reader) (*Statement, error) {
+	r := p.newReader(reader)
+
+	// Search the header, everything in front of it is the preamble.
+	var (
+		preamble [][]string
+		header   []string
+		mapping  columnMapping
+	)
+	for mapping == nil {
+		record, err := r.Read()
+		if err == io.EOF {
+			return nil, errors.New("failed to find header")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read header: %w", err)
+		}
+
+		mapping, err = p.newColumnMapping(record)
+		if err != nil {
+			if len(preamble) == maxPreambleRecords {
+				return nil, err
+			}
+			preamble = append(preamble, record)
+			continue
+		}
+		header = record
+	}
+
+	account := findIBAN(preamble)
+
+	// Read the CSV records, broken records are rejected and the rest of the
+	// file is read anyway.
+	s := &Statement{preamble: preamble, header: header}
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				s.Rejected = append(s.Rejected, &RecordError{Line: parseErr.StartLine, Record: record, Err: parseErr.Err})
+				continue
+			}
+			return nil, err
+		}
+		line, _ := r.FieldPos(0)
+
+		if len(record) < len(header) {
+			if p.footer {
+				break
+			}
+			s.Rejected = append(s.Rejected, &RecordError{
+				Line:   line,
+				Record: record,
+				Err:    fmt.Errorf("record has %d fields, expected %d", len(record), len(header)),
+			})
+			continue
+		}
+
+		transaction, err := p.parseRecord(mapping, record)
+		if err != nil {
+			recordErr := &RecordError{Line: line, Record: record, Err: err}
+			var colErr *columnError
+			if errors.As(err, &colErr) {
+				recordErr.Column = p.columnName(mapping, header, colErr.col)
+				recordErr.Err = colErr.err
+			}
+			s.Rejected = append(s.Rejected, recordErr)
+			continue
+		}
+
+		if transaction.Account == "" {
+			transaction.Account = account
+		}
+
+		// Add the transaction to the list
+		s.Transactions = append(s.Transactions, transaction)
+	}
+
+	return s, nil
+}
+
+// columnError is an error caused by the value of a single column.
+type columnError struct {
+	col column
+	err error
+}
+
+func (e *columnError) Error() string {
+	return e.err.Error()
+}
+
+// columnName returns the name of the column as stated in the header, or the
+// profile's name for it, if the file does not have it.
+func (p *csvProfile) columnName(mapping columnMapping, header []string, col column) string {
+	if i, ok := mapping[col]; ok && i >= 0 && i < len(header) {
+		return header[i]
+	}
+
+	return p.columns[col][0]
+}
+
+func (p *csvProfile) parseRecord(mapping columnMapping, record []string) (*transactions.Transaction, error) {
+	bookingDate, err := p.parseDate(mapping.get(record, columnBookingDate))
+	if err != nil {
+		return nil, &columnError{columnBookingDate, fmt.Errorf("failed to parse date %q: %w", mapping.get(record, columnBookingDate), err)}
+	}
+
+	valutaDate, err := p.parseDate(mapping.get(record, columnValutaDate))
+	if err != nil {
+		valutaDate = bookingDate
+	}
+
+	// Parse the transaction
+	transaction := &transactions.Transaction{
+		Account:           strings.ReplaceAll(mapping.get(record, columnAccount), " ", ""),
+		BookingDate:       bookingDate,
+		ValutaDate:        valutaDate,
+		BookingText:       mapping.get(record, columnBookingText),
+		Purpose:           mapping.get(record, columnPurpose),
+		CreditorID:        mapping.get(record, columnCreditorID),
+		MandateRef:        mapping.get(record, columnMandateRef),
+		CustomerRef:       mapping.get(record, columnCustomerRef),
+		CollectorRef:      mapping.get(record, columnCollectorRef),
+		Beneficiary:       mapping.get(record, columnBeneficiary),
+		AccountNumber:     strings.ReplaceAll(mapping.get(record, columnAccountNumber), " ", ""),
+		BIC:               mapping.get(record, columnBIC),
+		AdditionalDetails: mapping.get(record, columnAdditionalDetails),
+	}
+
+	currency := strings.TrimSpace(mapping.get(record, columnCurrency))
+	if currency == "" {
+		currency = p.currency
+	}
+
+	transaction.OrigAmount, err = p.parseAmount(mapping.get(record, columnOrigAmount), currency)
+	if err != nil {
+		return nil, &columnError{columnOrigAmount, err}
+	}
+	transaction.ChargebackFee, err = p.parseAmount(mapping.get(record, columnChargebackFee), currency)
+	if err != nil {
+		return nil, &columnError{columnChargebackFee, err}
+	}
+	transaction.Amount, err = p.parseAmount(mapping.get(record, columnAmount), currency)
+	if err != nil {
+		return nil, &columnError{columnAmount, err}
+	}
+
+	if mapping.has(columnDebit) && mapping.has(columnCredit) && !mapping.has(columnAmount) {
+		debit, err := p.parseAmount(mapping.get(record, columnDebit), currency)
+		if err != nil {
+			return nil, &columnError{columnDebit, err}
+		}
+		credit, err := p.parseAmount(mapping.get(record, columnCredit), currency)
+		if err != nil {
+			return nil, &columnError{columnCredit, err}
+		}
+
+		// Debits are stated either with or without sign.
+		if !debit.IsNegative() {
+			debit = debit.Neg()
+		}
+		if transaction.Amount, err = credit.Add(debit); err != nil {
+			return nil, &columnError{columnCredit, err}
+		}
+	}
+
+	if isDebit(mapping.get(record, columnIndicator)) && !transaction.Amount.IsNegative() {
+		transaction.Amount = transaction.Amount.Neg()
+	}
+
+	if transaction.Beneficiary == "" {
+		if transaction.Amount.IsNegative() {
+			transaction.Beneficiary = mapping.get(record, columnPayee)
+		} else {
+			transaction.Beneficiary = mapping.get(record, columnPayer)
+		}
+	}
+
+	return transaction, nil
+}
+
+func (p *csvProfile) newReader(r io.Reader) *csv.Reader {
+	csvReader := csv.NewReader(r)
+	csvReader.Comma = p.comma
+	// The preamble and the footer have a different number of fields.
+	csvReader.FieldsPerRecord = -1
+	csvReader.LazyQuotes = true
+
+	return csvReader
+}
+
+func (p *csvProfile) parseDate(s string) (time.Time, error) {
+	var err error
+	for _, layout := range p.dateLayouts {
+		var t time.Time
+		t, err = time.Parse(layout, strings.TrimSpace(s))
+		if err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, err
+}
+
+func (p *csvProfile) parseAmount(s, currency string) (transactions.Money, error) {
+	return p.amounts.parse(s, currency)
+}
+
+// columnMapping maps a column to its index within a CSV record.
+type columnMapping map[column]int
+
+// newColumnMapping maps the header names of a record to the profile's
+// columns. It fails if any required column is missing.
+func (p *csvProfile) newColumnMapping(header []string) (columnMapping, error) {
+	index := map[string]int{}
+	for i, name := range header {
+		name = normalizeHeader(name)
+		if _, ok := index[name]; !ok {
+			index[name] = i
+		}
+	}
+
+	mapping := columnMapping{}
+	for col, aliases := range p.columns {
+		for _, alias := range aliases {
+			if i, ok := index[normalizeHeader(alias)]; ok {
+				mapping[col] = i
+				break
+			}
+		}
+	}
+
+	// The amount can also be split into debit and credit.
+	if !mapping.has(columnAmount) && mapping.has(columnDebit) && mapping.has(columnCredit) {
+		mapping[columnAmount] = -1
+	}
+
+	var missing []string
+	for _, col := range p.required {
+		if _, ok := mapping[col]; !ok {
+			missing = append(missing, strconv.Quote(p.columns[col][0]))
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("missing required columns: %s", strings.Join(missing, ", "))
+	}
+
+	return mapping, nil
+}
+
+// get returns the value of the column within the record or an empty string
+// if the column is not part of the file.
+func (m columnMapping) get(record []string, col column) string {
+	i, ok := m[col]
+	if !ok || i < 0 || i >= len(record) {
+		return ""
+	}
+
+	return record[i]
+}
+
+// has returns true if the column is part of the file.
+func (m columnMapping) has(col column) bool {
+	i, ok := m[col]
+	return ok && i >= 0
+}
+
+// headerReplacer folds umlauts, so "Währung" and "Waehrung" match.
+var headerReplacer = strings.NewReplacer(
+	"\ufeff", "",
+	"ä", "ae", "ö", "oe", "ü", "ue", "ß", "ss",
+)
+
+func normalizeHeader(name string) string {
+	name = headerReplacer.Replace(strings.ToLower(name))
+	return strings.Join(strings.Fields(name), " ")
+}
+
+var ibanRegexp = regexp.MustCompile(`^[A-Z]{2}[0-9]{2}[A-Z0-9]{11,30}$`)
+
+// findIBAN returns the first IBAN found in the preamble of a file. Some
+// banks only mention the account under view there.
+func findIBAN(preamble [][]string) string {
+	for _, record := range preamble {
+		for _, field := range record {
+			// DKB appends the account type, e.g. "DE12... / Girokonto".
+			field, _, _ = strings.Cut(field, "/")
+			field = strings.ReplaceAll(strings.TrimSpace(field), " ", "")
+			if ibanRegexp.MatchString(field) {
+				return field
+			}
+		}
+	}
+
+	return ""
+}
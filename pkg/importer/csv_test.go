@@ -0,0 +1,140 @@
+package importer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNewColumnMapping(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		header  []string
+		want    columnMapping
+		wantErr string
+	}{
+		{
+			name:   "aliases",
+			header: []string{"Booking date", "Account", "Amount", "Payee"},
+			want: columnMapping{
+				columnBookingDate: 0,
+				columnAccount:     1,
+				columnAmount:      2,
+				columnBeneficiary: 3,
+			},
+		},
+		{
+			name:   "umlauts, case, spaces and byte order mark",
+			header: []string{"\ufeffAUFTRAGSKONTO", " Buchungstag ", "Betrag", "Währung"},
+			want: columnMapping{
+				columnAccount:     0,
+				columnBookingDate: 1,
+				columnAmount:      2,
+				columnCurrency:    3,
+			},
+		},
+		{
+			name:   "first alias wins",
+			header: []string{"Account", "Buchungstag", "Valuta", "Wertstellung", "Betrag"},
+			want: columnMapping{
+				columnAccount:     0,
+				columnBookingDate: 1,
+				columnValutaDate:  3,
+				columnAmount:      4,
+			},
+		},
+		{
+			name:   "debit and credit instead of amount",
+			header: []string{"Account", "Buchungstag", "Soll", "Haben"},
+			want: columnMapping{
+				columnAccount:     0,
+				columnBookingDate: 1,
+				columnDebit:       2,
+				columnCredit:      3,
+				columnAmount:      -1,
+			},
+		},
+		{
+			name:    "missing columns",
+			header:  []string{"Buchungstag", "Verwendungszweck"},
+			wantErr: `missing required columns: "Auftragskonto", "Betrag"`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := genericProfile.newColumnMapping(tc.header)
+			if tc.wantErr != "" {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Fatalf("got error %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestFindIBAN(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		preamble [][]string
+		want     string
+	}{
+		{
+			name:     "grouped",
+			preamble: [][]string{{"Umsatzanzeige"}, {"IBAN", "DE10 5001 0517 1234 5678 90"}},
+			want:     "DE10500105171234567890",
+		},
+		{
+			name:     "account type",
+			preamble: [][]string{{"Kontonummer:", "DE12120300001234567890 / Girokonto", ""}},
+			want:     "DE12120300001234567890",
+		},
+		{
+			name:     "first one",
+			preamble: [][]string{{"DE02120300000000202051"}, {"DE89370400440532013000"}},
+			want:     "DE02120300000000202051",
+		},
+		{
+			name:     "no IBAN",
+			preamble: [][]string{{"Kontostand", "1.234,56 EUR"}, {"Zeitraum: 30 Tage"}},
+		},
+		{
+			name: "no preamble",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := findIBAN(tc.preamble); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGenericProfile(t *testing.T) {
+	s := parseFile(t, "generic.csv", "csv")
+
+	checkStatement(t, s,
+		[]string{
+			// The account of the preamble is used if the column is empty.
+			"DE02120300000000202051 2024-03-01 2024-03-01 Hausverwaltung Schmidt -850.00 EUR",
+			"DE44500105175407324931 2024-03-02 2024-03-04 Arbeitgeber AG 3100.00 EUR",
+		},
+		[]string{"7 Betrag"},
+	)
+}
+
+func TestCSVHeaderNotFound(t *testing.T) {
+	file := strings.Repeat("Kein;Kopf\n", maxPreambleRecords+1)
+
+	if genericProfile.Detect([]byte(file)) {
+		t.Error("detected a file without header")
+	}
+	if _, err := genericProfile.Parse(strings.NewReader(file)); err == nil {
+		t.Error("parsed a file without header")
+	}
+}
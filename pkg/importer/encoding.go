@@ -0,0 +1,128 @@
+package importer
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// EncodingAuto detects the encoding from the content of the file.
+const EncodingAuto = "auto"
+
+// encodings are the supported encodings by name.
+var encodings = map[string]encoding.Encoding{
+	"utf-8":        unicode.UTF8,
+	"utf-16le":     unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM),
+	"utf-16be":     unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM),
+	"iso-8859-1":   charmap.ISO8859_1,
+	"iso-8859-15":  charmap.ISO8859_15,
+	"windows-1252": charmap.Windows1252,
+}
+
+// encodingAliases are alternative names of the supported encodings.
+var encodingAliases = map[string]string{
+	"utf8":    "utf-8",
+	"latin1":  "iso-8859-1",
+	"latin-1": "iso-8859-1",
+	"latin9":  "iso-8859-15",
+	"cp1252":  "windows-1252",
+	"1252":    "windows-1252",
+}
+
+// byteOrderMarks are the byte order marks of the Unicode encodings.
+var byteOrderMarks = map[string][]byte{
+	"utf-8":    {0xef, 0xbb, 0xbf},
+	"utf-16le": {0xff, 0xfe},
+	"utf-16be": {0xfe, 0xff},
+}
+
+// declaredEncodingRegexp matches the encoding declared by XML files and the
+// charset declared by the SGML header of OFX 1.x files.
+var declaredEncodingRegexp = regexp.MustCompile(`(?i)(?:<\?xml[^>]*encoding=["']([^"']+)["']|CHARSET:\s*([A-Za-z0-9-]+))`)
+
+// Encodings returns the names of the supported encodings.
+func Encodings() []string {
+	names := make([]string, 0, len(encodings))
+	for name := range encodings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Decode transcodes the file to UTF-8. The name is either one of Encodings
+// or EncodingAuto. It returns the name of the encoding that was used.
+func Decode(r io.Reader, name string) (io.Reader, string, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read file: %w", err)
+	}
+
+	if name == "" || name == EncodingAuto {
+		name = detectEncoding(b)
+	}
+
+	name = strings.ToLower(name)
+	if alias, ok := encodingAliases[name]; ok {
+		name = alias
+	}
+
+	enc, ok := encodings[name]
+	if !ok {
+		return nil, "", fmt.Errorf("unknown encoding %q, expected one of: %s, %s", name, EncodingAuto, strings.Join(Encodings(), ", "))
+	}
+
+	b = bytes.TrimPrefix(b, byteOrderMarks[name])
+	if name == "utf-8" {
+		return bytes.NewReader(b), name, nil
+	}
+
+	decoded, _, err := transform.Bytes(enc.NewDecoder(), b)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to decode %s: %w", name, err)
+	}
+
+	return bytes.NewReader(decoded), name, nil
+}
+
+// detectEncoding looks for a byte order mark first. Files without one are
+// UTF-8 if they are valid UTF-8. Otherwise the declared encoding of XML and
+// OFX files is used, and Windows-1252 as last resort, since it is what
+// German banks use besides UTF-8 and a superset of the printable
+// characters of ISO-8859-1.
+func detectEncoding(b []byte) string {
+	for _, name := range []string{"utf-8", "utf-16le", "utf-16be"} {
+		if bytes.HasPrefix(b, byteOrderMarks[name]) {
+			return name
+		}
+	}
+	if utf8.Valid(b) {
+		return "utf-8"
+	}
+
+	head := b
+	if len(head) > headSize {
+		head = head[:headSize]
+	}
+	if match := declaredEncodingRegexp.FindSubmatch(head); match != nil {
+		declared := strings.ToLower(string(match[1]) + string(match[2]))
+		if alias, ok := encodingAliases[declared]; ok {
+			declared = alias
+		}
+		if _, ok := encodings[declared]; ok && declared != "utf-8" {
+			return declared
+		}
+	}
+
+	return "windows-1252"
+}
@@ -0,0 +1,96 @@
+package importer
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestDetectEncoding(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		b    []byte
+		want string
+	}{
+		{"UTF-8 byte order mark", []byte("\xef\xbb\xbfBuchungstag"), "utf-8"},
+		{"UTF-16LE byte order mark", []byte("\xff\xfeB\x00"), "utf-16le"},
+		{"UTF-16BE byte order mark", []byte("\xfe\xff\x00B"), "utf-16be"},
+		{"valid UTF-8", []byte("Bäckerei"), "utf-8"},
+		{"ASCII with declared charset", []byte("OFXHEADER:100\nCHARSET:1252\n<OFX>"), "utf-8"},
+		{"declared XML encoding", []byte("<?xml version=\"1.0\" encoding=\"ISO-8859-15\"?><Nm>B\xe4ckerei \xa4</Nm>"), "iso-8859-15"},
+		{"declared XML alias", []byte("<?xml version='1.0' encoding='latin1'?><Nm>B\xe4ckerei</Nm>"), "iso-8859-1"},
+		{"declared OFX charset", []byte("OFXHEADER:100\nCHARSET:ISO-8859-1\n<OFX><NAME>B\xe4ckerei"), "iso-8859-1"},
+		{"unknown declared charset", []byte("OFXHEADER:100\nCHARSET:1252\n<OFX><NAME>B\xe4ckerei"), "windows-1252"},
+		{"fallback", []byte("B\xe4ckerei;\x80"), "windows-1252"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := detectEncoding(tc.b); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDecode(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		b        []byte
+		encoding string
+		want     string
+		wantName string
+	}{
+		{"UTF-8 without byte order mark", []byte("\xef\xbb\xbfBäckerei"), EncodingAuto, "Bäckerei", "utf-8"},
+		{"UTF-16LE", []byte("\xff\xfeB\x00\xe4\x00"), EncodingAuto, "Bä", "utf-16le"},
+		{"UTF-16BE", []byte("\xfe\xff\x00B\x00\xe4"), EncodingAuto, "Bä", "utf-16be"},
+		{"Windows-1252 euro sign", []byte("\x80 M\xfcller"), EncodingAuto, "€ Müller", "windows-1252"},
+		{"ISO-8859-15 euro sign", []byte("\xa4"), "iso-8859-15", "€", "iso-8859-15"},
+		{"alias", []byte("\xe4"), "Latin1", "ä", "iso-8859-1"},
+		{"forced encoding", []byte("\xc3\xa4"), "cp1252", "Ã¤", "windows-1252"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r, name, err := Decode(bytes.NewReader(tc.b), tc.encoding)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != tc.want || name != tc.wantName {
+				t.Errorf("got %q as %s, want %q as %s", b, name, tc.want, tc.wantName)
+			}
+		})
+	}
+}
+
+func TestDecodeUnknownEncoding(t *testing.T) {
+	_, _, err := Decode(strings.NewReader("x"), "ebcdic")
+	if err == nil || !strings.Contains(err.Error(), `unknown encoding "ebcdic"`) {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestDecodeFiles(t *testing.T) {
+	for _, tc := range []struct {
+		file   string
+		format string
+	}{
+		{"sparkasse-windows-1252.csv", "sparkasse"},
+		{"camt053-iso-8859-15.xml", "camt"},
+	} {
+		t.Run(tc.file, func(t *testing.T) {
+			s := parseFile(t, tc.file, FormatAuto)
+			if s.Format != tc.format {
+				t.Errorf("detected %q, want %q", s.Format, tc.format)
+			}
+			checkStatement(t, s,
+				[]string{"DE02120300000000202051 2024-03-02 2024-03-02 Bäckerei Müller -2.50 EUR"},
+				nil,
+			)
+			if got := s.Transactions[0].Purpose; got != "Brötchen für 2,50 €" {
+				t.Errorf("got purpose %q", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,209 @@
+package importer
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ibihim/banking-csv-cli/pkg/transactions"
+)
+
+const (
+	// FormatAuto selects the importer by looking at the content of the file.
+	FormatAuto = "auto"
+
+	// headSize is the number of bytes that are used to detect the format.
+	headSize = 4096
+)
+
+// Statement contains the data read from a bank export.
+type Statement struct {
+	// Format is the name of the importer that read the statement.
+	Format string
+	// Transactions are the transactions of the statement.
+	Transactions []*transactions.Transaction
+	// Balances are the balances stated by the bank, if the format has them.
+	Balances []*transactions.Balance
+	// Rejected are the records that could not be parsed. Formats that can
+	// skip a broken record report it here instead of failing.
+	Rejected []*RecordError
+
+	// preamble are the records in front of the header of CSV files, and
+	// header is the header. Both are used to write rejected records.
+	preamble [][]string
+	header   []string
+}
+
+// RecordError is a record of a file that could not be parsed.
+type RecordError struct {
+	// Line is the line of the file the record starts at.
+	Line int
+	// Column is the name of the column that could not be parsed, if the
+	// error is caused by a single column.
+	Column string
+	// Record are the fields of the record as read from the file.
+	Record []string
+	// Err is the reason the record was rejected.
+	Err error
+}
+
+func (e *RecordError) Error() string {
+	if e.Column == "" {
+		return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+	}
+
+	return fmt.Sprintf("line %d, column %q: %v", e.Line, e.Column, e.Err)
+}
+
+func (e *RecordError) Unwrap() error {
+	return e.Err
+}
+
+// WriteRejected writes the rejected records of a statement as CSV, in the
+// format of the file they were read from. The records are preceded by the
+// line, the column and the reason they were rejected for. The preamble and
+// the header of the file are kept, so the records can be imported again,
+// into the same account, once they are fixed.
+func WriteRejected(w io.Writer, s *Statement) error {
+	comma := ';'
+	if i, err := Get(s.Format); err == nil {
+		if p, ok := i.(*csvProfile); ok {
+			comma = p.comma
+		}
+	}
+
+	cw := csv.NewWriter(w)
+	cw.Comma = comma
+
+	for _, record := range s.preamble {
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	if err := cw.Write(append([]string{"Line", "Column", "Reason"}, s.header...)); err != nil {
+		return err
+	}
+	for _, e := range s.Rejected {
+		record := append([]string{strconv.Itoa(e.Line), e.Column, e.Err.Error()}, e.Record...)
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+
+	return cw.Error()
+}
+
+// Importer reads a bank export format.
+type Importer interface {
+	// Name returns the name that is used to select the importer.
+	Name() string
+	// Detect returns true if the beginning of a file is in the importer's
+	// format.
+	Detect(head []byte) bool
+	// Parse reads the transactions from the file.
+	Parse(r io.Reader) (*Statement, error)
+}
+
+// importers is the registry of importers. The order matters for detection,
+// so the structured formats come first, then the bank profiles, and the
+// generic CSV profile, which accepts any CSV file with the basic columns,
+// comes last.
+var importers = []Importer{
+	&camtImporter{},
+	&mt940Importer{},
+	&ofxImporter{},
+	&qifImporter{},
+	sparkasseProfile,
+	dkbProfile,
+	dkbClassicProfile,
+	ingProfile,
+	n26Profile,
+	comdirectProfile,
+	genericProfile,
+}
+
+// Register adds an importer to the registry. It is detected after the
+// built-in importers.
+func Register(i Importer) {
+	for _, registered := range importers {
+		if registered.Name() == i.Name() {
+			panic(fmt.Sprintf("importer %q registered twice", i.Name()))
+		}
+	}
+
+	importers = append(importers, i)
+}
+
+// Get returns the importer with the given name.
+func Get(name string) (Importer, error) {
+	for _, i := range importers {
+		if i.Name() == name {
+			return i, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown format %q, expected one of: %s", name, strings.Join(Names(), ", "))
+}
+
+// Names returns the names of all registered importers.
+func Names() []string {
+	names := make([]string, 0, len(importers))
+	for _, i := range importers {
+		names = append(names, i.Name())
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Detect returns the first importer that understands the head of a file.
+func Detect(head []byte) (Importer, error) {
+	for _, i := range importers {
+		if i.Detect(head) {
+			return i, nil
+		}
+	}
+
+	return nil, errors.New("failed to detect format")
+}
+
+// Parse reads a bank export. The format is either the name of an importer
+// or FormatAuto.
+func Parse(r io.Reader, format string) (*Statement, error) {
+	br := bufio.NewReaderSize(r, headSize)
+
+	var (
+		i   Importer
+		err error
+	)
+	if format == "" || format == FormatAuto {
+		head, err := br.Peek(headSize)
+		if err != nil && err != io.EOF {
+			return nil, fmt.Errorf("failed to read head: %w", err)
+		}
+
+		i, err = Detect(head)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		i, err = Get(format)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	s, err := i.Parse(br)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", i.Name(), err)
+	}
+	s.Format = i.Name()
+
+	return s, nil
+}
@@ -0,0 +1,161 @@
+package importer
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ibihim/banking-csv-cli/pkg/transactions"
+)
+
+// parseFile decodes and parses a file of testdata.
+func parseFile(t *testing.T, name, format string) *Statement {
+	t.Helper()
+
+	f, err := os.Open(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	r, _, err := Decode(f, EncodingAuto)
+	if err != nil {
+		t.Fatalf("failed to decode %s: %v", name, err)
+	}
+	s, err := Parse(r, format)
+	if err != nil {
+		t.Fatalf("failed to parse %s: %v", name, err)
+	}
+
+	return s
+}
+
+// summarize returns the fields of a transaction most tests check, like
+// "DE02120300000000202051 2024-03-01 2024-03-01 Hausverwaltung Schmidt -850.00 EUR".
+func summarize(t *transactions.Transaction) string {
+	return fmt.Sprintf("%s %s %s %s %s %s",
+		t.Account,
+		t.BookingDate.Format("2006-01-02"),
+		t.ValutaDate.Format("2006-01-02"),
+		t.Beneficiary,
+		t.Amount,
+		t.Amount.Currency,
+	)
+}
+
+// summarizeBalances returns the balances of a statement, like
+// "DE02120300000000202051 closing 2024-03-31 -120.00 EUR".
+func summarizeBalances(s *Statement) []string {
+	var bs []string
+	for _, b := range s.Balances {
+		bs = append(bs, fmt.Sprintf("%s %s %s %s %s", b.Account, b.Type, b.Date.Format("2006-01-02"), b.Amount, b.Amount.Currency))
+	}
+
+	return bs
+}
+
+// rejected returns the lines and columns of the rejected records, like
+// "4 Buchungstag".
+func rejected(s *Statement) []string {
+	var rs []string
+	for _, e := range s.Rejected {
+		rs = append(rs, fmt.Sprintf("%d %s", e.Line, e.Column))
+	}
+
+	return rs
+}
+
+// checkStatement compares the summaries of the transactions and the
+// rejected records of a statement.
+func checkStatement(t *testing.T, s *Statement, wantTransactions, wantRejected []string) {
+	t.Helper()
+
+	var got []string
+	for _, tr := range s.Transactions {
+		got = append(got, summarize(tr))
+	}
+	if !reflect.DeepEqual(got, wantTransactions) {
+		t.Errorf("transactions:\ngot  %q\nwant %q", got, wantTransactions)
+	}
+	if got := rejected(s); !reflect.DeepEqual(got, wantRejected) {
+		t.Errorf("rejected:\ngot  %q\nwant %q", got, wantRejected)
+	}
+}
+
+func TestDetect(t *testing.T) {
+	for _, tc := range []struct {
+		file   string
+		format string
+	}{
+		{"sparkasse.csv", "sparkasse"},
+		{"dkb.csv", "dkb"},
+		{"dkb-classic.csv", "dkb-classic"},
+		{"ing.csv", "ing"},
+		{"n26.csv", "n26"},
+		{"comdirect.csv", "comdirect"},
+		{"generic.csv", "csv"},
+		{"camt053.xml", "camt"},
+		{"camt052.xml", "camt"},
+		{"mt940.sta", "mt940"},
+		{"sgml.ofx", "ofx"},
+		{"xml.ofx", "ofx"},
+		{"german.qif", "qif"},
+	} {
+		t.Run(tc.file, func(t *testing.T) {
+			if s := parseFile(t, tc.file, FormatAuto); s.Format != tc.format {
+				t.Errorf("detected %q, want %q", s.Format, tc.format)
+			}
+		})
+	}
+}
+
+func TestRegistryOrder(t *testing.T) {
+	var names []string
+	for _, i := range importers {
+		names = append(names, i.Name())
+	}
+
+	want := []string{"camt", "mt940", "ofx", "qif", "sparkasse", "dkb", "dkb-classic", "ing", "n26", "comdirect", "csv"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("got %q, want %q", names, want)
+	}
+}
+
+func TestWriteRejected(t *testing.T) {
+	s := parseFile(t, "dkb.csv", "dkb")
+
+	var buf bytes.Buffer
+	if err := WriteRejected(&buf, s); err != nil {
+		t.Fatal(err)
+	}
+
+	// The preamble keeps the account, the header gets the reason.
+	want := `Girokonto;DE12 1203 0000 1234 5678 90
+
+Kontostand vom 31.03.2024:;2.015,44 €
+
+Line;Column;Reason;Buchungsdatum;Wertstellung;Status;Zahlungspflichtige*r;Zahlungsempfänger*in;Verwendungszweck;Umsatztyp;IBAN;Betrag (€);Gläubiger-ID;Mandatsreferenz;Kundenreferenz
+8;Betrag (€);"invalid character 'z' in amount ""zwölf €""";26.03.24;26.03.24;Gebucht;Max Mustermann;Bäckerei;Brötchen;Ausgang;DE44500105175407324931;zwölf €;;;
+`
+	if got := buf.String(); got != want {
+		t.Fatalf("got\n%s\nwant\n%s", got, want)
+	}
+
+	// Once fixed, the records are imported into the same account.
+	fixed := strings.Replace(buf.String(), ";zwölf €;", ";-12,00 €;", 1)
+	s, err := Parse(strings.NewReader(fixed), FormatAuto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Format != "dkb" {
+		t.Errorf("detected %q, want dkb", s.Format)
+	}
+	checkStatement(t, s,
+		[]string{"DE12120300001234567890 2024-03-26 2024-03-26 Bäckerei -12.00 EUR"},
+		nil,
+	)
+}
@@ -0,0 +1,321 @@
+package importer
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ibihim/banking-csv-cli/pkg/transactions"
+)
+
+// mt940Importer reads SWIFT MT940 customer statements, including the
+// structured :86: subfields used by German banks.
+type mt940Importer struct{}
+
+var _ Importer = &mt940Importer{}
+
+var (
+	// mt940TagRegexp matches the tag at the beginning of a field.
+	mt940TagRegexp = regexp.MustCompile(`^:([0-9]{2}[A-Z]?):`)
+	// mt940BalanceRegexp matches :60F: and :62F:, e.g. "C230331EUR1000,00".
+	mt940BalanceRegexp = regexp.MustCompile(`^([CD])([0-9]{6})([A-Z]{3})([0-9]+,[0-9]*)`)
+	// mt940StatementLineRegexp matches :61:, e.g.
+	// "2304030403DR50,00NDDTNONREF//XYZ". The bank reference and the
+	// supplementary details are not used.
+	mt940StatementLineRegexp = regexp.MustCompile(`^([0-9]{6})([0-9]{4})?(C|D|RC|RD)([A-Z])?([0-9]+,[0-9]*)([NSF][A-Z0-9]{3})([^\n]*?)(?://[^\n]*)?(?:\n[\s\S]*)?$`)
+	// mt940KeywordRegexp matches the SEPA keywords within the purpose of :86:.
+	mt940KeywordRegexp = regexp.MustCompile(`(EREF|KREF|MREF|CRED|DEBT|SVWZ|ABWA|ABWE|COAM|OAMT)\+`)
+)
+
+// mt940Field is a tag and its value, continuation lines included.
+type mt940Field struct {
+	tag   string
+	value string
+}
+
+// Name returns the name of the importer.
+func (m *mt940Importer) Name() string {
+	return "mt940"
+}
+
+// Detect returns true if the head of the file contains the mandatory tags
+// of a statement.
+func (m *mt940Importer) Detect(head []byte) bool {
+	return bytes.Contains(head, []byte(":20:")) &&
+		bytes.Contains(head, []byte(":25:")) &&
+		(bytes.Contains(head, []byte(":28C:")) || bytes.Contains(head, []byte(":60F:")))
+}
+
+// Parse parses the statements of a MT940 file.
+func (m *mt940Importer) Parse(r io.Reader) (*Statement, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		s        = &Statement{}
+		account  string
+		currency string
+		last     *transactions.Transaction
+	)
+	for _, field := range splitMT940Fields(string(b)) {
+		switch field.tag {
+		case "20":
+			account, currency, last = "", "", nil
+
+		case "25":
+			account = strings.ReplaceAll(strings.TrimSpace(field.value), " ", "")
+
+		case "60F", "60M", "62F", "62M":
+			balance, err := parseMT940Balance(field)
+			if err != nil {
+				return nil, err
+			}
+			balance.Account = account
+			currency = balance.Amount.Currency
+			s.Balances = append(s.Balances, balance)
+
+		case "61":
+			t, err := parseMT940StatementLine(field.value, currency)
+			if err != nil {
+				return nil, err
+			}
+			t.Account = account
+			s.Transactions = append(s.Transactions, t)
+			last = t
+
+		case "86":
+			if last == nil {
+				continue
+			}
+			if err := parseMT940Information(last, field.value); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return s, nil
+}
+
+// splitMT940Fields splits the file into fields. SWIFT headers, trailers and
+// the "-" that ends a statement are dropped.
+func splitMT940Fields(s string) []mt940Field {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+
+	var (
+		fields  []mt940Field
+		current *mt940Field
+	)
+	for _, line := range strings.Split(s, "\n") {
+		if match := mt940TagRegexp.FindStringSubmatch(line); match != nil {
+			fields = append(fields, mt940Field{tag: match[1], value: line[len(match[0]):]})
+			current = &fields[len(fields)-1]
+			continue
+		}
+
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "-" || trimmed == "-}" {
+			current = nil
+			continue
+		}
+
+		if current != nil {
+			current.value += "\n" + line
+		}
+	}
+
+	return fields
+}
+
+func parseMT940Balance(field mt940Field) (*transactions.Balance, error) {
+	match := mt940BalanceRegexp.FindStringSubmatch(field.value)
+	if match == nil {
+		return nil, fmt.Errorf("failed to parse balance :%s:%s", field.tag, field.value)
+	}
+
+	date, err := time.Parse("060102", match[2])
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse balance date (%q): %w", match[2], err)
+	}
+
+	amount, err := parseMT940Amount(match[4], match[3])
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse balance amount (%q): %w", match[4], err)
+	}
+	if match[1] == "D" {
+		amount = amount.Neg()
+	}
+
+	balanceType := transactions.OpeningBalance
+	if strings.HasPrefix(field.tag, "62") {
+		balanceType = transactions.ClosingBalance
+	}
+
+	return &transactions.Balance{
+		Type:   balanceType,
+		Date:   date,
+		Amount: amount,
+	}, nil
+}
+
+func parseMT940StatementLine(value, currency string) (*transactions.Transaction, error) {
+	match := mt940StatementLineRegexp.FindStringSubmatch(value)
+	if match == nil {
+		return nil, fmt.Errorf("failed to parse statement line :61:%s", value)
+	}
+
+	valutaDate, err := time.Parse("060102", match[1])
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse valuta date (%q): %w", match[1], err)
+	}
+
+	// The booking date has no year, it is taken from the valuta date.
+	bookingDate := valutaDate
+	if match[2] != "" {
+		year := valutaDate.Year()
+		switch {
+		case strings.HasPrefix(match[2], "12") && valutaDate.Month() == time.January:
+			year--
+		case strings.HasPrefix(match[2], "01") && valutaDate.Month() == time.December:
+			year++
+		}
+
+		bookingDate, err = time.Parse("20060102", fmt.Sprintf("%04d%s", year, match[2]))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse booking date (%q): %w", match[2], err)
+		}
+	}
+
+	amount, err := parseMT940Amount(match[5], currency)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse amount (%q): %w", match[5], err)
+	}
+	// A reversal of a credit is a debit and vice versa.
+	if match[3] == "D" || match[3] == "RC" {
+		amount = amount.Neg()
+	}
+
+	return &transactions.Transaction{
+		BookingDate: bookingDate,
+		ValutaDate:  valutaDate,
+		CustomerRef: notProvided(strings.TrimSpace(match[7])),
+		Amount:      amount,
+		BookingText: match[6],
+	}, nil
+}
+
+// parseMT940Information applies the :86: information to the transaction. If
+// the field is not structured, it is used as purpose.
+func parseMT940Information(t *transactions.Transaction, value string) error {
+	value = strings.ReplaceAll(value, "\n", "")
+
+	// Structured fields start with a business transaction code followed by
+	// the separator, e.g. "105?00".
+	if len(value) < 4 || !isDigits(value[:3]) {
+		t.Purpose = value
+		return nil
+	}
+	separator := value[3:4]
+
+	subfields := map[string]string{}
+	var purpose strings.Builder
+	for _, sub := range strings.Split(value[4:], separator) {
+		if len(sub) < 2 || !isDigits(sub[:2]) {
+			continue
+		}
+
+		key, content := sub[:2], sub[2:]
+		if (key >= "20" && key <= "29") || (key >= "60" && key <= "63") {
+			purpose.WriteString(content)
+			continue
+		}
+		subfields[key] += content
+	}
+
+	if text := subfields["00"]; text != "" {
+		t.BookingText = text
+	}
+	t.BIC = subfields["30"]
+	t.AccountNumber = subfields["31"]
+	t.Beneficiary = subfields["32"] + subfields["33"]
+
+	keywords := splitSEPAKeywords(purpose.String())
+	if len(keywords) == 0 {
+		t.Purpose = purpose.String()
+		return nil
+	}
+
+	t.Purpose = keywords["SVWZ"]
+	t.MandateRef = keywords["MREF"]
+	t.CreditorID = keywords["CRED"]
+	if ref := notProvided(keywords["EREF"]); ref != "" {
+		t.CustomerRef = ref
+	}
+	if ref := notProvided(keywords["KREF"]); ref != "" {
+		t.CollectorRef = ref
+	}
+
+	// An ultimate party is preferred over the direct one, like in camt.
+	if t.Amount.IsNegative() && keywords["ABWE"] != "" {
+		t.Beneficiary = keywords["ABWE"]
+	}
+	if !t.Amount.IsNegative() && keywords["ABWA"] != "" {
+		t.Beneficiary = keywords["ABWA"]
+	}
+
+	var err error
+	if oamt := keywords["OAMT"]; oamt != "" {
+		if t.OrigAmount, err = parseMT940Amount(oamt, t.Amount.Currency); err != nil {
+			return fmt.Errorf("failed to parse original amount (%q): %w", oamt, err)
+		}
+	}
+	if coam := keywords["COAM"]; coam != "" {
+		if t.ChargebackFee, err = parseMT940Amount(coam, t.Amount.Currency); err != nil {
+			return fmt.Errorf("failed to parse compensation amount (%q): %w", coam, err)
+		}
+	}
+
+	return nil
+}
+
+// splitSEPAKeywords splits a purpose like "EREF+123MREF+456SVWZ+Rent" into
+// its keywords.
+func splitSEPAKeywords(s string) map[string]string {
+	matches := mt940KeywordRegexp.FindAllStringSubmatchIndex(s, -1)
+	if len(matches) == 0 {
+		return nil
+	}
+
+	keywords := map[string]string{}
+	for i, match := range matches {
+		end := len(s)
+		if i+1 < len(matches) {
+			end = matches[i+1][0]
+		}
+
+		keyword := s[match[2]:match[3]]
+		keywords[keyword] = strings.TrimSpace(s[match[1]:end])
+	}
+
+	return keywords
+}
+
+// mt940Amounts are amounts like "1234,56". The sign is stated separately.
+var mt940Amounts = amountFormat{decimal: ','}
+
+func parseMT940Amount(s, currency string) (transactions.Money, error) {
+	return mt940Amounts.parse(s, currency)
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
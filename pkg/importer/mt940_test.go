@@ -0,0 +1,115 @@
+package importer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ibihim/banking-csv-cli/pkg/transactions"
+)
+
+func TestMT940(t *testing.T) {
+	s := parseFile(t, "mt940.sta", "mt940")
+
+	checkStatement(t, s,
+		[]string{
+			"12030000/1234567890 2023-12-29 2023-12-29 Stadtwerke Energie GmbH -850.00 EUR",
+			"12030000/1234567890 2024-01-02 2024-01-02 Arbeitgeber AG 3100.00 EUR",
+			// The booking date is in the year before the valuta date.
+			"12030000/1234567890 2023-12-31 2024-01-02 Verein 40.00 EUR",
+			"12030000/1234567890 2024-01-03 2024-01-03  12.50 EUR",
+		},
+		nil,
+	)
+
+	wantBalances := []string{
+		"12030000/1234567890 opening 2023-12-29 1000.00 EUR",
+		"12030000/1234567890 closing 2024-01-03 3232.50 EUR",
+	}
+	if got := summarizeBalances(s); !reflect.DeepEqual(got, wantBalances) {
+		t.Errorf("balances:\ngot  %q\nwant %q", got, wantBalances)
+	}
+}
+
+func TestMT940Information(t *testing.T) {
+	s := parseFile(t, "mt940.sta", "mt940")
+	if len(s.Transactions) != 4 {
+		t.Fatalf("got %d transactions, want 4", len(s.Transactions))
+	}
+
+	for _, tc := range []struct {
+		name string
+		got  *transactions.Transaction
+		want transactions.Transaction
+	}{
+		{
+			name: "direct debit",
+			got:  s.Transactions[0],
+			want: transactions.Transaction{
+				BookingText:   "FOLGELASTSCHRIFT",
+				Purpose:       "Strom Dezember",
+				CustomerRef:   "E2E-4711",
+				MandateRef:    "M-0815",
+				CreditorID:    "DE12ZZZ00000123456",
+				BIC:           "INGDDEFFXXX",
+				AccountNumber: "DE44500105175407324931",
+			},
+		},
+		{
+			name: "credit transfer",
+			got:  s.Transactions[1],
+			want: transactions.Transaction{
+				BookingText: "GUTSCHRIFT",
+				Purpose:     "Lohn Dezember",
+			},
+		},
+		{
+			name: "returned direct debit",
+			got:  s.Transactions[2],
+			want: transactions.Transaction{
+				BookingText:   "RUECKLASTSCHRIFT",
+				Purpose:       "Beitrag",
+				CustomerRef:   "E2E-1",
+				OrigAmount:    transactions.NewMoney(3700, "EUR"),
+				ChargebackFee: transactions.NewMoney(300, "EUR"),
+			},
+		},
+		{
+			name: "unstructured",
+			got:  s.Transactions[3],
+			want: transactions.Transaction{
+				BookingText: "NMSC",
+				Purpose:     "Zinsen fuer 2023",
+				CustomerRef: "KREF123",
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := transactions.Transaction{
+				BookingText:   tc.got.BookingText,
+				Purpose:       tc.got.Purpose,
+				CustomerRef:   tc.got.CustomerRef,
+				MandateRef:    tc.got.MandateRef,
+				CreditorID:    tc.got.CreditorID,
+				BIC:           tc.got.BIC,
+				AccountNumber: tc.got.AccountNumber,
+				OrigAmount:    tc.got.OrigAmount,
+				ChargebackFee: tc.got.ChargebackFee,
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got  %+v\nwant %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSplitSEPAKeywords(t *testing.T) {
+	got := splitSEPAKeywords("EREF+123 MREF+456SVWZ+Miete Maerz ABWA+Max")
+	want := map[string]string{"EREF": "123", "MREF": "456", "SVWZ": "Miete Maerz", "ABWA": "Max"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	if got := splitSEPAKeywords("Miete Maerz"); got != nil {
+		t.Errorf("got %q for a purpose without keywords", got)
+	}
+}
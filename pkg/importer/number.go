@@ -0,0 +1,141 @@
+package importer
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/ibihim/banking-csv-cli/pkg/transactions"
+)
+
+var (
+	// germanAmounts are amounts like "-1.234,56".
+	germanAmounts = amountFormat{decimal: ',', thousands: '.'}
+	// englishAmounts are amounts like "-1,234.56".
+	englishAmounts = amountFormat{decimal: '.', thousands: ','}
+)
+
+// debitIndicators and creditIndicators mark the direction of an amount,
+// either as suffix or prefix of the amount or in a separate column. "S" and
+// "H" are the German "Soll" and "Haben".
+var (
+	debitIndicators  = []string{"S", "DR", "D", "DBIT", "-"}
+	creditIndicators = []string{"H", "CR", "C", "CRDT", "+"}
+)
+
+// currencySymbols are removed from amounts.
+var currencySymbols = []string{"€", "$", "£", "¥", "₣", "CHF", "EUR", "USD", "GBP"}
+
+// amountFormat describes how a bank writes amounts.
+type amountFormat struct {
+	// decimal is the decimal separator.
+	decimal rune
+	// thousands is the thousands separator, 0 if there is none.
+	thousands rune
+}
+
+// parse parses an amount. Besides a leading minus, it understands a
+// trailing minus ("123,45-"), parentheses ("(123,45)"), debit and credit
+// indicators ("123,45 S") and currency symbols ("-1.234,56 €").
+func (f amountFormat) parse(s, currency string) (transactions.Money, error) {
+	orig := s
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return transactions.Money{Currency: currency}, nil
+	}
+
+	for _, symbol := range currencySymbols {
+		s = strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(s, symbol), symbol))
+	}
+
+	negative := false
+	if strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") {
+		negative = true
+		s = strings.TrimSpace(s[1 : len(s)-1])
+	}
+
+	if indicator, rest, ok := cutIndicator(s); ok {
+		// An amount like "-123,45 S" is ambiguous, it is either a debit or
+		// a negated debit.
+		if _, _, signed := cutIndicator(rest); signed || negative {
+			return transactions.Money{}, fmt.Errorf("amount %q has both a sign and a debit or credit indicator", orig)
+		}
+		negative = isDebit(indicator)
+		s = rest
+	}
+
+	// The currency symbol might be between the sign and the amount.
+	for _, symbol := range currencySymbols {
+		s = strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(s, symbol), symbol))
+	}
+
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == f.thousands && f.thousands != 0:
+		case r == f.decimal:
+			b.WriteRune('.')
+		case unicode.IsDigit(r):
+			b.WriteRune(r)
+		case unicode.IsSpace(r):
+			// Some banks group thousands with spaces.
+		default:
+			return transactions.Money{}, fmt.Errorf("invalid character %q in amount %q", r, orig)
+		}
+	}
+
+	m, err := transactions.ParseMoney(b.String(), currency)
+	if err != nil {
+		return transactions.Money{}, err
+	}
+	if negative {
+		m = m.Neg()
+	}
+
+	return m, nil
+}
+
+// cutIndicator removes a debit or credit indicator from the beginning or
+// the end of an amount.
+func cutIndicator(s string) (indicator, rest string, ok bool) {
+	for _, indicators := range [][]string{debitIndicators, creditIndicators} {
+		for _, i := range indicators {
+			if strings.HasPrefix(s, i) && startsWithAmount(s[len(i):]) {
+				return i, strings.TrimSpace(s[len(i):]), true
+			}
+			if strings.HasSuffix(s, i) && endsWithAmount(s[:len(s)-len(i)]) {
+				return i, strings.TrimSpace(s[:len(s)-len(i)]), true
+			}
+		}
+	}
+
+	return "", s, false
+}
+
+func startsWithAmount(s string) bool {
+	s = strings.TrimLeftFunc(s, unicode.IsSpace)
+	for _, symbol := range currencySymbols {
+		s = strings.TrimSpace(strings.TrimPrefix(s, symbol))
+	}
+	return s != "" && (unicode.IsDigit(rune(s[0])) || s[0] == '.' || s[0] == ',')
+}
+
+func endsWithAmount(s string) bool {
+	s = strings.TrimRightFunc(s, unicode.IsSpace)
+	for _, symbol := range currencySymbols {
+		s = strings.TrimSpace(strings.TrimSuffix(s, symbol))
+	}
+	return s != "" && unicode.IsDigit(rune(s[len(s)-1]))
+}
+
+// isDebit returns true if the indicator marks a debit. It is used for
+// indicators within the amount and for separate indicator columns.
+func isDebit(indicator string) bool {
+	indicator = strings.ToUpper(strings.TrimSpace(indicator))
+	for _, i := range debitIndicators {
+		if indicator == i {
+			return true
+		}
+	}
+	return false
+}
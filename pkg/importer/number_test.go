@@ -0,0 +1,104 @@
+package importer
+
+import "testing"
+
+func TestAmountFormatParse(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		format   amountFormat
+		amount   string
+		currency string
+		want     string
+		wantErr  bool
+	}{
+		{name: "german", format: germanAmounts, amount: "-1.234,56", currency: "EUR", want: "-1234.56"},
+		{name: "english", format: englishAmounts, amount: "-1,234.56", currency: "USD", want: "-1234.56"},
+		{name: "empty", format: germanAmounts, amount: " ", currency: "EUR", want: "0.00"},
+		{name: "plus sign", format: germanAmounts, amount: "+12,00", currency: "EUR", want: "12.00"},
+		{name: "trailing minus", format: germanAmounts, amount: "123,45-", currency: "EUR", want: "-123.45"},
+		{name: "parentheses", format: englishAmounts, amount: "(123.45)", currency: "USD", want: "-123.45"},
+		{name: "currency symbol suffix", format: germanAmounts, amount: "-1.234,56 €", currency: "EUR", want: "-1234.56"},
+		{name: "currency symbol prefix", format: englishAmounts, amount: "$1,234.56", currency: "USD", want: "1234.56"},
+		{name: "currency code between sign and amount", format: germanAmounts, amount: "- EUR 12,00", currency: "EUR", want: "-12.00"},
+		{name: "spaces as thousands separator", format: germanAmounts, amount: "1 234 567,89", currency: "EUR", want: "1234567.89"},
+		{name: "no decimals", format: germanAmounts, amount: "100", currency: "EUR", want: "100.00"},
+		{name: "yen", format: englishAmounts, amount: "-3,500", currency: "JPY", want: "-3500"},
+		{name: "debit suffix", format: germanAmounts, amount: "123,45 S", currency: "EUR", want: "-123.45"},
+		{name: "credit suffix", format: germanAmounts, amount: "123,45 H", currency: "EUR", want: "123.45"},
+		{name: "debit prefix", format: englishAmounts, amount: "DR 99.00", currency: "GBP", want: "-99.00"},
+		{name: "credit prefix", format: englishAmounts, amount: "CR99.00", currency: "GBP", want: "99.00"},
+		{name: "DBIT suffix", format: englishAmounts, amount: "10.00 DBIT", currency: "EUR", want: "-10.00"},
+		{name: "CRDT suffix", format: englishAmounts, amount: "10.00 CRDT", currency: "EUR", want: "10.00"},
+		{name: "sign and debit indicator", format: germanAmounts, amount: "-123,45 S", currency: "EUR", wantErr: true},
+		{name: "sign and credit indicator", format: germanAmounts, amount: "+123,45 H", currency: "EUR", wantErr: true},
+		{name: "trailing minus and indicator", format: englishAmounts, amount: "CR 99.00-", currency: "GBP", wantErr: true},
+		{name: "parentheses and indicator", format: englishAmounts, amount: "(99.00 DR)", currency: "GBP", wantErr: true},
+		{name: "letters", format: germanAmounts, amount: "12,00 Euro", currency: "EUR", wantErr: true},
+		{name: "too many decimals", format: germanAmounts, amount: "1,234", currency: "EUR", wantErr: true},
+		{name: "english amount as german", format: germanAmounts, amount: "1,234.56", currency: "EUR", wantErr: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := tc.format.parse(tc.amount, tc.currency)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("got %s, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.String() != tc.want || got.Currency != tc.currency {
+				t.Errorf("got %s %s, want %s %s", got, got.Currency, tc.want, tc.currency)
+			}
+		})
+	}
+}
+
+func TestIsDebit(t *testing.T) {
+	for indicator, want := range map[string]bool{
+		"S":    true,
+		" s ":  true,
+		"DR":   true,
+		"dbit": true,
+		"-":    true,
+		"H":    false,
+		"CR":   false,
+		"CRDT": false,
+		"+":    false,
+		"":     false,
+		"Soll": false,
+	} {
+		if got := isDebit(indicator); got != want {
+			t.Errorf("isDebit(%q) = %v, want %v", indicator, got, want)
+		}
+	}
+}
+
+func TestIndicatorColumn(t *testing.T) {
+	s := parseFile(t, "indicator.csv", "csv")
+
+	checkStatement(t, s,
+		[]string{
+			"DE02120300000000202051 2024-03-01 2024-03-01 Hausverwaltung Schmidt -850.00 EUR",
+			"DE02120300000000202051 2024-03-05 2024-03-05 Arbeitgeber AG 3100.00 EUR",
+			// The indicator does not negate an amount twice.
+			"DE02120300000000202051 2024-03-06 2024-03-06 Kiosk -2.50 EUR",
+		},
+		nil,
+	)
+}
+
+func TestDebitCreditColumns(t *testing.T) {
+	s := parseFile(t, "debit-credit.csv", "csv")
+
+	checkStatement(t, s,
+		[]string{
+			"DE02120300000000202051 2024-03-01 2024-03-01 Hausverwaltung Schmidt -850.00 EUR",
+			"DE02120300000000202051 2024-03-05 2024-03-05 Arbeitgeber AG 3100.00 EUR",
+			// Debits are stated either with or without sign.
+			"DE02120300000000202051 2024-03-06 2024-03-06 Kiosk -2.50 EUR",
+		},
+		[]string{"5 Haben"},
+	)
+}
@@ -0,0 +1,191 @@
+package importer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/ibihim/banking-csv-cli/pkg/transactions"
+)
+
+// ofxImporter reads OFX 1.x (SGML) and OFX 2.x (XML) bank and credit card
+// statements. QFX files are OFX files with additional Quicken elements.
+type ofxImporter struct{}
+
+var _ Importer = &ofxImporter{}
+
+// Name returns the name of the importer.
+func (o *ofxImporter) Name() string {
+	return "ofx"
+}
+
+// Detect returns true if the head of the file has an OFX header.
+func (o *ofxImporter) Detect(head []byte) bool {
+	upper := bytes.ToUpper(head)
+	return bytes.Contains(upper, []byte("OFXHEADER")) || bytes.Contains(upper, []byte("<OFX>"))
+}
+
+// Parse parses the statements of an OFX file. SGML does not close elements
+// that contain a value, so the file is read as a stream of tags. Elements
+// with a value are leaves, all others are aggregates.
+func (o *ofxImporter) Parse(r io.Reader) (*Statement, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	start := bytes.Index(bytes.ToUpper(b), []byte("<OFX>"))
+	if start < 0 {
+		return nil, errors.New("failed to find <OFX> element")
+	}
+	body := string(b[start:])
+
+	var (
+		s        = &Statement{}
+		account  string
+		currency string
+		trn      map[string]string
+		ledger   map[string]string
+	)
+	for len(body) > 0 {
+		open := strings.IndexByte(body, '<')
+		if open < 0 {
+			break
+		}
+		end := strings.IndexByte(body[open:], '>')
+		if end < 0 {
+			break
+		}
+
+		tag := strings.ToUpper(strings.TrimSpace(body[open+1 : open+end]))
+		body = body[open+end+1:]
+
+		value := body
+		if next := strings.IndexByte(body, '<'); next >= 0 {
+			value = body[:next]
+		}
+		value = ofxUnescape(strings.TrimSpace(value))
+
+		switch {
+		case tag == "STMTRS" || tag == "CCSTMTRS":
+			account, currency = "", ""
+		case tag == "STMTTRN":
+			trn = map[string]string{}
+		case tag == "/STMTTRN" && trn != nil:
+			t, err := parseOFXTransaction(trn, currency)
+			if err != nil {
+				return nil, err
+			}
+			t.Account = account
+			s.Transactions = append(s.Transactions, t)
+			trn = nil
+		case tag == "LEDGERBAL":
+			ledger = map[string]string{}
+		case tag == "/LEDGERBAL" && ledger != nil:
+			b, err := parseOFXBalance(ledger, currency)
+			if err != nil {
+				return nil, err
+			}
+			b.Account = account
+			s.Balances = append(s.Balances, b)
+			ledger = nil
+		case strings.HasPrefix(tag, "/") || value == "":
+			// Closing tags of leaves and other aggregates.
+		case trn != nil:
+			if _, ok := trn[tag]; !ok {
+				trn[tag] = value
+			}
+		case ledger != nil:
+			ledger[tag] = value
+		case tag == "ACCTID":
+			account = value
+		case tag == "CURDEF":
+			currency = value
+		}
+	}
+
+	return s, nil
+}
+
+func parseOFXTransaction(trn map[string]string, currency string) (*transactions.Transaction, error) {
+	bookingDate, err := parseOFXDate(trn["DTPOSTED"])
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse DTPOSTED (in transaction %q): %w", trn["FITID"], err)
+	}
+
+	valutaDate := bookingDate
+	for _, key := range []string{"DTUSER", "DTAVAIL"} {
+		if date, err := parseOFXDate(trn[key]); err == nil {
+			valutaDate = date
+			break
+		}
+	}
+
+	// The currency of a transaction is only stated if it differs from the
+	// statement's default.
+	if cur := trn["CURSYM"]; cur != "" {
+		currency = cur
+	}
+
+	amount, err := parseOFXAmount(trn["TRNAMT"], currency)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse TRNAMT (in transaction %q): %w", trn["FITID"], err)
+	}
+
+	t := &transactions.Transaction{
+		FITID:         trn["FITID"],
+		BookingDate:   bookingDate,
+		ValutaDate:    valutaDate,
+		BookingText:   trn["TRNTYPE"],
+		Purpose:       trn["MEMO"],
+		CustomerRef:   firstNonEmpty(trn["REFNUM"], trn["CHECKNUM"]),
+		Beneficiary:   trn["NAME"],
+		AccountNumber: trn["ACCTID"],
+		BIC:           trn["BANKID"],
+		Amount:        amount,
+	}
+
+	return t, nil
+}
+
+func parseOFXBalance(ledger map[string]string, currency string) (*transactions.Balance, error) {
+	date, err := parseOFXDate(ledger["DTASOF"])
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse DTASOF of ledger balance: %w", err)
+	}
+
+	amount, err := parseOFXAmount(ledger["BALAMT"], currency)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse BALAMT of ledger balance: %w", err)
+	}
+
+	return &transactions.Balance{
+		Type:   transactions.ClosingBalance,
+		Date:   date,
+		Amount: amount,
+	}, nil
+}
+
+// parseOFXDate parses dates like "20230403", "20230403120000" or
+// "20230403120000.000[-5:EST]". Only the date is used.
+func parseOFXDate(s string) (time.Time, error) {
+	if len(s) > len("20060102") {
+		s = s[:len("20060102")]
+	}
+
+	return time.Parse("20060102", s)
+}
+
+// parseOFXAmount parses amounts. Some banks use a decimal comma.
+func parseOFXAmount(s, currency string) (transactions.Money, error) {
+	return transactions.ParseMoney(strings.ReplaceAll(s, ",", "."), currency)
+}
+
+var ofxUnescaper = strings.NewReplacer("&lt;", "<", "&gt;", ">", "&quot;", `"`, "&apos;", "'", "&amp;", "&")
+
+func ofxUnescape(s string) string {
+	return ofxUnescaper.Replace(s)
+}
@@ -0,0 +1,86 @@
+package importer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestOFX(t *testing.T) {
+	for _, tc := range []struct {
+		name             string
+		file             string
+		wantTransactions []string
+		wantBalances     []string
+	}{
+		{
+			name: "SGML",
+			file: "sgml.ofx",
+			wantTransactions: []string{
+				"4003921 2024-03-05 2024-03-04 AT&T -45.20 USD",
+				"4003921 2024-03-10 2024-03-10 Landlord LLC -1200.00 USD",
+				"4003921 2024-03-15 2024-03-15 Payroll 2500.00 USD",
+			},
+			wantBalances: []string{
+				"4003921 closing 2024-03-31 3254.80 USD",
+			},
+		},
+		{
+			name: "XML",
+			file: "xml.ofx",
+			wantTransactions: []string{
+				"DE02120300000000202051 2024-03-02 2024-03-03 Buchhandlung Müller -12.99 EUR",
+				// The currency of a transaction overrides the default.
+				"DE02120300000000202051 2024-03-06 2024-03-06 Ramen Shop -3500 JPY",
+			},
+			wantBalances: []string{
+				"DE02120300000000202051 closing 2024-03-31 -512.99 EUR",
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s := parseFile(t, tc.file, "ofx")
+			checkStatement(t, s, tc.wantTransactions, nil)
+			if got := summarizeBalances(s); !reflect.DeepEqual(got, tc.wantBalances) {
+				t.Errorf("balances:\ngot  %q\nwant %q", got, tc.wantBalances)
+			}
+		})
+	}
+}
+
+func TestOFXFields(t *testing.T) {
+	s := parseFile(t, "sgml.ofx", "ofx")
+	if len(s.Transactions) != 3 {
+		t.Fatalf("got %d transactions, want 3", len(s.Transactions))
+	}
+
+	phone := s.Transactions[0]
+	if phone.FITID != "20240305-1" || phone.BookingText != "DEBIT" || phone.Purpose != "Phone bill" {
+		t.Errorf("got FITID %q, booking text %q and purpose %q", phone.FITID, phone.BookingText, phone.Purpose)
+	}
+	if check := s.Transactions[1]; check.CustomerRef != "1042" {
+		t.Errorf("got check number %q", check.CustomerRef)
+	}
+
+	s = parseFile(t, "xml.ofx", "ofx")
+	if got := s.Transactions[0].Purpose; got != "Roman <Taschenbuch>" {
+		t.Errorf("got purpose %q", got)
+	}
+}
+
+func TestOFXErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		file string
+	}{
+		{"no OFX element", "OFXHEADER:100\nDATA:OFXSGML\n"},
+		{"broken date", "<OFX><STMTRS><CURDEF>EUR<STMTTRN><DTPOSTED>2024031<TRNAMT>1.00<FITID>1</STMTTRN></STMTRS></OFX>"},
+		{"broken amount", "<OFX><STMTRS><CURDEF>EUR<STMTTRN><DTPOSTED>20240301<TRNAMT>ten<FITID>1</STMTTRN></STMTRS></OFX>"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := Parse(strings.NewReader(tc.file), "ofx"); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,201 @@
+package importer
+
+// sparkasseProfile reads the "CSV-CAMT" export of Sparkasse.
+var sparkasseProfile = &csvProfile{
+	name:        "sparkasse",
+	comma:       ';',
+	dateLayouts: []string{"02.01.06"},
+	amounts:     germanAmounts,
+	columns: map[column][]string{
+		columnAccount:           {"Auftragskonto"},
+		columnBookingDate:       {"Buchungstag"},
+		columnValutaDate:        {"Valutadatum"},
+		columnBookingText:       {"Buchungstext"},
+		columnPurpose:           {"Verwendungszweck"},
+		columnCreditorID:        {"Glaeubiger ID"},
+		columnMandateRef:        {"Mandatsreferenz"},
+		columnCustomerRef:       {"Kundenreferenz (End-to-End)"},
+		columnCollectorRef:      {"Sammlerreferenz"},
+		columnOrigAmount:        {"Lastschrift Ursprungsbetrag"},
+		columnChargebackFee:     {"Auslagenersatz Ruecklastschrift"},
+		columnBeneficiary:       {"Beguenstigter/Zahlungspflichtiger"},
+		columnAccountNumber:     {"Kontonummer/IBAN"},
+		columnBIC:               {"BIC (SWIFT-Code)"},
+		columnAmount:            {"Betrag"},
+		columnCurrency:          {"Waehrung"},
+		columnAdditionalDetails: {"Info"},
+	},
+	required: []column{
+		columnAccount,
+		columnBookingDate,
+		columnValutaDate,
+		columnBeneficiary,
+		columnAmount,
+		columnCurrency,
+	},
+}
+
+// dkbProfile reads the CSV export of DKB's banking, introduced in 2023.
+var dkbProfile = &csvProfile{
+	name:        "dkb",
+	comma:       ';',
+	dateLayouts: []string{"02.01.06", "02.01.2006"},
+	amounts:     germanAmounts,
+	currency:    "EUR",
+	columns: map[column][]string{
+		columnBookingDate:       {"Buchungsdatum"},
+		columnValutaDate:        {"Wertstellung"},
+		columnAdditionalDetails: {"Status"},
+		columnPayer:             {"Zahlungspflichtige*r"},
+		columnPayee:             {"Zahlungsempfänger*in"},
+		columnPurpose:           {"Verwendungszweck"},
+		columnBookingText:       {"Umsatztyp"},
+		columnAccountNumber:     {"IBAN"},
+		columnAmount:            {"Betrag (€)"},
+		columnCreditorID:        {"Gläubiger-ID"},
+		columnMandateRef:        {"Mandatsreferenz"},
+		columnCustomerRef:       {"Kundenreferenz"},
+	},
+	required: []column{
+		columnBookingDate,
+		columnPayer,
+		columnPayee,
+		columnAmount,
+	},
+}
+
+// dkbClassicProfile reads the CSV export of DKB's former banking.
+var dkbClassicProfile = &csvProfile{
+	name:        "dkb-classic",
+	comma:       ';',
+	dateLayouts: []string{"02.01.2006"},
+	amounts:     germanAmounts,
+	currency:    "EUR",
+	columns: map[column][]string{
+		columnBookingDate:   {"Buchungstag"},
+		columnValutaDate:    {"Wertstellung"},
+		columnBookingText:   {"Buchungstext"},
+		columnBeneficiary:   {"Auftraggeber / Begünstigter"},
+		columnPurpose:       {"Verwendungszweck"},
+		columnAccountNumber: {"Kontonummer"},
+		columnBIC:           {"BLZ"},
+		columnAmount:        {"Betrag (EUR)"},
+		columnCreditorID:    {"Gläubiger-ID"},
+		columnMandateRef:    {"Mandatsreferenz"},
+		columnCustomerRef:   {"Kundenreferenz"},
+	},
+	required: []column{
+		columnBookingDate,
+		columnValutaDate,
+		columnBeneficiary,
+		columnAmount,
+	},
+}
+
+// ingProfile reads the CSV export of ING.
+var ingProfile = &csvProfile{
+	name:        "ing",
+	comma:       ';',
+	dateLayouts: []string{"02.01.2006"},
+	amounts:     germanAmounts,
+	columns: map[column][]string{
+		columnBookingDate: {"Buchung"},
+		columnValutaDate:  {"Valuta"},
+		columnBeneficiary: {"Auftraggeber/Empfänger"},
+		columnBookingText: {"Buchungstext"},
+		columnPurpose:     {"Verwendungszweck"},
+		columnAmount:      {"Betrag"},
+		columnCurrency:    {"Währung"},
+	},
+	required: []column{
+		columnBookingDate,
+		columnValutaDate,
+		columnBeneficiary,
+		columnAmount,
+	},
+}
+
+// n26Profile reads the CSV export of N26. The export does not contain the
+// account under view.
+var n26Profile = &csvProfile{
+	name:        "n26",
+	comma:       ',',
+	dateLayouts: []string{"2006-01-02"},
+	amounts:     englishAmounts,
+	currency:    "EUR",
+	columns: map[column][]string{
+		columnBookingDate:   {"Booking Date", "Date"},
+		columnValutaDate:    {"Value Date"},
+		columnBeneficiary:   {"Partner Name", "Payee"},
+		columnAccountNumber: {"Partner Iban", "Account number"},
+		columnBookingText:   {"Type", "Transaction type"},
+		columnPurpose:       {"Payment Reference"},
+		columnAmount:        {"Amount (EUR)"},
+	},
+	required: []column{
+		columnBookingDate,
+		columnBeneficiary,
+		columnPurpose,
+		columnAmount,
+	},
+}
+
+// comdirectProfile reads the CSV export of comdirect. It ends with the old
+// balance of the account.
+var comdirectProfile = &csvProfile{
+	name:        "comdirect",
+	comma:       ';',
+	dateLayouts: []string{"02.01.2006"},
+	amounts:     germanAmounts,
+	currency:    "EUR",
+	columns: map[column][]string{
+		columnBookingDate: {"Buchungstag"},
+		columnValutaDate:  {"Wertstellung (Valuta)"},
+		columnBookingText: {"Vorgang"},
+		columnPurpose:     {"Buchungstext"},
+		columnAmount:      {"Umsatz in EUR"},
+	},
+	required: []column{
+		columnBookingDate,
+		columnValutaDate,
+		columnBookingText,
+		columnAmount,
+	},
+	footer: true,
+}
+
+// genericProfile reads CSV files that name their columns after any of the
+// common aliases. It is used when no bank specific profile matches.
+var genericProfile = &csvProfile{
+	name:        "csv",
+	comma:       ';',
+	dateLayouts: []string{"02.01.06", "02.01.2006", "2006-01-02"},
+	amounts:     germanAmounts,
+	columns: map[column][]string{
+		columnAccount:           {"Auftragskonto", "Account", "IBAN Auftragskonto"},
+		columnBookingDate:       {"Buchungstag", "Booking date", "Buchungsdatum"},
+		columnValutaDate:        {"Valutadatum", "Value date", "Wertstellung", "Valuta"},
+		columnBookingText:       {"Buchungstext", "Booking text", "Umsatzart", "Transaction type"},
+		columnPurpose:           {"Verwendungszweck", "Purpose", "Payment reference"},
+		columnCreditorID:        {"Glaeubiger ID", "Gläubiger-ID", "Creditor ID"},
+		columnMandateRef:        {"Mandatsreferenz", "Mandate reference"},
+		columnCustomerRef:       {"Kundenreferenz (End-to-End)", "Kundenreferenz", "Customer reference", "End-to-End reference"},
+		columnCollectorRef:      {"Sammlerreferenz", "Collector reference"},
+		columnOrigAmount:        {"Lastschrift Ursprungsbetrag", "Original amount"},
+		columnChargebackFee:     {"Auslagenersatz Ruecklastschrift", "Chargeback fee"},
+		columnBeneficiary:       {"Beguenstigter/Zahlungspflichtiger", "Beneficiary", "Payee", "Name Zahlungsbeteiligter"},
+		columnAccountNumber:     {"Kontonummer/IBAN", "IBAN", "Account number", "IBAN Zahlungsbeteiligter"},
+		columnBIC:               {"BIC (SWIFT-Code)", "BIC", "BIC Zahlungsbeteiligter"},
+		columnAmount:            {"Betrag", "Amount", "Betrag (EUR)", "Umsatz"},
+		columnCurrency:          {"Waehrung", "Währung", "Currency"},
+		columnAdditionalDetails: {"Info", "Additional details", "Status"},
+		columnIndicator:         {"Soll/Haben", "S/H", "Debit/Credit"},
+		columnDebit:             {"Soll", "Debit", "Ausgang"},
+		columnCredit:            {"Haben", "Credit", "Eingang"},
+	},
+	required: []column{
+		columnAccount,
+		columnBookingDate,
+		columnAmount,
+	},
+}
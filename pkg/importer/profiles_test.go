@@ -0,0 +1,88 @@
+package importer
+
+import "testing"
+
+func TestProfiles(t *testing.T) {
+	for _, tc := range []struct {
+		name             string
+		file             string
+		wantTransactions []string
+		wantRejected     []string
+	}{
+		{
+			name: "sparkasse",
+			file: "sparkasse.csv",
+			wantTransactions: []string{
+				"DE02120300000000202051 2024-03-01 2024-03-01 Hausverwaltung Schmidt -850.00 EUR",
+				"DE02120300000000202051 2024-03-04 2024-03-01 Stadtwerke Musterstadt -1234.56 EUR",
+				"DE02120300000000202051 2024-03-05 2024-03-05 Arbeitgeber AG 3100.00 EUR",
+			},
+			wantRejected: []string{"4 Buchungstag"},
+		},
+		{
+			name: "dkb",
+			file: "dkb.csv",
+			wantTransactions: []string{
+				"DE12120300001234567890 2024-03-28 2024-03-28 Supermarkt GmbH -42.17 EUR",
+				"DE12120300001234567890 2024-03-27 2024-03-27 Erika Mustermann 100.00 EUR",
+			},
+			wantRejected: []string{"8 Betrag (€)"},
+		},
+		{
+			name: "dkb-classic",
+			file: "dkb-classic.csv",
+			wantTransactions: []string{
+				"DE12120300001234567890 2024-03-29 2024-03-29 Versicherung AG -89.90 EUR",
+				"DE12120300001234567890 2024-03-28 2024-03-28 Finanzamt 1250.00 EUR",
+			},
+			wantRejected: []string{"10 "},
+		},
+		{
+			name: "ing",
+			file: "ing.csv",
+			wantTransactions: []string{
+				"DE10500105171234567890 2024-03-30 2024-03-30 Tankstelle -65.40 EUR",
+				"DE10500105171234567890 2024-03-29 2024-03-28 Arbeitgeber AG 2800.00 EUR",
+			},
+			wantRejected: []string{"16 Buchung"},
+		},
+		{
+			name: "n26",
+			file: "n26.csv",
+			wantTransactions: []string{
+				" 2024-03-01 2024-03-01 Coffee Shop -3.50 EUR",
+				" 2024-03-02 2024-03-02 Jane Doe 1234.00 EUR",
+			},
+			wantRejected: []string{"4 Amount (EUR)"},
+		},
+		{
+			name: "comdirect",
+			file: "comdirect.csv",
+			wantTransactions: []string{
+				" 2024-04-01 2024-04-01  50.00 EUR",
+				" 2024-03-31 2024-03-31  -1019.99 EUR",
+			},
+			wantRejected: []string{"6 Buchungstag"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s := parseFile(t, tc.file, tc.name)
+			checkStatement(t, s, tc.wantTransactions, tc.wantRejected)
+		})
+	}
+}
+
+func TestComdirectColumns(t *testing.T) {
+	s := parseFile(t, "comdirect.csv", "comdirect")
+	if len(s.Transactions) == 0 {
+		t.Fatal("no transactions")
+	}
+
+	got := s.Transactions[0]
+	if got.BookingText != "Übertrag / Überweisung" {
+		t.Errorf("booking text: got %q", got.BookingText)
+	}
+	if got.Purpose != "Auftraggeber: Max Mustermann Buchungstext: Taschengeld" {
+		t.Errorf("purpose: got %q", got.Purpose)
+	}
+}
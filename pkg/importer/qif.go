@@ -0,0 +1,160 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/ibihim/banking-csv-cli/pkg/transactions"
+)
+
+// qifImporter reads the bank, cash and credit card sections of Quicken
+// Interchange Format files. Investment sections, categories and classes are
+// skipped.
+type qifImporter struct{}
+
+var _ Importer = &qifImporter{}
+
+// qifDateLayouts are the date layouts used by common finance apps.
+var qifDateLayouts = []string{
+	"01/02/2006", "1/2/2006", "01/02'06", "1/2'06", "01/02/06", "1/2/06",
+	"02.01.2006", "2.1.2006", "02.01.06", "2006-01-02",
+}
+
+// qifTransactionTypes are the section types that contain transactions.
+var qifTransactionTypes = map[string]bool{
+	"bank": true, "cash": true, "ccard": true, "oth a": true, "oth l": true,
+}
+
+// Name returns the name of the importer.
+func (q *qifImporter) Name() string {
+	return "qif"
+}
+
+// Detect returns true if the file starts with a QIF section header.
+func (q *qifImporter) Detect(head []byte) bool {
+	head = bytes.TrimSpace(bytes.TrimPrefix(head, []byte("\xef\xbb\xbf")))
+	return bytes.HasPrefix(head, []byte("!Type:")) ||
+		bytes.HasPrefix(head, []byte("!Account")) ||
+		bytes.HasPrefix(head, []byte("!Option:"))
+}
+
+// Parse parses the transactions of a QIF file. The account of the
+// transactions is the name of the preceding !Account record, if any.
+func (q *qifImporter) Parse(r io.Reader) (*Statement, error) {
+	var (
+		s       = &Statement{}
+		scanner = bufio.NewScanner(r)
+		section string
+		account string
+		record  = map[byte]string{}
+		line    int
+	)
+	for scanner.Scan() {
+		line++
+		text := strings.TrimRight(strings.TrimPrefix(scanner.Text(), "\ufeff"), "\r")
+		if text == "" {
+			continue
+		}
+
+		if strings.HasPrefix(text, "!") {
+			header := strings.ToLower(strings.TrimSpace(text[1:]))
+			if strings.HasPrefix(header, "option:") || strings.HasPrefix(header, "clear:") {
+				continue
+			}
+			section = header
+			record = map[byte]string{}
+			continue
+		}
+
+		if text[0] != '^' {
+			// Split lines can occur multiple times, only the first one
+			// of each code is kept.
+			if _, ok := record[text[0]]; !ok {
+				record[text[0]] = strings.TrimSpace(text[1:])
+			}
+			continue
+		}
+
+		switch {
+		case section == "account":
+			account = record['N']
+		case qifTransactionTypes[strings.TrimPrefix(section, "type:")]:
+			t, err := parseQIFTransaction(record)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse record ending in line %d: %w", line, err)
+			}
+			t.Account = account
+			s.Transactions = append(s.Transactions, t)
+		}
+		record = map[byte]string{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func parseQIFTransaction(record map[byte]string) (*transactions.Transaction, error) {
+	date, err := parseQIFDate(record['D'])
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse date (%q): %w", record['D'], err)
+	}
+
+	amountStr := record['T']
+	if amountStr == "" {
+		amountStr = record['U']
+	}
+	// QIF does not state the currency.
+	amount, err := parseQIFAmount(amountStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse amount (%q): %w", amountStr, err)
+	}
+
+	return &transactions.Transaction{
+		BookingDate:       date,
+		ValutaDate:        date,
+		BookingText:       record['L'],
+		Purpose:           record['M'],
+		CustomerRef:       record['N'],
+		Beneficiary:       record['P'],
+		Amount:            amount,
+		AdditionalDetails: record['C'],
+	}, nil
+}
+
+func parseQIFDate(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+
+	var err error
+	for _, layout := range qifDateLayouts {
+		var t time.Time
+		t, err = time.Parse(layout, s)
+		if err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, err
+}
+
+// parseQIFAmount parses amounts like "-1,234.56" and "-1.234,56". The last
+// separator is the decimal separator, if it is followed by at most two
+// digits.
+func parseQIFAmount(s string) (transactions.Money, error) {
+	s = strings.TrimSpace(s)
+
+	last := strings.LastIndexAny(s, ".,")
+	if last >= 0 && len(s)-last-1 <= 2 {
+		integer := strings.NewReplacer(".", "", ",", "").Replace(s[:last])
+		s = integer + "." + s[last+1:]
+	} else {
+		s = strings.NewReplacer(".", "", ",", "").Replace(s)
+	}
+
+	return transactions.ParseMoney(s, "")
+}
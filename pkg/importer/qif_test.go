@@ -0,0 +1,73 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestQIF(t *testing.T) {
+	s := parseFile(t, "german.qif", "qif")
+
+	// The investment section is skipped, QIF does not state currencies.
+	checkStatement(t, s,
+		[]string{
+			"Girokonto 2024-03-01 2024-03-01 Hausverwaltung Schmidt -1234.56 ",
+			"Girokonto 2024-03-05 2024-03-05 Arbeitgeber AG 3100.00 ",
+			"Girokonto 2024-03-08 2024-03-08 Kiosk -12.50 ",
+		},
+		nil,
+	)
+
+	rent := s.Transactions[0]
+	if rent.Purpose != "Miete Maerz" || rent.BookingText != "Wohnen:Miete" {
+		t.Errorf("got purpose %q and booking text %q", rent.Purpose, rent.BookingText)
+	}
+	if salary := s.Transactions[1]; salary.CustomerRef != "1001" || salary.AdditionalDetails != "X" {
+		t.Errorf("got reference %q and status %q", salary.CustomerRef, salary.AdditionalDetails)
+	}
+}
+
+func TestParseQIFAmount(t *testing.T) {
+	for _, tc := range []struct {
+		amount string
+		want   string
+	}{
+		{"-1,234.56", "-1234.56"},
+		{"-1.234,56", "-1234.56"},
+		{"1234.5", "1234.50"},
+		{"1234,5", "1234.50"},
+		{"12", "12.00"},
+		// A separator followed by three digits groups thousands.
+		{"1.234", "1234.00"},
+		{"1,234", "1234.00"},
+		{"1,234,567", "1234567.00"},
+		{"1.234.567,89", "1234567.89"},
+		{" -0,99 ", "-0.99"},
+	} {
+		t.Run(tc.amount, func(t *testing.T) {
+			got, err := parseQIFAmount(tc.amount)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.String() != tc.want {
+				t.Errorf("got %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestQIFErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		file string
+	}{
+		{"broken date", "!Type:Bank\nD2024/31/12\nT1.00\n^\n"},
+		{"broken amount", "!Type:Bank\nD01.03.2024\nTzehn\n^\n"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := Parse(strings.NewReader(tc.file), "qif"); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
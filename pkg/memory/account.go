@@ -0,0 +1,100 @@
+package memory
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/ibihim/banking-csv-cli/pkg/transactions"
+)
+
+// AddAccount adds an account to the database and returns its id.
+func (d *Database) AddAccount(a *transactions.Account) (int64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.checkAccount(a); err != nil {
+		return 0, err
+	}
+
+	d.lastAccountID++
+	a.ID = d.lastAccountID
+	stored := *a
+	d.accounts = append(d.accounts, &stored)
+
+	return a.ID, nil
+}
+
+// UpdateAccount replaces the account of the same id.
+func (d *Database) UpdateAccount(a *transactions.Account) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.checkAccount(a); err != nil {
+		return err
+	}
+
+	for i, known := range d.accounts {
+		if known.ID == a.ID {
+			stored := *a
+			d.accounts[i] = &stored
+			return nil
+		}
+	}
+
+	return transactions.ErrNoAccount
+}
+
+// checkAccount returns an error if another account has the same IBAN or
+// nickname, like the unique constraints of sql.Database.
+func (d *Database) checkAccount(a *transactions.Account) error {
+	for _, known := range d.accounts {
+		if known.ID == a.ID {
+			continue
+		}
+		if known.IBAN == a.IBAN {
+			return fmt.Errorf("account %s already exists", a.IBAN)
+		}
+		if a.Nickname != "" && known.Nickname == a.Nickname {
+			return fmt.Errorf("account nickname %q already exists", a.Nickname)
+		}
+	}
+
+	return nil
+}
+
+// GetAccounts returns all accounts, ordered by nickname and IBAN.
+func (d *Database) GetAccounts() ([]*transactions.Account, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	accounts := make([]*transactions.Account, 0, len(d.accounts))
+	for _, a := range d.accounts {
+		c := *a
+		accounts = append(accounts, &c)
+	}
+	sort.Slice(accounts, func(i, j int) bool {
+		if accounts[i].Name() != accounts[j].Name() {
+			return accounts[i].Name() < accounts[j].Name()
+		}
+		return accounts[i].IBAN < accounts[j].IBAN
+	})
+
+	return accounts, nil
+}
+
+// GetAccount returns the account with the given IBAN or nickname. It returns
+// transactions.ErrNoAccount if there is none.
+func (d *Database) GetAccount(ref string) (*transactions.Account, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	iban := transactions.NormalizeIBAN(ref)
+	for _, a := range d.accounts {
+		if a.IBAN == iban || a.Nickname == ref {
+			c := *a
+			return &c, nil
+		}
+	}
+
+	return nil, transactions.ErrNoAccount
+}
@@ -0,0 +1,163 @@
+package memory
+
+import (
+	"fmt"
+
+	"github.com/ibihim/banking-csv-cli/pkg/transactions"
+)
+
+// GetCategories returns all categories, ordered like a tree, see
+// transactions.SetCategoryPaths.
+func (d *Database) GetCategories() ([]*transactions.Category, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.getCategories(), nil
+}
+
+// getCategories returns copies of the categories with their paths set.
+func (d *Database) getCategories() []*transactions.Category {
+	categories := make([]*transactions.Category, 0, len(d.categories))
+	for _, c := range d.categories {
+		copied := *c
+		for _, t := range d.transactions {
+			if d.transactionCategories[t.Fingerprint] == c.ID {
+				copied.Transactions++
+			}
+		}
+		categories = append(categories, &copied)
+	}
+	transactions.SetCategoryPaths(categories)
+
+	return categories
+}
+
+// AddCategory adds the category of a path and its missing parents. It
+// returns the number of added categories, 0 if the category exists.
+func (d *Database) AddCategory(path string) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	names, err := transactions.ParseCategoryPath(path)
+	if err != nil {
+		return 0, err
+	}
+
+	added := 0
+	var parentID int64
+	for _, name := range names {
+		var category *transactions.Category
+		for _, c := range d.categories {
+			if c.ParentID == parentID && c.Name == name {
+				category = c
+				break
+			}
+		}
+		if category == nil {
+			d.lastCategoryID++
+			category = &transactions.Category{ID: d.lastCategoryID, ParentID: parentID, Name: name}
+			d.categories = append(d.categories, category)
+			added++
+		}
+		parentID = category.ID
+	}
+
+	return added, nil
+}
+
+// CategorizeTransactions sets the category of the transactions with the
+// given ids. Transactions that have another category keep it, unless
+// replace is set. It returns the number of transactions whose category
+// changed.
+func (d *Database) CategorizeTransactions(path string, ids []int64, replace bool) (int64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var category *transactions.Category
+	for _, c := range d.getCategories() {
+		if c.Path == path {
+			category = c
+		}
+	}
+	if category == nil {
+		return 0, fmt.Errorf("%w: %s", transactions.ErrNoCategory, path)
+	}
+	if d.transactionCategories == nil {
+		d.transactionCategories = map[string]int64{}
+	}
+
+	var categorized int64
+	for _, t := range d.findTransactions(ids) {
+		known, ok := d.transactionCategories[t.Fingerprint]
+		if known == category.ID || (ok && !replace) {
+			continue
+		}
+		d.transactionCategories[t.Fingerprint] = category.ID
+		categorized++
+	}
+
+	return categorized, nil
+}
+
+// UncategorizeTransactions removes the category of the transactions with
+// the given ids. It returns the number of transactions that had one.
+func (d *Database) UncategorizeTransactions(ids []int64) (int64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var uncategorized int64
+	for _, t := range d.findTransactions(ids) {
+		if _, ok := d.transactionCategories[t.Fingerprint]; ok {
+			delete(d.transactionCategories, t.Fingerprint)
+			uncategorized++
+		}
+	}
+
+	return uncategorized, nil
+}
+
+// DeleteCategory deletes a category and its subcategories. Their
+// transactions become uncategorized.
+func (d *Database) DeleteCategory(path string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	deleted := map[int64]bool{}
+	for _, id := range transactions.Subcategories(d.getCategories(), path) {
+		deleted[id] = true
+	}
+	if len(deleted) == 0 {
+		return fmt.Errorf("%w: %s", transactions.ErrNoCategory, path)
+	}
+
+	categories := d.categories[:0:0]
+	for _, c := range d.categories {
+		if !deleted[c.ID] {
+			categories = append(categories, c)
+		}
+	}
+	d.categories = categories
+	for fingerprint, id := range d.transactionCategories {
+		if deleted[id] {
+			delete(d.transactionCategories, fingerprint)
+		}
+	}
+
+	return nil
+}
+
+// categoryOf returns the path of the category of a fingerprint, or an
+// empty string.
+func (d *Database) categoryOf(categories []*transactions.Category, fingerprint string) string {
+	id, ok := d.transactionCategories[fingerprint]
+	if !ok {
+		return ""
+	}
+	for _, c := range categories {
+		if c.ID == id {
+			return c.Path
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,513 @@
+package memory
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ibihim/banking-csv-cli/pkg/transactions"
+)
+
+// Database stores transactions in memory. It behaves like sql.Database and
+// is meant for tests and demos, its content is lost when the process ends.
+type Database struct {
+	mu sync.Mutex
+
+	transactions []*transactions.Transaction
+	balances     []*transactions.Balance
+	imports      []*transactions.Import
+	accounts     []*transactions.Account
+	labels       []*transactions.Label
+	// transactionLabels are the fingerprints of the transactions of each
+	// label id.
+	transactionLabels map[int64]map[string]bool
+	categories        []*transactions.Category
+	// transactionCategories are the category ids of the fingerprints of
+	// the transactions.
+	transactionCategories map[string]int64
+
+	lastTransactionID int64
+	lastBalanceID     int64
+	lastImportID      int64
+	lastAccountID     int64
+	lastLabelID       int64
+	lastCategoryID    int64
+}
+
+// NewDatabase creates a new, empty database.
+func NewDatabase() *Database {
+	return &Database{}
+}
+
+// Close does nothing, it exists to match sql.Database.
+func (d *Database) Close() error {
+	return nil
+}
+
+// AddTransaction adds a transaction to the database.
+func (d *Database) AddTransaction(t *transactions.Transaction) (int64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if t.Fingerprint == "" {
+		t.Fingerprint = transactions.Fingerprint(t, 0)
+	}
+	if d.hasFingerprint(t.Fingerprint) {
+		return 0, fmt.Errorf("%w (%+v)", transactions.ErrDuplicate, t)
+	}
+
+	d.addTransaction(t)
+	return t.ID, nil
+}
+
+// addTransaction stores a copy of the transaction and sets its id.
+func (d *Database) addTransaction(t *transactions.Transaction) {
+	d.lastTransactionID++
+	t.ID = d.lastTransactionID
+
+	stored := *t
+	d.transactions = append(d.transactions, &stored)
+}
+
+// GetTransactions retrieves all transactions from the database.
+func (d *Database) GetTransactions() ([]*transactions.Transaction, error) {
+	page, err := d.QueryTransactions(&transactions.Query{})
+	if err != nil {
+		return nil, err
+	}
+
+	return page.Transactions, nil
+}
+
+// QueryTransactions returns the transactions selected by the query.
+func (d *Database) QueryTransactions(q *transactions.Query) (*transactions.Page, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	order := q.Order()
+	if _, err := transactions.ParseSortOrder(string(order)); err != nil {
+		return nil, err
+	}
+
+	var cursorKey any
+	var cursorID int64
+	if q.Cursor != "" {
+		var err error
+		if cursorKey, cursorID, err = transactions.DecodeCursor(q); err != nil {
+			return nil, err
+		}
+	}
+
+	var labeled map[string]bool
+	if q.Label != "" {
+		labeled = map[string]bool{}
+		if l := d.findLabel(q.Label); l != nil {
+			labeled = d.transactionLabels[l.ID]
+		}
+	}
+
+	categories := d.getCategories()
+	var inCategory map[int64]bool
+	if q.Category != "" {
+		inCategory = map[int64]bool{}
+		for _, id := range transactions.Subcategories(categories, q.Category) {
+			inCategory[id] = true
+		}
+	}
+
+	var ts []*transactions.Transaction
+	for _, t := range d.transactions {
+		if !matches(q, t) || (labeled != nil && !labeled[t.Fingerprint]) {
+			continue
+		}
+		if inCategory != nil {
+			id, ok := d.transactionCategories[t.Fingerprint]
+			if q.Category == transactions.Uncategorized && ok {
+				continue
+			}
+			if q.Category != transactions.Uncategorized && !inCategory[id] {
+				continue
+			}
+		}
+		if q.Cursor != "" && compare(order, order.SortKey(t), t.ID, cursorKey, cursorID) <= 0 {
+			continue
+		}
+		ts = append(ts, t)
+	}
+
+	sort.Slice(ts, func(i, j int) bool {
+		return compare(order, order.SortKey(ts[i]), ts[i].ID, order.SortKey(ts[j]), ts[j].ID) < 0
+	})
+
+	if q.Offset > 0 {
+		if q.Offset >= len(ts) {
+			ts = nil
+		} else {
+			ts = ts[q.Offset:]
+		}
+	}
+	page := &transactions.Page{}
+	if q.Limit > 0 && len(ts) >= q.Limit {
+		ts = ts[:q.Limit]
+		page.NextCursor = transactions.EncodeCursor(q, ts[len(ts)-1])
+	}
+
+	for _, t := range ts {
+		c := *t
+		c.Labels = d.labelsOf(t.Fingerprint)
+		c.Category = d.categoryOf(categories, t.Fingerprint)
+		page.Transactions = append(page.Transactions, &c)
+	}
+
+	return page, nil
+}
+
+// matches returns true if the transaction is selected by the query.
+func matches(q *transactions.Query, t *transactions.Transaction) bool {
+	date := t.ValutaDate.Format("2006-01-02")
+	switch {
+	case !q.From.IsZero() && date < q.From.Format("2006-01-02"):
+		return false
+	case !q.To.IsZero() && date > q.To.Format("2006-01-02"):
+		return false
+	case q.Account != "" && t.Account != q.Account:
+		return false
+	case q.Beneficiary != "" && !containsFold(t.Beneficiary, q.Beneficiary):
+		return false
+	case q.MinAmount != nil && (!sameCurrency(t.Amount, *q.MinAmount) || t.Amount.Minor < q.MinAmount.Minor):
+		return false
+	case q.MaxAmount != nil && (!sameCurrency(t.Amount, *q.MaxAmount) || t.Amount.Minor > q.MaxAmount.Minor):
+		return false
+	}
+
+	if q.Text != "" {
+		for _, s := range []string{t.Beneficiary, t.Purpose, t.BookingText, t.AdditionalDetails} {
+			if containsFold(s, q.Text) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return true
+}
+
+// sameCurrency returns true if the amount is in the currency of the bound of
+// a query, or if the bound has none.
+func sameCurrency(amount, bound transactions.Money) bool {
+	return bound.Currency == "" || amount.Currency == bound.Currency
+}
+
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+// compare compares two transactions by their sort keys and ids, in the
+// direction of the order.
+func compare(order transactions.SortOrder, keyA any, idA int64, keyB any, idB int64) int {
+	c := 0
+	switch a := keyA.(type) {
+	case int64:
+		b, _ := keyB.(int64)
+		c = compareInts(a, b)
+	case string:
+		b, _ := keyB.(string)
+		c = strings.Compare(a, b)
+	}
+	if c == 0 {
+		c = compareInts(idA, idB)
+	}
+
+	if order.Descending() {
+		return -c
+	}
+	return c
+}
+
+func compareInts(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// HasTransaction checks if a transaction already exists in the database,
+// by its fingerprint.
+func (d *Database) HasTransaction(t *transactions.Transaction) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	fingerprint := t.Fingerprint
+	if fingerprint == "" {
+		fingerprint = transactions.Fingerprint(t, 0)
+	}
+
+	return d.hasFingerprint(fingerprint), nil
+}
+
+func (d *Database) hasFingerprint(fingerprint string) bool {
+	for _, t := range d.transactions {
+		if t.Fingerprint == fingerprint {
+			return true
+		}
+	}
+
+	return false
+}
+
+// AddBalance adds a balance to the database. A balance of the same account,
+// type and date is replaced.
+func (d *Database) AddBalance(b *transactions.Balance) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.addBalance(b)
+	return nil
+}
+
+// GetBalances returns the balances of an account, ordered by date.
+func (d *Database) GetBalances(account string) ([]*transactions.Balance, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var balances []*transactions.Balance
+	for _, b := range d.balances {
+		if b.Account == account {
+			c := *b
+			balances = append(balances, &c)
+		}
+	}
+	sort.SliceStable(balances, func(i, j int) bool {
+		return balances[i].Date.Before(balances[j].Date)
+	})
+
+	return balances, nil
+}
+
+func (d *Database) addBalance(b *transactions.Balance) {
+	for i, known := range d.balances {
+		if known.Account == b.Account && known.Type == b.Type && known.Date.Equal(b.Date) {
+			b.ID = known.ID
+			stored := *b
+			d.balances[i] = &stored
+			return
+		}
+	}
+
+	d.lastBalanceID++
+	b.ID = d.lastBalanceID
+	stored := *b
+	d.balances = append(d.balances, &stored)
+}
+
+// Import adds the transactions and balances of the batches in order. If
+// anything fails, nothing is imported. See sql.Database.Import.
+func (d *Database) Import(batches []*transactions.ImportBatch, policy transactions.DuplicatePolicy) error {
+	return d.runImport(batches, policy, false)
+}
+
+// PreviewImport does what Import does, but writes nothing. See
+// sql.Database.PreviewImport.
+func (d *Database) PreviewImport(batches []*transactions.ImportBatch, policy transactions.DuplicatePolicy) error {
+	return d.runImport(batches, policy, true)
+}
+
+func (d *Database) runImport(batches []*transactions.ImportBatch, policy transactions.DuplicatePolicy, dryRun bool) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// The state is restored after a failure. Stored values are replaced,
+	// never changed, so copying the slices is enough.
+	savedTransactions := append([]*transactions.Transaction(nil), d.transactions...)
+	savedBalances := append([]*transactions.Balance(nil), d.balances...)
+	savedImports := append([]*transactions.Import(nil), d.imports...)
+	lastTransactionID, lastBalanceID, lastImportID := d.lastTransactionID, d.lastBalanceID, d.lastImportID
+	restore := func() {
+		d.transactions = savedTransactions
+		d.balances = savedBalances
+		d.imports = savedImports
+		d.lastTransactionID = lastTransactionID
+		d.lastBalanceID = lastBalanceID
+		d.lastImportID = lastImportID
+	}
+
+	// previewed are the fingerprints of the new transactions of a preview.
+	var previewed map[string]bool
+	if dryRun {
+		previewed = map[string]bool{}
+	}
+	for _, batch := range batches {
+		if err := d.importBatch(batch, policy, previewed); err != nil {
+			restore()
+			return fmt.Errorf("failed to import %s: %w", batch.Import.Filename, err)
+		}
+	}
+
+	return nil
+}
+
+// importBatch imports a batch, or previews it if previewed is not nil.
+func (d *Database) importBatch(batch *transactions.ImportBatch, policy transactions.DuplicatePolicy, previewed map[string]bool) error {
+	dryRun := previewed != nil
+	imp := batch.Import
+	if imp.ImportedAt.IsZero() {
+		imp.ImportedAt = time.Now()
+	}
+	if !dryRun {
+		d.lastImportID++
+		imp.ID = d.lastImportID
+	}
+	known := func(fingerprint string) bool {
+		return previewed[fingerprint] || d.hasFingerprint(fingerprint)
+	}
+
+	result := &transactions.ImportResult{}
+	occurrences := map[string]int{}
+	for _, t := range batch.Transactions {
+		key := transactions.FingerprintKey(t)
+		occurrence := occurrences[key]
+		occurrences[key]++
+		t.Fingerprint = transactions.Fingerprint(t, occurrence)
+		t.ImportID = imp.ID
+
+		row := &transactions.ImportRow{Status: transactions.RowNew, Transaction: t}
+		result.Rows = append(result.Rows, row)
+		if known(t.Fingerprint) {
+			row.Status = transactions.RowDuplicate
+			result.Duplicates = append(result.Duplicates, t)
+
+			switch policy {
+			case transactions.DuplicateFail:
+				if dryRun {
+					result.Skipped++
+					continue
+				}
+				return fmt.Errorf("%w (%+v)", transactions.ErrDuplicate, t)
+			case transactions.DuplicateInsert:
+				// Use the next occurrence that is not known yet.
+				for known(t.Fingerprint) {
+					occurrence++
+					t.Fingerprint = transactions.Fingerprint(t, occurrence)
+				}
+				if occurrences[key] <= occurrence {
+					occurrences[key] = occurrence + 1
+				}
+			default:
+				result.Skipped++
+				continue
+			}
+		} else if dryRun {
+			if conflict := d.findConflict(t); conflict != nil {
+				row.Status = transactions.RowConflict
+				row.ConflictID = conflict.ID
+			}
+		}
+
+		result.Inserted++
+		if dryRun {
+			previewed[t.Fingerprint] = true
+			continue
+		}
+		d.addTransaction(t)
+	}
+
+	imp.Inserted = result.Inserted
+	imp.Skipped = result.Skipped
+	imp.Duplicates = len(result.Duplicates)
+	batch.Result = result
+	if dryRun {
+		return nil
+	}
+
+	for _, b := range batch.Balances {
+		d.addBalance(b)
+	}
+
+	stored := *imp
+	d.imports = append(d.imports, &stored)
+
+	return nil
+}
+
+// findConflict returns a known transaction that looks like a new one.
+func (d *Database) findConflict(t *transactions.Transaction) *transactions.Transaction {
+	for _, known := range d.transactions {
+		if known.Account == t.Account &&
+			known.BookingDate.Equal(t.BookingDate) &&
+			known.Amount == t.Amount &&
+			known.Fingerprint != t.Fingerprint {
+			return known
+		}
+	}
+
+	return nil
+}
+
+// GetImports returns all imports, oldest first.
+func (d *Database) GetImports() ([]*transactions.Import, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	imps := make([]*transactions.Import, 0, len(d.imports))
+	for _, imp := range d.imports {
+		c := *imp
+		imps = append(imps, &c)
+	}
+
+	return imps, nil
+}
+
+// GetImportByChecksum returns the latest import of a file with the given
+// checksum, or nil if the file was not imported yet.
+func (d *Database) GetImportByChecksum(checksum string) (*transactions.Import, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for i := len(d.imports) - 1; i >= 0; i-- {
+		if d.imports[i].Checksum == checksum {
+			c := *d.imports[i]
+			return &c, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// RevertImport deletes the transactions added by an import and the record
+// of the import. It returns the number of deleted transactions.
+func (d *Database) RevertImport(id int64) (int64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	found := false
+	imps := d.imports[:0:0]
+	for _, imp := range d.imports {
+		if imp.ID == id {
+			found = true
+			continue
+		}
+		imps = append(imps, imp)
+	}
+	if !found {
+		return 0, fmt.Errorf("import %d does not exist", id)
+	}
+	d.imports = imps
+
+	var deleted int64
+	ts := d.transactions[:0:0]
+	for _, t := range d.transactions {
+		if t.ImportID == id {
+			deleted++
+			continue
+		}
+		ts = append(ts, t)
+	}
+	d.transactions = ts
+
+	return deleted, nil
+}
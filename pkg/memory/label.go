@@ -0,0 +1,168 @@
+package memory
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/ibihim/banking-csv-cli/pkg/transactions"
+)
+
+// GetLabels returns all labels, ordered by name.
+func (d *Database) GetLabels() ([]*transactions.Label, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	labels := make([]*transactions.Label, 0, len(d.labels))
+	for _, l := range d.labels {
+		c := *l
+		for _, t := range d.transactions {
+			if d.transactionLabels[l.ID][t.Fingerprint] {
+				c.Transactions++
+			}
+		}
+		labels = append(labels, &c)
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		return labels[i].Name < labels[j].Name
+	})
+
+	return labels, nil
+}
+
+// LabelTransactions adds the label to the transactions with the given ids.
+// The label is created if it does not exist, unless none of the ids is
+// known. It returns the number of transactions that did not have the label
+// yet.
+func (d *Database) LabelTransactions(name string, ids []int64) (int64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	ts := d.findTransactions(ids)
+	l := d.findLabel(name)
+	if l == nil && len(ts) == 0 {
+		return 0, nil
+	}
+	if l == nil {
+		d.lastLabelID++
+		l = &transactions.Label{ID: d.lastLabelID, Name: name}
+		d.labels = append(d.labels, l)
+	}
+	if d.transactionLabels == nil {
+		d.transactionLabels = map[int64]map[string]bool{}
+	}
+	if d.transactionLabels[l.ID] == nil {
+		d.transactionLabels[l.ID] = map[string]bool{}
+	}
+
+	var labeled int64
+	for _, t := range ts {
+		if !d.transactionLabels[l.ID][t.Fingerprint] {
+			d.transactionLabels[l.ID][t.Fingerprint] = true
+			labeled++
+		}
+	}
+
+	return labeled, nil
+}
+
+// UnlabelTransactions removes the label from the transactions with the
+// given ids. It returns the number of transactions that had the label.
+func (d *Database) UnlabelTransactions(name string, ids []int64) (int64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	l := d.findLabel(name)
+	if l == nil {
+		return 0, fmt.Errorf("%w: %s", transactions.ErrNoLabel, name)
+	}
+
+	var unlabeled int64
+	for _, t := range d.findTransactions(ids) {
+		if d.transactionLabels[l.ID][t.Fingerprint] {
+			delete(d.transactionLabels[l.ID], t.Fingerprint)
+			unlabeled++
+		}
+	}
+
+	return unlabeled, nil
+}
+
+// RenameLabel renames a label. The transactions keep it.
+func (d *Database) RenameLabel(name, newName string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	l := d.findLabel(name)
+	if l == nil {
+		return fmt.Errorf("%w: %s", transactions.ErrNoLabel, name)
+	}
+	if name != newName && d.findLabel(newName) != nil {
+		return fmt.Errorf("label %q already exists", newName)
+	}
+	l.Name = newName
+
+	return nil
+}
+
+// DeleteLabel removes a label from all transactions and deletes it.
+func (d *Database) DeleteLabel(name string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	labels := d.labels[:0:0]
+	found := false
+	for _, l := range d.labels {
+		if l.Name == name {
+			found = true
+			delete(d.transactionLabels, l.ID)
+			continue
+		}
+		labels = append(labels, l)
+	}
+	if !found {
+		return fmt.Errorf("%w: %s", transactions.ErrNoLabel, name)
+	}
+	d.labels = labels
+
+	return nil
+}
+
+func (d *Database) findLabel(name string) *transactions.Label {
+	for _, l := range d.labels {
+		if l.Name == name {
+			return l
+		}
+	}
+
+	return nil
+}
+
+// findTransactions returns the stored transactions with the given ids.
+func (d *Database) findTransactions(ids []int64) []*transactions.Transaction {
+	wanted := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		wanted[id] = true
+	}
+
+	var ts []*transactions.Transaction
+	for _, t := range d.transactions {
+		if wanted[t.ID] {
+			ts = append(ts, t)
+		}
+	}
+
+	return ts
+}
+
+// labelsOf returns the sorted names of the labels of a fingerprint.
+func (d *Database) labelsOf(fingerprint string) []string {
+	var names []string
+	for _, l := range d.labels {
+		if d.transactionLabels[l.ID][fingerprint] {
+			names = append(names, l.Name)
+		}
+	}
+	sort.Strings(names)
+
+	return names
+}
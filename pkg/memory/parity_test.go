@@ -0,0 +1,403 @@
+package memory_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ibihim/banking-csv-cli/pkg/memory"
+	"github.com/ibihim/banking-csv-cli/pkg/sql"
+	"github.com/ibihim/banking-csv-cli/pkg/transactions"
+)
+
+// datastore is what the scenarios use of memory.Database and sql.Database.
+type datastore interface {
+	AddTransaction(transaction *transactions.Transaction) (int64, error)
+	QueryTransactions(query *transactions.Query) (*transactions.Page, error)
+	AddBalance(balance *transactions.Balance) error
+	GetBalances(account string) ([]*transactions.Balance, error)
+	Import(batches []*transactions.ImportBatch, policy transactions.DuplicatePolicy) error
+	PreviewImport(batches []*transactions.ImportBatch, policy transactions.DuplicatePolicy) error
+	GetImports() ([]*transactions.Import, error)
+	RevertImport(id int64) (int64, error)
+	AddAccount(account *transactions.Account) (int64, error)
+	GetAccount(ref string) (*transactions.Account, error)
+	GetLabels() ([]*transactions.Label, error)
+	LabelTransactions(name string, ids []int64) (int64, error)
+	UnlabelTransactions(name string, ids []int64) (int64, error)
+	RenameLabel(name, newName string) error
+	DeleteLabel(name string) error
+	GetCategories() ([]*transactions.Category, error)
+	AddCategory(path string) (int, error)
+	CategorizeTransactions(path string, ids []int64, replace bool) (int64, error)
+	UncategorizeTransactions(ids []int64) (int64, error)
+	DeleteCategory(path string) error
+}
+
+// recorder writes down what a scenario observes, to compare the
+// datastores.
+type recorder struct {
+	t     *testing.T
+	lines []string
+}
+
+func (r *recorder) printf(format string, args ...any) {
+	r.lines = append(r.lines, fmt.Sprintf(format, args...))
+}
+
+// check records the outcome of a call that returns a number.
+func (r *recorder) check(what string, n any, err error) {
+	if err != nil {
+		r.printf("%s: error", what)
+		return
+	}
+	r.printf("%s: %v", what, n)
+}
+
+func (r *recorder) query(ds datastore, q *transactions.Query) *transactions.Page {
+	page, err := ds.QueryTransactions(q)
+	if err != nil {
+		r.printf("query %+v: error", *q)
+		return &transactions.Page{}
+	}
+	r.printf("query %+v:", *q)
+	for _, t := range page.Transactions {
+		r.printf("  %d %s %s %s %s %q [%s] %s", t.ID, t.Account, t.ValutaDate.Format("2006-01-02"),
+			t.Amount, t.Amount.Currency, t.Beneficiary, strings.Join(t.Labels, ", "), t.Category)
+	}
+	if page.NextCursor != "" {
+		r.printf("  more")
+	}
+
+	return page
+}
+
+func (r *recorder) results(batches []*transactions.ImportBatch) {
+	for _, b := range batches {
+		r.printf("import %d %s: inserted %d, skipped %d, duplicates %d",
+			b.Import.ID, b.Import.Filename, b.Result.Inserted, b.Result.Skipped, len(b.Result.Duplicates))
+		for _, row := range b.Result.Rows {
+			r.printf("  %d %s %d", row.Transaction.ID, row.Status, row.ConflictID)
+		}
+	}
+}
+
+func (r *recorder) imports(ds datastore) {
+	imps, err := ds.GetImports()
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	for _, imp := range imps {
+		r.printf("import %d %s %s: inserted %d, skipped %d, duplicates %d, rejected %d",
+			imp.ID, imp.Filename, imp.Format, imp.Inserted, imp.Skipped, imp.Duplicates, imp.Rejected)
+	}
+}
+
+func (r *recorder) labels(ds datastore) {
+	labels, err := ds.GetLabels()
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	for _, l := range labels {
+		r.printf("label %d %s %d", l.ID, l.Name, l.Transactions)
+	}
+}
+
+func (r *recorder) categories(ds datastore) {
+	categories, err := ds.GetCategories()
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	for _, c := range categories {
+		r.printf("category %d %d %s %d", c.ID, c.ParentID, c.Path, c.Transactions)
+	}
+}
+
+var (
+	march1 = time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	march4 = time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)
+	march5 = time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)
+)
+
+// newTransaction returns a transaction of the giro account.
+func newTransaction(date time.Time, beneficiary, purpose string, amount int64) *transactions.Transaction {
+	return &transactions.Transaction{
+		Account:     "DE02120300000000202051",
+		BookingDate: date,
+		ValutaDate:  date,
+		Beneficiary: beneficiary,
+		Purpose:     purpose,
+		Amount:      transactions.Money{Minor: amount, Currency: "EUR"},
+	}
+}
+
+func rent() *transactions.Transaction {
+	return newTransaction(march1, "Hausverwaltung Schmidt", "Miete Maerz", -85000)
+}
+
+func coffee() *transactions.Transaction {
+	return newTransaction(march4, "Café Zentral", "Kaffee", -350)
+}
+
+func salary() *transactions.Transaction {
+	return newTransaction(march5, "Arbeitgeber AG", "Lohn Maerz", 310000)
+}
+
+func batch(filename string, ts ...*transactions.Transaction) *transactions.ImportBatch {
+	return &transactions.ImportBatch{
+		Import:       &transactions.Import{Filename: filename, Checksum: filename, Format: "csv"},
+		Transactions: ts,
+	}
+}
+
+// scenarios are run against each datastore. They start with an empty one.
+var scenarios = []struct {
+	name string
+	run  func(r *recorder, ds datastore)
+}{
+	{
+		name: "import",
+		run: func(r *recorder, ds datastore) {
+			// Two coffees on a day are two transactions, but importing the
+			// file again adds neither.
+			batches := []*transactions.ImportBatch{
+				batch("march.csv", rent(), coffee(), coffee()),
+				batch("march-again.csv", coffee(), coffee(), salary()),
+			}
+			if err := ds.Import(batches, transactions.DuplicateSkip); err != nil {
+				r.t.Fatal(err)
+			}
+			r.results(batches)
+
+			// The preview knows the new transactions of the batches before.
+			changed := coffee()
+			changed.Purpose = "Cappuccino"
+			preview := []*transactions.ImportBatch{
+				batch("preview.csv", coffee(), coffee(), coffee(), changed),
+				batch("preview-again.csv", coffee(), coffee(), coffee()),
+			}
+			if err := ds.PreviewImport(preview, transactions.DuplicateFail); err != nil {
+				r.t.Fatal(err)
+			}
+			r.results(preview)
+
+			err := ds.Import([]*transactions.ImportBatch{batch("fail.csv", salary(), rent())}, transactions.DuplicateFail)
+			r.printf("fail: %v", errors.Is(err, transactions.ErrDuplicate))
+
+			inserted := []*transactions.ImportBatch{batch("insert.csv", rent(), rent())}
+			if err := ds.Import(inserted, transactions.DuplicateInsert); err != nil {
+				r.t.Fatal(err)
+			}
+			r.results(inserted)
+
+			r.imports(ds)
+			r.query(ds, &transactions.Query{})
+		},
+	},
+	{
+		name: "query",
+		run: func(r *recorder, ds datastore) {
+			cash := coffee()
+			cash.Account = "cash"
+			for _, t := range []*transactions.Transaction{rent(), coffee(), salary(), cash} {
+				if _, err := ds.AddTransaction(t); err != nil {
+					r.t.Fatal(err)
+				}
+			}
+
+			min := transactions.Money{Minor: -1000, Currency: "EUR"}
+			max := transactions.Money{Minor: 0, Currency: "EUR"}
+			for _, q := range []*transactions.Query{
+				{From: march4},
+				{To: march4},
+				{Account: "cash"},
+				{Beneficiary: "café"},
+				{Beneficiary: "%"},
+				{Text: "MAERZ"},
+				{MinAmount: &min},
+				{MinAmount: &min, MaxAmount: &max},
+				{Sort: transactions.SortDateDesc},
+				{Sort: transactions.SortAmount},
+				{Sort: transactions.SortAmountDesc},
+				{Sort: transactions.SortBeneficiary},
+				{Sort: transactions.SortBeneficiaryDesc},
+				{Limit: 2, Offset: 1},
+				{Offset: 3},
+				{Sort: "unknown"},
+			} {
+				r.query(ds, q)
+			}
+
+			q := &transactions.Query{Sort: transactions.SortAmountDesc, Limit: 3}
+			for {
+				page := r.query(ds, q)
+				if page.NextCursor == "" {
+					break
+				}
+				q.Cursor = page.NextCursor
+			}
+		},
+	},
+	{
+		name: "labels and categories",
+		run: func(r *recorder, ds datastore) {
+			if err := ds.Import([]*transactions.ImportBatch{batch("march.csv", rent(), coffee(), salary())}, transactions.DuplicateSkip); err != nil {
+				r.t.Fatal(err)
+			}
+
+			n, err := ds.LabelTransactions("Steuer", []int64{1, 3, 3, 42})
+			r.check("label", n, err)
+			n, err = ds.LabelTransactions("Steuer", []int64{1, 2})
+			r.check("label again", n, err)
+			n, err = ds.UnlabelTransactions("Steuer", []int64{2, 3})
+			r.check("unlabel", n, err)
+			n, err = ds.UnlabelTransactions("Unbekannt", []int64{1})
+			r.check("unlabel unknown", n, err)
+			n, err = ds.LabelTransactions("Urlaub", []int64{2})
+			r.check("label", n, err)
+			r.check("rename", 0, ds.RenameLabel("Urlaub", "Urlaub 2024"))
+			r.check("rename to existing", 0, ds.RenameLabel("Urlaub 2024", "Steuer"))
+			r.check("rename unknown", 0, ds.RenameLabel("Unbekannt", "Neu"))
+			r.labels(ds)
+
+			added, err := ds.AddCategory("Wohnen/Miete")
+			r.check("add category", added, err)
+			added, err = ds.AddCategory("Wohnen/Nebenkosten/Strom")
+			r.check("add category", added, err)
+			added, err = ds.AddCategory("Wohnen")
+			r.check("add known category", added, err)
+			n, err = ds.CategorizeTransactions("Wohnen/Miete", []int64{1, 2}, false)
+			r.check("categorize", n, err)
+			n, err = ds.CategorizeTransactions("Wohnen/Nebenkosten/Strom", []int64{1, 2}, false)
+			r.check("categorize without replace", n, err)
+			n, err = ds.CategorizeTransactions("Wohnen/Nebenkosten/Strom", []int64{2}, true)
+			r.check("categorize with replace", n, err)
+			n, err = ds.CategorizeTransactions("Unbekannt", []int64{3}, false)
+			r.check("categorize unknown", n, err)
+			r.categories(ds)
+
+			r.query(ds, &transactions.Query{Label: "Steuer"})
+			r.query(ds, &transactions.Query{Label: "Unbekannt"})
+			r.query(ds, &transactions.Query{Category: "Wohnen"})
+			r.query(ds, &transactions.Query{Category: "Wohnen/Nebenkosten"})
+			r.query(ds, &transactions.Query{Category: transactions.Uncategorized})
+
+			n, err = ds.UncategorizeTransactions([]int64{1, 3})
+			r.check("uncategorize", n, err)
+			r.check("delete category", 0, ds.DeleteCategory("Wohnen/Nebenkosten"))
+			r.check("delete unknown category", 0, ds.DeleteCategory("Unbekannt"))
+			r.check("delete label", 0, ds.DeleteLabel("Steuer"))
+			r.labels(ds)
+			r.categories(ds)
+			r.query(ds, &transactions.Query{})
+		},
+	},
+	{
+		name: "revert",
+		run: func(r *recorder, ds datastore) {
+			batches := []*transactions.ImportBatch{batch("march.csv", rent(), coffee()), batch("april.csv", salary())}
+			if err := ds.Import(batches, transactions.DuplicateSkip); err != nil {
+				r.t.Fatal(err)
+			}
+			n, err := ds.LabelTransactions("Steuer", []int64{1, 3})
+			r.check("label", n, err)
+
+			n, err = ds.RevertImport(1)
+			r.check("revert", n, err)
+			n, err = ds.RevertImport(1)
+			r.check("revert again", n, err)
+			r.imports(ds)
+			r.labels(ds)
+
+			// Labels come back with the transactions.
+			again := []*transactions.ImportBatch{batch("march.csv", rent(), coffee())}
+			if err := ds.Import(again, transactions.DuplicateSkip); err != nil {
+				r.t.Fatal(err)
+			}
+			r.results(again)
+			r.labels(ds)
+			r.query(ds, &transactions.Query{})
+		},
+	},
+	{
+		name: "balances and accounts",
+		run: func(r *recorder, ds datastore) {
+			for _, b := range []*transactions.Balance{
+				{Account: "DE02120300000000202051", Type: transactions.ClosingBalance, Date: march5, Amount: transactions.Money{Minor: 100, Currency: "EUR"}},
+				{Account: "DE02120300000000202051", Type: transactions.OpeningBalance, Date: march1, Amount: transactions.Money{Minor: 50, Currency: "EUR"}},
+				{Account: "DE02120300000000202051", Type: transactions.ClosingBalance, Date: march5, Amount: transactions.Money{Minor: 200, Currency: "EUR"}},
+				{Account: "cash", Type: transactions.ClosingBalance, Date: march4, Amount: transactions.Money{Minor: 3000, Currency: "EUR"}},
+			} {
+				if err := ds.AddBalance(b); err != nil {
+					r.t.Fatal(err)
+				}
+			}
+			bs, err := ds.GetBalances("DE02120300000000202051")
+			if err != nil {
+				r.t.Fatal(err)
+			}
+			for _, b := range bs {
+				r.printf("balance %s %s %s %s", b.Type, b.Date.Format("2006-01-02"), b.Amount, b.Amount.Currency)
+			}
+
+			id, err := ds.AddAccount(&transactions.Account{IBAN: "DE02120300000000202051", Nickname: "Giro", Currency: "EUR"})
+			r.check("add account", id, err)
+			id, err = ds.AddAccount(&transactions.Account{IBAN: "DE02120300000000202051", Nickname: "Zweites"})
+			r.check("add known account", id, err)
+			for _, ref := range []string{"Giro", "DE02120300000000202051", "Unbekannt"} {
+				a, err := ds.GetAccount(ref)
+				if err != nil {
+					r.printf("account %s: %v", ref, errors.Is(err, transactions.ErrNoAccount))
+					continue
+				}
+				r.printf("account %s: %d %s %s", ref, a.ID, a.IBAN, a.Nickname)
+			}
+		},
+	},
+}
+
+// newSQLite returns a migrated SQLite database in a temporary directory.
+func newSQLite(t *testing.T) datastore {
+	t.Helper()
+
+	db := sql.NewDatabase(&sql.DatabaseOptions{URL: filepath.Join(t.TempDir(), "transactions.db")})
+	if err := db.Connect(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := db.AutoMigrate(); err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+// TestParity runs the same scenarios against the in-memory datastore and a
+// SQLite database, which have to observe the same.
+func TestParity(t *testing.T) {
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			want := &recorder{t: t}
+			sc.run(want, newSQLite(t))
+			got := &recorder{t: t}
+			sc.run(got, memory.NewDatabase())
+
+			for i := 0; i < len(want.lines) || i < len(got.lines); i++ {
+				var w, g string
+				if i < len(want.lines) {
+					w = want.lines[i]
+				}
+				if i < len(got.lines) {
+					g = got.lines[i]
+				}
+				if w != g {
+					t.Fatalf("line %d differs:\nsqlite %s\nmemory %s\n\nsqlite:\n%s", i+1, w, g, strings.Join(want.lines, "\n"))
+				}
+			}
+		})
+	}
+}
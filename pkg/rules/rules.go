@@ -0,0 +1,365 @@
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/ibihim/banking-csv-cli/pkg/transactions"
+)
+
+// Rule assigns labels and a category to the transactions it matches.
+type Rule struct {
+	// Name identifies the rule, it is unique within a file.
+	Name string `yaml:"name"`
+	// Match selects the transactions of the rule.
+	Match *Condition `yaml:"match"`
+	// Labels are the names of the labels of the matched transactions.
+	Labels []string `yaml:"labels"`
+	// Category is the path of the category of the matched transactions,
+	// like "Wohnen/Miete".
+	Category string `yaml:"category"`
+}
+
+// Condition matches transactions. All of its fields that are set must
+// match, fields that are not set match any transaction.
+type Condition struct {
+	// All matches if all of its conditions match.
+	All []*Condition `yaml:"all"`
+	// Any matches if at least one of its conditions matches.
+	Any []*Condition `yaml:"any"`
+	// Not matches if its condition does not match.
+	Not *Condition `yaml:"not"`
+
+	CreditorID  *Pattern `yaml:"creditorId"`
+	MandateRef  *Pattern `yaml:"mandateRef"`
+	BookingText *Pattern `yaml:"bookingText"`
+	Beneficiary *Pattern `yaml:"beneficiary"`
+	Purpose     *Pattern `yaml:"purpose"`
+	// IBAN is matched against the IBAN of the beneficiary.
+	IBAN *Pattern `yaml:"iban"`
+
+	// Amount is the range of the amount of the transaction.
+	Amount *AmountRange `yaml:"amount"`
+}
+
+// Pattern is a regular expression, written in the syntax of package regexp.
+// It matches if it matches any part of a field, "(?i)" ignores case.
+type Pattern struct {
+	*regexp.Regexp
+}
+
+// UnmarshalYAML compiles the regular expression of a node.
+func (p *Pattern) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.ScalarNode {
+		return fmt.Errorf("line %d: expected a regular expression", node.Line)
+	}
+
+	re, err := regexp.Compile(node.Value)
+	if err != nil {
+		return fmt.Errorf("line %d: %w", node.Line, err)
+	}
+	p.Regexp = re
+
+	return nil
+}
+
+// defaultCurrency is the currency of amount ranges that do not set one.
+const defaultCurrency = "EUR"
+
+// AmountRange is a range of amounts, both ends are included. Expenses are
+// negative.
+type AmountRange struct {
+	Min *Amount `yaml:"min"`
+	Max *Amount `yaml:"max"`
+	// Currency is the ISO 4217 code of the amounts, EUR if not set. The
+	// range matches only transactions in this currency.
+	Currency string `yaml:"currency"`
+}
+
+// Amount is an amount of an AmountRange, written like -12.34 with at most
+// the decimal places of the currency of the range.
+type Amount struct {
+	transactions.Money
+
+	value string
+	line  int
+}
+
+// UnmarshalYAML keeps the amount of a node, it is parsed once the currency
+// of the range is known.
+func (a *Amount) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.ScalarNode {
+		return fmt.Errorf("line %d: expected an amount", node.Line)
+	}
+	a.value = node.Value
+	a.line = node.Line
+
+	return nil
+}
+
+// parse parses the amounts of the range in its currency.
+func (r *AmountRange) parse() error {
+	if r.Currency == "" {
+		r.Currency = defaultCurrency
+	}
+	r.Currency = strings.ToUpper(r.Currency)
+
+	for _, a := range []*Amount{r.Min, r.Max} {
+		if a == nil {
+			continue
+		}
+		m, err := transactions.ParseMoney(a.value, r.Currency)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", a.line, err)
+		}
+		a.Money = m
+	}
+
+	return nil
+}
+
+// Contains returns true if the amount is in the currency of the range and
+// within its ends.
+func (r *AmountRange) Contains(m transactions.Money) bool {
+	if !strings.EqualFold(m.Currency, r.Currency) {
+		return false
+	}
+	if r.Min != nil && m.Minor < r.Min.Minor {
+		return false
+	}
+	if r.Max != nil && m.Minor > r.Max.Minor {
+		return false
+	}
+
+	return true
+}
+
+// file is the content of a rules file.
+type file struct {
+	Rules []*Rule `yaml:"rules"`
+}
+
+// Load reads the rules of a YAML file, like:
+//
+//	rules:
+//	  - name: rent
+//	    category: Wohnen/Miete
+//	    match:
+//	      all:
+//	        - beneficiary: "(?i)vermieter"
+//	        - amount: {max: -500}
+//	  - name: hotel
+//	    labels: [Reise]
+//	    match:
+//	      amount: {min: -50000, max: -5000, currency: JPY}
+//	  - name: streaming
+//	    labels: [Abo, Freizeit]
+//	    match:
+//	      any:
+//	        - creditorId: "^DE12ZZZ00000123456$"
+//	        - purpose: "(?i)netflix|spotify"
+//	      not:
+//	        bookingText: "(?i)gutschrift"
+func Load(r io.Reader) ([]*Rule, error) {
+	dec := yaml.NewDecoder(r)
+	dec.KnownFields(true)
+
+	f := &file{}
+	if err := dec.Decode(f); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	names := map[string]bool{}
+	for i, rule := range f.Rules {
+		if rule.Name == "" {
+			return nil, fmt.Errorf("rule %d has no name", i+1)
+		}
+		if names[rule.Name] {
+			return nil, fmt.Errorf("rule %q is defined twice", rule.Name)
+		}
+		names[rule.Name] = true
+
+		if rule.Match == nil {
+			return nil, fmt.Errorf("rule %q has no match", rule.Name)
+		}
+		if err := rule.Match.check(); err != nil {
+			return nil, fmt.Errorf("rule %q: %w", rule.Name, err)
+		}
+		if len(rule.Labels) == 0 && rule.Category == "" {
+			return nil, fmt.Errorf("rule %q assigns neither labels nor a category", rule.Name)
+		}
+		for j, label := range rule.Labels {
+			name, err := transactions.ParseLabelName(label)
+			if err != nil {
+				return nil, fmt.Errorf("rule %q: %w", rule.Name, err)
+			}
+			rule.Labels[j] = name
+		}
+		if rule.Category != "" {
+			names, err := transactions.ParseCategoryPath(rule.Category)
+			if err != nil {
+				return nil, fmt.Errorf("rule %q: %w", rule.Name, err)
+			}
+			rule.Category = transactions.CategoryPath(names)
+		}
+	}
+
+	return f.Rules, nil
+}
+
+// LoadFile reads the rules of a YAML file by its name.
+func LoadFile(filename string) ([]*Rule, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	rules, err := Load(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rules of %s: %w", filename, err)
+	}
+
+	return rules, nil
+}
+
+// Find returns the rule with the given name, or nil.
+func Find(rules []*Rule, name string) *Rule {
+	for _, r := range rules {
+		if r.Name == name {
+			return r
+		}
+	}
+
+	return nil
+}
+
+// check rejects empty conditions, which would match every transaction, and
+// parses the amount ranges of the condition and its subconditions.
+func (c *Condition) check() error {
+	if c.empty() {
+		return errors.New("empty condition, it would match every transaction")
+	}
+
+	subs := append(append([]*Condition{}, c.All...), c.Any...)
+	if c.Not != nil {
+		subs = append(subs, c.Not)
+	}
+	for _, sub := range subs {
+		if sub == nil {
+			return errors.New("empty condition, it would match every transaction")
+		}
+		if err := sub.check(); err != nil {
+			return err
+		}
+	}
+
+	if c.Amount != nil {
+		return c.Amount.parse()
+	}
+
+	return nil
+}
+
+// empty returns true if no field of the condition is set.
+func (c *Condition) empty() bool {
+	return len(c.All) == 0 && len(c.Any) == 0 && c.Not == nil &&
+		c.CreditorID == nil && c.MandateRef == nil && c.BookingText == nil &&
+		c.Beneficiary == nil && c.Purpose == nil && c.IBAN == nil && c.Amount == nil
+}
+
+// Matches returns true if the rule matches the transaction.
+func (r *Rule) Matches(t *transactions.Transaction) bool {
+	return r.Match.Matches(t)
+}
+
+// Matches returns true if the condition matches the transaction.
+func (c *Condition) Matches(t *transactions.Transaction) bool {
+	for _, sub := range c.All {
+		if !sub.Matches(t) {
+			return false
+		}
+	}
+	if len(c.Any) > 0 {
+		matched := false
+		for _, sub := range c.Any {
+			if sub.Matches(t) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if c.Not != nil && c.Not.Matches(t) {
+		return false
+	}
+
+	for _, field := range []struct {
+		pattern *Pattern
+		value   string
+	}{
+		{c.CreditorID, t.CreditorID},
+		{c.MandateRef, t.MandateRef},
+		{c.BookingText, t.BookingText},
+		{c.Beneficiary, t.Beneficiary},
+		{c.Purpose, t.Purpose},
+		{c.IBAN, t.AccountNumber},
+	} {
+		if field.pattern != nil && !field.pattern.MatchString(field.value) {
+			return false
+		}
+	}
+
+	if c.Amount != nil && !c.Amount.Contains(t.Amount) {
+		return false
+	}
+
+	return true
+}
+
+// Labels returns the ids of the transactions each label is assigned to by
+// the rules.
+func Labels(rules []*Rule, ts []*transactions.Transaction) map[string][]int64 {
+	labels := map[string][]int64{}
+	for _, t := range ts {
+		assigned := map[string]bool{}
+		for _, r := range rules {
+			if !r.Matches(t) {
+				continue
+			}
+			for _, label := range r.Labels {
+				if !assigned[label] {
+					assigned[label] = true
+					labels[label] = append(labels[label], t.ID)
+				}
+			}
+		}
+	}
+
+	return labels
+}
+
+// Categories returns the ids of the transactions each category is assigned
+// to by the rules. A transaction gets the category of the first rule that
+// matches it and assigns one.
+func Categories(rules []*Rule, ts []*transactions.Transaction) map[string][]int64 {
+	categories := map[string][]int64{}
+	for _, t := range ts {
+		for _, r := range rules {
+			if r.Category != "" && r.Matches(t) {
+				categories[r.Category] = append(categories[r.Category], t.ID)
+				break
+			}
+		}
+	}
+
+	return categories
+}
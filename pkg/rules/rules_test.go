@@ -0,0 +1,248 @@
+package rules
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ibihim/banking-csv-cli/pkg/transactions"
+)
+
+// testTransactions are matched by the tests, their ids are their indices.
+var testTransactions = []*transactions.Transaction{
+	{ID: 0, Beneficiary: "Hausverwaltung Schmidt", Purpose: "Miete März", Amount: transactions.NewMoney(-85000, "EUR")},
+	{ID: 1, Beneficiary: "Stadtwerke", Purpose: "Strom Abschlag", BookingText: "Lastschrift", CreditorID: "DE98ZZZ09999999999", Amount: transactions.NewMoney(-12345, "EUR")},
+	{ID: 2, Beneficiary: "Stadtwerke", Purpose: "Strom Erstattung", BookingText: "Gutschrift", Amount: transactions.NewMoney(4200, "EUR")},
+	{ID: 3, Beneficiary: "Netflix", Purpose: "Abo", BookingText: "Lastschrift", Amount: transactions.NewMoney(-1299, "EUR")},
+	{ID: 4, Beneficiary: "Ryokan", Purpose: "Hotel Kyoto", Amount: transactions.NewMoney(-35000, "JPY")},
+}
+
+// mustLoad loads the rules of a YAML document.
+func mustLoad(t *testing.T, doc string) []*Rule {
+	t.Helper()
+
+	rs, err := Load(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return rs
+}
+
+// matching returns the ids of the test transactions the rule matches.
+func matching(r *Rule) []int64 {
+	var ids []int64
+	for _, t := range testTransactions {
+		if r.Matches(t) {
+			ids = append(ids, t.ID)
+		}
+	}
+
+	return ids
+}
+
+func TestMatches(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		match string
+		want  []int64
+	}{
+		{
+			name:  "field",
+			match: `{beneficiary: "(?i)stadtwerke"}`,
+			want:  []int64{1, 2},
+		},
+		{
+			name:  "fields of a condition must all match",
+			match: `{beneficiary: "Stadtwerke", bookingText: "Gutschrift"}`,
+			want:  []int64{2},
+		},
+		{
+			name:  "all",
+			match: `{all: [{purpose: "Strom"}, {amount: {max: -100}}]}`,
+			want:  []int64{1},
+		},
+		{
+			name:  "any",
+			match: `{any: [{creditorId: "^DE98ZZZ"}, {beneficiary: "Netflix"}]}`,
+			want:  []int64{1, 3},
+		},
+		{
+			name:  "not",
+			match: `{not: {bookingText: "Lastschrift"}}`,
+			want:  []int64{0, 2, 4},
+		},
+		{
+			name:  "any with not",
+			match: `{any: [{beneficiary: "Stadtwerke"}, {beneficiary: "Netflix"}], not: {bookingText: "Gutschrift"}}`,
+			want:  []int64{1, 3},
+		},
+		{
+			name:  "nested",
+			match: `{all: [{any: [{purpose: "Miete"}, {purpose: "Strom"}]}, {not: {any: [{amount: {min: 0}}, {beneficiary: "Hausverwaltung"}]}}]}`,
+			want:  []int64{1},
+		},
+		{
+			name:  "amounts are euros by default",
+			match: `{amount: {min: -1000, max: -100}}`,
+			want:  []int64{0, 1},
+		},
+		{
+			name:  "amounts in yen",
+			match: `{amount: {min: -50000, max: -5000, currency: jpy}}`,
+			want:  []int64{4},
+		},
+		{
+			name:  "ends are included",
+			match: `{amount: {min: -123.45, max: -12.99}}`,
+			want:  []int64{1, 3},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rs := mustLoad(t, "rules:\n  - name: test\n    labels: [Test]\n    match: "+tc.match+"\n")
+			if got := matching(rs[0]); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		doc     string
+		wantErr string
+	}{
+		{
+			name: "empty match",
+			doc: `rules:
+  - name: everything
+    labels: [Alles]
+    match: {}
+`,
+			wantErr: `rule "everything": empty condition, it would match every transaction`,
+		},
+		{
+			name: "empty nested condition",
+			doc: `rules:
+  - name: everything
+    labels: [Alles]
+    match:
+      any: [{beneficiary: "Netflix"}, {}]
+`,
+			wantErr: `rule "everything": empty condition, it would match every transaction`,
+		},
+		{
+			name: "unknown field",
+			doc: `rules:
+  - name: typo
+    labels: [Abo]
+    match:
+      benificiary: "Netflix"
+`,
+			wantErr: "field benificiary not found",
+		},
+		{
+			name: "unknown field of an amount",
+			doc: `rules:
+  - name: typo
+    labels: [Abo]
+    match:
+      amount: {maximum: -10}
+`,
+			wantErr: "field maximum not found",
+		},
+		{
+			name: "duplicate names",
+			doc: `rules:
+  - name: abo
+    labels: [Abo]
+    match: {beneficiary: "Netflix"}
+  - name: abo
+    labels: [Abo]
+    match: {beneficiary: "Spotify"}
+`,
+			wantErr: `rule "abo" is defined twice`,
+		},
+		{
+			name: "too many decimal places",
+			doc: `rules:
+  - name: hotel
+    labels: [Reise]
+    match:
+      amount: {max: -5000.5, currency: JPY}
+`,
+			wantErr: `rule "hotel": line 5: amount "5000.5" has more than 0 decimal places`,
+		},
+		{
+			name: "invalid regular expression",
+			doc: `rules:
+  - name: broken
+    labels: [Abo]
+    match: {purpose: "(netflix"}
+`,
+			wantErr: "line 4: error parsing regexp",
+		},
+		{
+			name: "no match",
+			doc: `rules:
+  - name: nothing
+    labels: [Abo]
+`,
+			wantErr: `rule "nothing" has no match`,
+		},
+		{
+			name: "neither labels nor category",
+			doc: `rules:
+  - name: nothing
+    match: {beneficiary: "Netflix"}
+`,
+			wantErr: `rule "nothing" assigns neither labels nor a category`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Load(strings.NewReader(tc.doc))
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("got error %v, want %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestLabelsAndCategories(t *testing.T) {
+	rs := mustLoad(t, `rules:
+  - name: strom
+    labels: [Fixkosten]
+    category: Wohnen/Strom
+    match: {purpose: "Strom"}
+  - name: stadtwerke
+    labels: [Fixkosten, Stadtwerke]
+    category: Wohnen/Nebenkosten
+    match: {beneficiary: "Stadtwerke"}
+  - name: abo
+    labels: [Abo]
+    match: {beneficiary: "Netflix"}
+  - name: ausgaben
+    category: Sonstiges
+    match: {amount: {max: 0}}
+`)
+
+	// Labels of all matching rules are assigned, each once.
+	wantLabels := map[string][]int64{
+		"Fixkosten":  {1, 2},
+		"Stadtwerke": {1, 2},
+		"Abo":        {3},
+	}
+	if got := Labels(rs, testTransactions); !reflect.DeepEqual(got, wantLabels) {
+		t.Errorf("labels: got %v, want %v", got, wantLabels)
+	}
+
+	// The first matching rule with a category wins, "abo" has none.
+	wantCategories := map[string][]int64{
+		"Wohnen/Strom": {1, 2},
+		"Sonstiges":    {0, 3},
+	}
+	if got := Categories(rs, testTransactions); !reflect.DeepEqual(got, wantCategories) {
+		t.Errorf("categories: got %v, want %v", got, wantCategories)
+	}
+}
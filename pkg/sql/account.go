@@ -0,0 +1,116 @@
+package sql
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/ibihim/banking-csv-cli/pkg/transactions"
+)
+
+const (
+	selectAccountQuery = `
+		SELECT id, iban, bank, COALESCE(nickname, ''), owner, currency, type,
+			opening_balance, opening_date
+		FROM accounts
+	`
+	insertAccountQuery = `
+		INSERT INTO accounts (
+			iban, bank, nickname, owner, currency, type, opening_balance, opening_date
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		RETURNING id
+	`
+	updateAccountQuery = `
+		UPDATE accounts SET
+			iban = ?, bank = ?, nickname = ?, owner = ?, currency = ?, type = ?,
+			opening_balance = ?, opening_date = ?
+		WHERE id = ?
+	`
+)
+
+// AddAccount adds an account to the database and returns its id.
+func (d *Database) AddAccount(a *transactions.Account) (int64, error) {
+	err := d.db.QueryRow(d.rebind(insertAccountQuery), accountArgs(a)...).Scan(&a.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	return a.ID, nil
+}
+
+// UpdateAccount replaces the account of the same id.
+func (d *Database) UpdateAccount(a *transactions.Account) error {
+	res, err := d.db.Exec(d.rebind(updateAccountQuery), append(accountArgs(a), a.ID)...)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return transactions.ErrNoAccount
+	}
+
+	return nil
+}
+
+func accountArgs(a *transactions.Account) []any {
+	openingDate := sql.NullString{String: a.OpeningDate.Format(dateLayout), Valid: !a.OpeningDate.IsZero()}
+	nickname := sql.NullString{String: a.Nickname, Valid: a.Nickname != ""}
+
+	return []any{
+		a.IBAN, a.Bank, nickname, a.Owner, a.Currency, string(a.Type),
+		a.OpeningBalance.Minor, openingDate,
+	}
+}
+
+// GetAccounts returns all accounts, ordered by nickname and IBAN.
+func (d *Database) GetAccounts() ([]*transactions.Account, error) {
+	rows, err := d.db.Query(selectAccountQuery + ` ORDER BY COALESCE(nickname, iban), iban`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var accounts []*transactions.Account
+	for rows.Next() {
+		a, err := scanAccount(rows)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, a)
+	}
+
+	return accounts, rows.Err()
+}
+
+// GetAccount returns the account with the given IBAN or nickname. It returns
+// transactions.ErrNoAccount if there is none.
+func (d *Database) GetAccount(ref string) (*transactions.Account, error) {
+	a, err := scanAccount(d.db.QueryRow(
+		d.rebind(selectAccountQuery+` WHERE iban = ? OR nickname = ? ORDER BY id LIMIT 1`),
+		transactions.NormalizeIBAN(ref), ref,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, transactions.ErrNoAccount
+	}
+
+	return a, err
+}
+
+func scanAccount(row interface{ Scan(...any) error }) (*transactions.Account, error) {
+	a := &transactions.Account{}
+	accountType := ""
+	openingDate := dateValue{}
+
+	err := row.Scan(
+		&a.ID, &a.IBAN, &a.Bank, &a.Nickname, &a.Owner, &a.Currency, &accountType,
+		&a.OpeningBalance.Minor, &openingDate,
+	)
+	if err != nil {
+		return nil, err
+	}
+	a.Type = transactions.AccountType(accountType)
+	a.OpeningBalance.Currency = a.Currency
+	a.OpeningDate = openingDate.Time
+
+	return a, nil
+}
@@ -0,0 +1,373 @@
+package sql
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ibihim/banking-csv-cli/pkg/transactions"
+)
+
+// uncategorizedFilter selects the transactions without a category.
+const uncategorizedFilter = `fingerprint NOT IN (SELECT fingerprint FROM transaction_categories)`
+
+// GetCategories returns all categories, ordered like a tree, see
+// transactions.SetCategoryPaths.
+func (d *Database) GetCategories() ([]*transactions.Category, error) {
+	return getCategories(d.db)
+}
+
+// getCategories returns all categories of the database or of a database
+// transaction.
+func getCategories(q interface {
+	Query(string, ...any) (*sql.Rows, error)
+}) ([]*transactions.Category, error) {
+	rows, err := q.Query(`
+		SELECT c.id, COALESCE(c.parent_id, 0), c.name, COUNT(t.id)
+		FROM categories c
+		LEFT JOIN transaction_categories tc ON tc.category_id = c.id
+		LEFT JOIN transactions t ON t.fingerprint = tc.fingerprint
+		GROUP BY c.id, c.parent_id, c.name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var categories []*transactions.Category
+	for rows.Next() {
+		c := &transactions.Category{}
+		if err := rows.Scan(&c.ID, &c.ParentID, &c.Name, &c.Transactions); err != nil {
+			return nil, err
+		}
+		categories = append(categories, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	transactions.SetCategoryPaths(categories)
+
+	return categories, nil
+}
+
+// getCategoryPaths returns all categories of the database or of a database
+// transaction, like getCategories, but without counting their transactions.
+func getCategoryPaths(q interface {
+	Query(string, ...any) (*sql.Rows, error)
+}) ([]*transactions.Category, error) {
+	rows, err := q.Query(`SELECT id, COALESCE(parent_id, 0), name FROM categories`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var categories []*transactions.Category
+	for rows.Next() {
+		c := &transactions.Category{}
+		if err := rows.Scan(&c.ID, &c.ParentID, &c.Name); err != nil {
+			return nil, err
+		}
+		categories = append(categories, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	transactions.SetCategoryPaths(categories)
+
+	return categories, nil
+}
+
+// AddCategory adds the category of a path and its missing parents. It
+// returns the number of added categories, 0 if the category exists.
+func (d *Database) AddCategory(path string) (int, error) {
+	names, err := transactions.ParseCategoryPath(path)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	added := 0
+	parentID := sql.NullInt64{}
+	for _, name := range names {
+		var id int64
+		err := tx.QueryRow(
+			d.rebind(`SELECT id FROM categories WHERE COALESCE(parent_id, 0) = ? AND name = ?`),
+			parentID.Int64, name,
+		).Scan(&id)
+		if errors.Is(err, sql.ErrNoRows) {
+			err = tx.QueryRow(
+				d.rebind(`INSERT INTO categories (parent_id, name) VALUES (?, ?) RETURNING id`),
+				parentID, name,
+			).Scan(&id)
+			added++
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to add category %q: %w", name, err)
+		}
+		parentID = sql.NullInt64{Int64: id, Valid: true}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit categories: %w", err)
+	}
+
+	return added, nil
+}
+
+// CategorizeTransactions sets the category of the transactions with the
+// given ids. Transactions that have another category keep it, unless
+// replace is set. It returns the number of transactions whose category
+// changed.
+func (d *Database) CategorizeTransactions(path string, ids []int64, replace bool) (int64, error) {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Categories attach to fingerprints, which old transactions may lack.
+	if _, err := backfillFingerprints(tx, d.dialect); err != nil {
+		return 0, fmt.Errorf("failed to compute fingerprints of known transactions: %w", err)
+	}
+
+	categories, err := getCategoryPaths(tx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load categories: %w", err)
+	}
+	category := findCategory(categories, path)
+	if category == nil {
+		return 0, fmt.Errorf("%w: %s", transactions.ErrNoCategory, path)
+	}
+
+	onConflict := `DO NOTHING`
+	if replace {
+		onConflict = `DO UPDATE SET category_id = excluded.category_id
+			WHERE transaction_categories.category_id <> excluded.category_id`
+	}
+	stmt, err := tx.Prepare(d.rebind(`
+		INSERT INTO transaction_categories (fingerprint, category_id)
+		SELECT t.fingerprint, c.id FROM transactions t, categories c WHERE t.id = ? AND c.id = ?
+		ON CONFLICT (fingerprint) ` + onConflict))
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare category insert: %w", err)
+	}
+	defer stmt.Close()
+
+	var categorized int64
+	for _, id := range ids {
+		res, err := stmt.Exec(id, category.ID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to categorize transaction %d: %w", id, err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		categorized += n
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit categories: %w", err)
+	}
+
+	return categorized, nil
+}
+
+// UncategorizeTransactions removes the category of the transactions with
+// the given ids. It returns the number of transactions that had one.
+func (d *Database) UncategorizeTransactions(ids []int64) (int64, error) {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(d.rebind(`
+		DELETE FROM transaction_categories
+		WHERE fingerprint = (SELECT fingerprint FROM transactions WHERE id = ?)
+	`))
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare category delete: %w", err)
+	}
+	defer stmt.Close()
+
+	var uncategorized int64
+	for _, id := range ids {
+		res, err := stmt.Exec(id)
+		if err != nil {
+			return 0, fmt.Errorf("failed to uncategorize transaction %d: %w", id, err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		uncategorized += n
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit categories: %w", err)
+	}
+
+	return uncategorized, nil
+}
+
+// DeleteCategory deletes a category and its subcategories. Their
+// transactions become uncategorized.
+func (d *Database) DeleteCategory(path string) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	categories, err := getCategoryPaths(tx)
+	if err != nil {
+		return fmt.Errorf("failed to load categories: %w", err)
+	}
+	ids := transactions.Subcategories(categories, path)
+	if len(ids) == 0 {
+		return fmt.Errorf("%w: %s", transactions.ErrNoCategory, path)
+	}
+
+	in, args := inList(ids)
+	if _, err := tx.Exec(d.rebind(`DELETE FROM transaction_categories WHERE category_id IN `+in), args...); err != nil {
+		return fmt.Errorf("failed to uncategorize transactions: %w", err)
+	}
+	// Subcategories are deleted before their parents.
+	for i := len(ids) - 1; i >= 0; i-- {
+		if _, err := tx.Exec(d.rebind(`DELETE FROM categories WHERE id = ?`), ids[i]); err != nil {
+			return fmt.Errorf("failed to delete category: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit category deletion: %w", err)
+	}
+
+	return nil
+}
+
+// categoryFilter returns the condition and arguments that select the
+// transactions of a category path, including its subcategories.
+func (d *Database) categoryFilter(path string) (string, []any, error) {
+	if path == transactions.Uncategorized {
+		return uncategorizedFilter, nil, nil
+	}
+
+	categories, err := getCategoryPaths(d.db)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to load categories: %w", err)
+	}
+	ids := transactions.Subcategories(categories, path)
+	if len(ids) == 0 {
+		// Like an unknown label, an unknown category selects nothing.
+		return "1 = 0", nil, nil
+	}
+
+	in, args := inList(ids)
+	return `fingerprint IN (SELECT fingerprint FROM transaction_categories WHERE category_id IN ` + in + `)`, args, nil
+}
+
+// loadCategories sets the categories of the transactions.
+func (d *Database) loadCategories(ts []*transactions.Transaction) error {
+	chunks := fingerprintChunks(ts)
+	if len(chunks) == 0 {
+		return nil
+	}
+
+	categories, err := getCategoryPaths(d.db)
+	if err != nil {
+		return err
+	}
+	paths := make(map[int64]string, len(categories))
+	for _, c := range categories {
+		paths[c.ID] = c.Path
+	}
+
+	byFingerprint := map[string]string{}
+	for _, fingerprints := range chunks {
+		in, args := inList(fingerprints)
+		rows, err := d.db.Query(d.rebind(`
+			SELECT fingerprint, category_id FROM transaction_categories
+			WHERE fingerprint IN `+in), args...)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			var fingerprint string
+			var id int64
+			if err := rows.Scan(&fingerprint, &id); err != nil {
+				rows.Close()
+				return err
+			}
+			byFingerprint[fingerprint] = paths[id]
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+	}
+
+	for _, t := range ts {
+		t.Category = byFingerprint[t.Fingerprint]
+	}
+
+	return nil
+}
+
+// findCategory returns the category of a path, or nil.
+func findCategory(categories []*transactions.Category, path string) *transactions.Category {
+	for _, c := range categories {
+		if c.Path == path {
+			return c
+		}
+	}
+
+	return nil
+}
+
+// inList returns a parenthesized list of placeholders and the values as
+// arguments.
+func inList[T any](values []T) (string, []any) {
+	args := make([]any, len(values))
+	for i, v := range values {
+		args[i] = v
+	}
+
+	return "(" + strings.TrimSuffix(strings.Repeat("?, ", len(values)), ", ") + ")", args
+}
+
+// maxInList is the maximum number of values of an IN list, well below the
+// limit of SQLite on the number of arguments of a statement.
+const maxInList = 500
+
+// fingerprintChunks returns the distinct fingerprints of the transactions,
+// split into chunks of at most maxInList.
+func fingerprintChunks(ts []*transactions.Transaction) [][]string {
+	var chunks [][]string
+	var chunk []string
+	seen := make(map[string]bool, len(ts))
+	for _, t := range ts {
+		if t.Fingerprint == "" || seen[t.Fingerprint] {
+			continue
+		}
+		seen[t.Fingerprint] = true
+
+		chunk = append(chunk, t.Fingerprint)
+		if len(chunk) == maxInList {
+			chunks = append(chunks, chunk)
+			chunk = nil
+		}
+	}
+	if len(chunk) > 0 {
+		chunks = append(chunks, chunk)
+	}
+
+	return chunks
+}
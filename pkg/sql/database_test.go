@@ -0,0 +1,270 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ibihim/banking-csv-cli/pkg/transactions"
+)
+
+// newTestDatabase returns a migrated SQLite database in a temporary
+// directory.
+func newTestDatabase(t *testing.T) *Database {
+	t.Helper()
+
+	d := NewDatabase(&DatabaseOptions{URL: filepath.Join(t.TempDir(), "transactions.db")})
+	if err := d.Connect(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { d.Close() })
+
+	if _, err := d.AutoMigrate(); err != nil {
+		t.Fatal(err)
+	}
+
+	return d
+}
+
+func TestBackfillFingerprints(t *testing.T) {
+	d := newTestDatabase(t)
+
+	date := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	coffee := func() *transactions.Transaction {
+		return &transactions.Transaction{
+			Account:     "DE02120300000000202051",
+			BookingDate: date,
+			ValutaDate:  date,
+			Beneficiary: "Café",
+			Amount:      transactions.Money{Minor: -350, Currency: "EUR"},
+		}
+	}
+	rent := coffee()
+	rent.Beneficiary = "Hausverwaltung"
+	rent.Amount.Minor = -85000
+
+	// Before fingerprints existed, a file imported twice doubled its
+	// transactions.
+	var ids []int64
+	for i, tr := range []*transactions.Transaction{coffee(), rent, coffee(), coffee()} {
+		tr.Fingerprint = fmt.Sprintf("legacy-%d", i)
+		id, err := d.AddTransaction(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	if _, err := d.db.Exec(`UPDATE transactions SET fingerprint = NULL`); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := d.BackfillFingerprints()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("got %d legacy duplicates, want 2", n)
+	}
+
+	duplicates, err := d.GetLegacyDuplicates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, dup := range duplicates {
+		got = append(got, fmt.Sprintf("%d of %d", dup.Transaction.ID, dup.DuplicateOf))
+		if dup.Transaction.Fingerprint == "" {
+			t.Errorf("transaction %d has no fingerprint", dup.Transaction.ID)
+		}
+	}
+	want := []string{fmt.Sprintf("%d of %d", ids[2], ids[0]), fmt.Sprintf("%d of %d", ids[3], ids[0])}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	// Backfilling again finds nothing new.
+	if n, err := d.BackfillFingerprints(); err != nil || n != 0 {
+		t.Errorf("backfilling again: got %d, %v", n, err)
+	}
+
+	if kept, err := d.KeepLegacyDuplicates([]int64{ids[2]}); err != nil || kept != 1 {
+		t.Errorf("keep: got %d, %v", kept, err)
+	}
+	// Transactions that are not marked are not deleted.
+	if deleted, err := d.DeleteLegacyDuplicates([]int64{ids[0], ids[1], ids[2], ids[3]}); err != nil || deleted != 1 {
+		t.Errorf("delete: got %d, %v", deleted, err)
+	}
+
+	ts, err := d.GetTransactions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ts) != 3 {
+		t.Errorf("got %d transactions, want 3", len(ts))
+	}
+	if duplicates, err := d.GetLegacyDuplicates(); err != nil || len(duplicates) != 0 {
+		t.Errorf("got %d legacy duplicates left, %v", len(duplicates), err)
+	}
+}
+
+func TestImportAtomic(t *testing.T) {
+	date := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	transaction := func(beneficiary string, minor int64) *transactions.Transaction {
+		return &transactions.Transaction{
+			Account:     "DE02120300000000202051",
+			BookingDate: date,
+			ValutaDate:  date,
+			Beneficiary: beneficiary,
+			Amount:      transactions.Money{Minor: minor, Currency: "EUR"},
+		}
+	}
+	batch := func(name string, ts ...*transactions.Transaction) *transactions.ImportBatch {
+		return &transactions.ImportBatch{
+			Import:       &transactions.Import{Filename: name + ".csv", Checksum: name, Format: "csv"},
+			Transactions: ts,
+			Balances: []*transactions.Balance{
+				{Account: "DE02120300000000202051", Type: transactions.ClosingBalance, Date: date, Amount: transactions.Money{Minor: 100000, Currency: "EUR"}},
+			},
+		}
+	}
+
+	for _, tc := range []struct {
+		name    string
+		setup   string
+		batches []*transactions.ImportBatch
+		policy  transactions.DuplicatePolicy
+		wantErr string
+	}{
+		{
+			name: "duplicate in a later batch",
+			batches: []*transactions.ImportBatch{
+				batch("march", transaction("Hausverwaltung", -85000), transaction("Stadtwerke", -12345)),
+				batch("march-again", transaction("Bäckerei", -250), transaction("Stadtwerke", -12345)),
+			},
+			policy:  transactions.DuplicateFail,
+			wantErr: "duplicate transaction",
+		},
+		{
+			name: "failed insert in a later batch",
+			setup: `CREATE TRIGGER fail_insert BEFORE INSERT ON transactions
+				WHEN NEW.beneficiary = 'Kaputt'
+				BEGIN SELECT RAISE(ABORT, 'broken'); END`,
+			batches: []*transactions.ImportBatch{
+				batch("march", transaction("Hausverwaltung", -85000)),
+				batch("april", transaction("Bäckerei", -250), transaction("Kaputt", -100)),
+			},
+			policy:  transactions.DuplicateInsert,
+			wantErr: "broken",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			d := newTestDatabase(t)
+			if tc.setup != "" {
+				if _, err := d.db.Exec(tc.setup); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := d.Import(tc.batches, tc.policy)
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("got error %v, want %q", err, tc.wantErr)
+			}
+
+			ts, err := d.GetTransactions()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(ts) != 0 {
+				t.Errorf("stored %d transactions", len(ts))
+			}
+			imports, err := d.GetImports()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(imports) != 0 {
+				t.Errorf("stored %d imports", len(imports))
+			}
+			balances, err := d.GetBalances("DE02120300000000202051")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(balances) != 0 {
+				t.Errorf("stored %d balances", len(balances))
+			}
+		})
+	}
+}
+
+func TestMigrateISODates(t *testing.T) {
+	d := NewDatabase(&DatabaseOptions{URL: filepath.Join(t.TempDir(), "transactions.db")})
+	if err := d.Connect(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { d.Close() })
+
+	// The version before dates were stored as ISO 8601.
+	if err := d.MigrateTo(1792317800); err != nil {
+		t.Fatal(err)
+	}
+	legacy := []string{"01.03.24", "31.12.68", "01.01.69", "15.06.70", "29.02.00", "2024-03-05"}
+	for _, date := range legacy {
+		if _, err := d.db.Exec(
+			`INSERT INTO transactions (account, booking_date, valuta_date, amount, currency, fingerprint) VALUES (?, ?, ?, ?, ?, ?)`,
+			"DE02120300000000202051", date, date, -100, "EUR", date,
+		); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := d.db.Exec(
+			`INSERT INTO balances (account, type, date, amount, currency) VALUES (?, ?, ?, ?, ?)`,
+			"DE02120300000000202051", "closing", date, 100, "EUR",
+		); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := d.MigrateUp(0); err != nil {
+		t.Fatal(err)
+	}
+
+	// Years up to 68 are 20xx, from 69 on 19xx, like time.Parse reads them.
+	want := []string{"2024-03-01", "2068-12-31", "1969-01-01", "1970-06-15", "2000-02-29", "2024-03-05"}
+	for _, query := range []string{
+		`SELECT booking_date FROM transactions ORDER BY id`,
+		`SELECT valuta_date FROM transactions ORDER BY id`,
+		`SELECT date FROM balances ORDER BY id`,
+	} {
+		rows, err := d.db.Query(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for rows.Next() {
+			var date string
+			if err := rows.Scan(&date); err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, date)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			t.Fatal(err)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("%s:\ngot  %q\nwant %q", query, got, want)
+		}
+	}
+
+	// The migration agrees with the importers, which use time.Parse.
+	for i, s := range legacy[:5] {
+		parsed, err := time.Parse("02.01.06", s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := parsed.Format(dateLayout); got != want[i] {
+			t.Errorf("time.Parse reads %s as %s, the migration as %s", s, got, want[i])
+		}
+	}
+}
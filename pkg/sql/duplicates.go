@@ -0,0 +1,110 @@
+package sql
+
+import (
+	"fmt"
+
+	"github.com/ibihim/banking-csv-cli/pkg/transactions"
+)
+
+// LegacyDuplicate is a transaction imported before fingerprints existed
+// that is identical to an earlier one. It is most likely an import of the
+// same file twice, but may as well be a genuine second transaction.
+type LegacyDuplicate struct {
+	Transaction *transactions.Transaction
+	// DuplicateOf is the id of the earlier transaction.
+	DuplicateOf int64
+}
+
+// BackfillFingerprints computes the fingerprints of transactions that were
+// imported before fingerprints existed, and returns the number of legacy
+// duplicates it found.
+func (d *Database) BackfillFingerprints() (int, error) {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	n, err := backfillFingerprints(tx, d.dialect)
+	if err != nil {
+		return 0, fmt.Errorf("failed to compute fingerprints of known transactions: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit fingerprints: %w", err)
+	}
+
+	return n, nil
+}
+
+// GetLegacyDuplicates returns the legacy duplicates that were neither
+// deleted nor kept yet, ordered by id.
+func (d *Database) GetLegacyDuplicates() ([]*LegacyDuplicate, error) {
+	rows, err := d.db.Query(`
+		SELECT ` + transactionColumns + `, duplicate_of
+		FROM transactions JOIN legacy_duplicates ON transaction_id = id
+		ORDER BY id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var duplicates []*LegacyDuplicate
+	for rows.Next() {
+		dup := &LegacyDuplicate{}
+		var err error
+		dup.Transaction, err = scanTransaction(scanFunc(func(dest ...any) error {
+			return rows.Scan(append(dest, &dup.DuplicateOf)...)
+		}))
+		if err != nil {
+			return nil, err
+		}
+		duplicates = append(duplicates, dup)
+	}
+
+	return duplicates, rows.Err()
+}
+
+// DeleteLegacyDuplicates deletes the legacy duplicates with the given ids.
+// Ids of other transactions are ignored. It returns the number of deleted
+// transactions.
+func (d *Database) DeleteLegacyDuplicates(ids []int64) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	in, args := inList(ids)
+
+	res, err := d.db.Exec(d.rebind(`
+		DELETE FROM transactions
+		WHERE id IN (SELECT transaction_id FROM legacy_duplicates WHERE transaction_id IN `+in+`)
+	`), args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete legacy duplicates: %w", err)
+	}
+
+	return res.RowsAffected()
+}
+
+// KeepLegacyDuplicates marks the legacy duplicates with the given ids as
+// genuine transactions. It returns the number of kept transactions.
+func (d *Database) KeepLegacyDuplicates(ids []int64) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	in, args := inList(ids)
+
+	res, err := d.db.Exec(d.rebind(`DELETE FROM legacy_duplicates WHERE transaction_id IN `+in), args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to keep legacy duplicates: %w", err)
+	}
+
+	return res.RowsAffected()
+}
+
+// scanFunc adapts a function to the Scan method of a row.
+type scanFunc func(dest ...any) error
+
+func (f scanFunc) Scan(dest ...any) error {
+	return f(dest...)
+}
@@ -0,0 +1,234 @@
+package sql
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/ibihim/banking-csv-cli/pkg/transactions"
+)
+
+// labelFilter selects the transactions with the label of the given name.
+const labelFilter = `fingerprint IN (
+	SELECT tl.fingerprint FROM transaction_labels tl JOIN labels l ON l.id = tl.label_id
+	WHERE l.name = ?
+)`
+
+// GetLabels returns all labels, ordered by name.
+func (d *Database) GetLabels() ([]*transactions.Label, error) {
+	rows, err := d.db.Query(`
+		SELECT l.id, l.name, COUNT(t.id)
+		FROM labels l
+		LEFT JOIN transaction_labels tl ON tl.label_id = l.id
+		LEFT JOIN transactions t ON t.fingerprint = tl.fingerprint
+		GROUP BY l.id, l.name
+		ORDER BY l.name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var labels []*transactions.Label
+	for rows.Next() {
+		l := &transactions.Label{}
+		if err := rows.Scan(&l.ID, &l.Name, &l.Transactions); err != nil {
+			return nil, err
+		}
+		labels = append(labels, l)
+	}
+
+	return labels, rows.Err()
+}
+
+// LabelTransactions adds the label to the transactions with the given ids.
+// The label is created if it does not exist, unless none of the ids is
+// known. It returns the number of transactions that did not have the label
+// yet.
+func (d *Database) LabelTransactions(name string, ids []int64) (int64, error) {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Labels attach to fingerprints, which old transactions may lack.
+	if _, err := backfillFingerprints(tx, d.dialect); err != nil {
+		return 0, fmt.Errorf("failed to compute fingerprints of known transactions: %w", err)
+	}
+
+	// A conflicting insert would use up an id, so known labels are looked
+	// up first.
+	labelID, err := findLabelID(tx, d.dialect, name)
+	created := errors.Is(err, transactions.ErrNoLabel)
+	if created {
+		err = tx.QueryRow(d.rebind(`INSERT INTO labels (name) VALUES (?) RETURNING id`), name).Scan(&labelID)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to add label: %w", err)
+	}
+
+	stmt, err := tx.Prepare(d.rebind(`
+		INSERT INTO transaction_labels (label_id, fingerprint)
+		SELECT l.id, t.fingerprint FROM labels l, transactions t WHERE l.id = ? AND t.id = ?
+		ON CONFLICT (label_id, fingerprint) DO NOTHING
+	`))
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare label insert: %w", err)
+	}
+	defer stmt.Close()
+
+	var labeled int64
+	for _, id := range ids {
+		res, err := stmt.Exec(labelID, id)
+		if err != nil {
+			return 0, fmt.Errorf("failed to label transaction %d: %w", id, err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		labeled += n
+	}
+	// A new label without transactions is rolled back.
+	if created && labeled == 0 {
+		return 0, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit labels: %w", err)
+	}
+
+	return labeled, nil
+}
+
+// UnlabelTransactions removes the label from the transactions with the
+// given ids. It returns the number of transactions that had the label.
+func (d *Database) UnlabelTransactions(name string, ids []int64) (int64, error) {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	labelID, err := findLabelID(tx, d.dialect, name)
+	if err != nil {
+		return 0, err
+	}
+
+	stmt, err := tx.Prepare(d.rebind(`
+		DELETE FROM transaction_labels
+		WHERE label_id = ? AND fingerprint = (SELECT fingerprint FROM transactions WHERE id = ?)
+	`))
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare label delete: %w", err)
+	}
+	defer stmt.Close()
+
+	var unlabeled int64
+	for _, id := range ids {
+		res, err := stmt.Exec(labelID, id)
+		if err != nil {
+			return 0, fmt.Errorf("failed to unlabel transaction %d: %w", id, err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		unlabeled += n
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit labels: %w", err)
+	}
+
+	return unlabeled, nil
+}
+
+// RenameLabel renames a label. The transactions keep it.
+func (d *Database) RenameLabel(name, newName string) error {
+	res, err := d.db.Exec(d.rebind(`UPDATE labels SET name = ? WHERE name = ?`), newName, name)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return fmt.Errorf("%w: %s", transactions.ErrNoLabel, name)
+	}
+
+	return nil
+}
+
+// DeleteLabel removes a label from all transactions and deletes it.
+func (d *Database) DeleteLabel(name string) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	labelID, err := findLabelID(tx, d.dialect, name)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(d.rebind(`DELETE FROM transaction_labels WHERE label_id = ?`), labelID); err != nil {
+		return fmt.Errorf("failed to unlabel transactions: %w", err)
+	}
+	if _, err := tx.Exec(d.rebind(`DELETE FROM labels WHERE id = ?`), labelID); err != nil {
+		return fmt.Errorf("failed to delete label: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit label deletion: %w", err)
+	}
+
+	return nil
+}
+
+// findLabelID returns the id of the label with the given name, or
+// transactions.ErrNoLabel.
+func findLabelID(tx *sql.Tx, dialect *dialect, name string) (int64, error) {
+	var id int64
+	err := tx.QueryRow(dialect.rebind(`SELECT id FROM labels WHERE name = ?`), name).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("%w: %s", transactions.ErrNoLabel, name)
+	}
+
+	return id, err
+}
+
+// loadLabels sets the labels of the transactions.
+func (d *Database) loadLabels(ts []*transactions.Transaction) error {
+	labels := map[string][]string{}
+	for _, fingerprints := range fingerprintChunks(ts) {
+		in, args := inList(fingerprints)
+		rows, err := d.db.Query(d.rebind(`
+			SELECT tl.fingerprint, l.name
+			FROM transaction_labels tl JOIN labels l ON l.id = tl.label_id
+			WHERE tl.fingerprint IN `+in+`
+			ORDER BY l.name
+		`), args...)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			var fingerprint, name string
+			if err := rows.Scan(&fingerprint, &name); err != nil {
+				rows.Close()
+				return err
+			}
+			labels[fingerprint] = append(labels[fingerprint], name)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+	}
+
+	for _, t := range ts {
+		t.Labels = labels[t.Fingerprint]
+	}
+
+	return nil
+}
@@ -0,0 +1,186 @@
+package sql
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+// migrationsFS contains the migrations of the schema, so the binary works
+// without a source checkout.
+//
+//go:embed migrations/sqlite/*.sql migrations/postgres/*.sql
+var migrationsFS embed.FS
+
+// Migration is a migration of the schema.
+type Migration struct {
+	// Version is the version the migration migrates to.
+	Version uint
+	// Name is the description in the file name of the migration.
+	Name string
+	// Applied is true if the schema contains the migration.
+	Applied bool
+}
+
+// MigrationStatus is the state of the schema.
+type MigrationStatus struct {
+	// Version is the version of the schema, 0 if no migration ran yet.
+	Version uint
+	// Dirty is true if the migration to Version failed halfway. It has to
+	// be fixed manually and forced to a version.
+	Dirty bool
+	// Migrations are all migrations, oldest first.
+	Migrations []*Migration
+}
+
+// Latest returns the version of the newest migration.
+func (s *MigrationStatus) Latest() uint {
+	if len(s.Migrations) == 0 {
+		return 0
+	}
+
+	return s.Migrations[len(s.Migrations)-1].Version
+}
+
+// Behind returns true if there are migrations that are not applied yet.
+func (s *MigrationStatus) Behind() bool {
+	return s.Version < s.Latest()
+}
+
+// migrationsSource returns the migrations of the dialect of the database.
+func (d *Database) migrationsSource() (source.Driver, error) {
+	return iofs.New(migrationsFS, d.dialect.migrations)
+}
+
+// runMigration runs fn with the migrate instance of the database. It is not
+// an error if there was nothing to migrate.
+func (d *Database) runMigration(fn func(m *migrate.Migrate) error) error {
+	src, err := d.migrationsSource()
+	if err != nil {
+		return fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	// The migrate instance is not closed, as that would close the database
+	// connection.
+	driver, release, err := d.Driver()
+	if err != nil {
+		return fmt.Errorf("failed to get db driver: %w", err)
+	}
+	defer release()
+
+	m, err := migrate.NewWithInstance("iofs", src, d.dialect.name, driver)
+	if err != nil {
+		return fmt.Errorf("failed to create migration instance: %w", err)
+	}
+
+	if err := fn(m); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("failed to run migrations: %w", err)
+	}
+
+	return nil
+}
+
+// MigrateUp applies the given number of migrations, all of them if steps is
+// 0.
+func (d *Database) MigrateUp(steps int) error {
+	return d.runMigration(func(m *migrate.Migrate) error {
+		if steps == 0 {
+			return m.Up()
+		}
+		return m.Steps(steps)
+	})
+}
+
+// MigrateDown reverts the given number of migrations, all of them if steps
+// is 0.
+func (d *Database) MigrateDown(steps int) error {
+	return d.runMigration(func(m *migrate.Migrate) error {
+		if steps == 0 {
+			return m.Down()
+		}
+		return m.Steps(-steps)
+	})
+}
+
+// MigrateTo applies or reverts migrations until the schema has the given
+// version.
+func (d *Database) MigrateTo(version uint) error {
+	return d.runMigration(func(m *migrate.Migrate) error {
+		return m.Migrate(version)
+	})
+}
+
+// ForceVersion sets the version of the schema and clears the dirty flag,
+// without running any migration. A version of -1 means no migration ran.
+func (d *Database) ForceVersion(version int) error {
+	return d.runMigration(func(m *migrate.Migrate) error {
+		return m.Force(version)
+	})
+}
+
+// MigrationStatus returns the state of the schema.
+func (d *Database) MigrationStatus() (*MigrationStatus, error) {
+	status := &MigrationStatus{}
+	err := d.runMigration(func(m *migrate.Migrate) error {
+		var err error
+		status.Version, status.Dirty, err = m.Version()
+		if errors.Is(err, migrate.ErrNilVersion) {
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get schema version: %w", err)
+	}
+
+	src, err := d.migrationsSource()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+	defer src.Close()
+
+	version, err := src.First()
+	for err == nil {
+		r, identifier, readErr := src.ReadUp(version)
+		if readErr != nil {
+			return nil, fmt.Errorf("failed to read migration %d: %w", version, readErr)
+		}
+		r.Close()
+
+		status.Migrations = append(status.Migrations, &Migration{
+			Version: version,
+			Name:    strings.TrimSuffix(identifier, ".up.sql"),
+			Applied: version < status.Version || (version == status.Version && !status.Dirty),
+		})
+		version, err = src.Next(version)
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	return status, nil
+}
+
+// AutoMigrate applies all migrations if the schema is behind. It returns the
+// status before the migration.
+func (d *Database) AutoMigrate() (*MigrationStatus, error) {
+	status, err := d.MigrationStatus()
+	if err != nil {
+		return nil, err
+	}
+
+	if status.Dirty {
+		return nil, fmt.Errorf("the migration to version %d failed, fix the schema and run `db migrate force <version>`", status.Version)
+	}
+	if !status.Behind() {
+		return status, nil
+	}
+
+	return status, d.MigrateUp(0)
+}
@@ -0,0 +1,3 @@
+DROP TABLE balances;
+DROP TABLE transactions;
+DROP TABLE imports;
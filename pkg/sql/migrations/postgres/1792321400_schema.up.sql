@@ -0,0 +1,55 @@
+-- PostgreSQL starts at the schema SQLite has after 1792321400_iso_dates.
+-- Dates are DATE columns. They are written as ISO 8601 text, like in SQLite,
+-- which PostgreSQL converts, so both share their queries.
+CREATE TABLE imports (
+    id BIGSERIAL PRIMARY KEY,
+    filename TEXT NOT NULL,
+    checksum TEXT NOT NULL,
+    format TEXT NOT NULL,
+    imported_at TEXT NOT NULL,
+    inserted INTEGER NOT NULL DEFAULT 0,
+    skipped INTEGER NOT NULL DEFAULT 0,
+    duplicates INTEGER NOT NULL DEFAULT 0,
+    rejected INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX imports_checksum ON imports (checksum);
+
+CREATE TABLE transactions (
+    id BIGSERIAL PRIMARY KEY,
+    account TEXT NOT NULL,
+    booking_date DATE NOT NULL,
+    valuta_date DATE NOT NULL,
+    booking_text TEXT,
+    purpose TEXT,
+    creditor_id TEXT,
+    mandate_ref TEXT,
+    customer_ref TEXT,
+    collector_ref TEXT,
+    orig_amount BIGINT NOT NULL DEFAULT 0,
+    chargeback_fee BIGINT NOT NULL DEFAULT 0,
+    beneficiary TEXT,
+    account_number TEXT,
+    bic TEXT,
+    amount BIGINT NOT NULL,
+    currency TEXT NOT NULL,
+    additional_details TEXT,
+    fitid TEXT NOT NULL DEFAULT '',
+    fingerprint TEXT,
+    import_id BIGINT REFERENCES imports (id)
+);
+CREATE INDEX transactions_fitid ON transactions (account, fitid);
+CREATE INDEX transactions_duplicate ON transactions (account, booking_date, amount);
+CREATE UNIQUE INDEX transactions_fingerprint ON transactions (fingerprint);
+CREATE INDEX transactions_import ON transactions (import_id);
+CREATE INDEX transactions_account_valuta_date ON transactions (account, valuta_date);
+CREATE INDEX transactions_beneficiary ON transactions (beneficiary);
+
+CREATE TABLE balances (
+    id BIGSERIAL PRIMARY KEY,
+    account TEXT NOT NULL,
+    type TEXT NOT NULL,
+    date DATE NOT NULL,
+    amount BIGINT NOT NULL,
+    currency TEXT NOT NULL,
+    UNIQUE (account, type, date)
+);
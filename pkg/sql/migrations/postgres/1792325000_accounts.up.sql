@@ -0,0 +1,11 @@
+CREATE TABLE accounts (
+    id BIGSERIAL PRIMARY KEY,
+    iban TEXT NOT NULL UNIQUE,
+    bank TEXT NOT NULL DEFAULT '',
+    nickname TEXT UNIQUE,
+    owner TEXT NOT NULL DEFAULT '',
+    currency TEXT NOT NULL,
+    type TEXT NOT NULL,
+    opening_balance BIGINT NOT NULL DEFAULT 0,
+    opening_date DATE
+);
@@ -0,0 +1,13 @@
+CREATE TABLE labels (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE
+);
+
+-- Labels attach to fingerprints instead of ids, so they survive reverting
+-- and importing a file again.
+CREATE TABLE transaction_labels (
+    label_id BIGINT NOT NULL REFERENCES labels (id) ON DELETE CASCADE,
+    fingerprint TEXT NOT NULL,
+    PRIMARY KEY (label_id, fingerprint)
+);
+CREATE INDEX transaction_labels_fingerprint ON transaction_labels (fingerprint);
@@ -0,0 +1,16 @@
+CREATE TABLE categories (
+    id BIGSERIAL PRIMARY KEY,
+    parent_id BIGINT REFERENCES categories (id) ON DELETE CASCADE,
+    name TEXT NOT NULL
+);
+-- Names are unique among the children of a category and among the top level
+-- categories.
+CREATE UNIQUE INDEX categories_parent_name ON categories (COALESCE(parent_id, 0), name);
+
+-- Each transaction has at most one category, by its fingerprint like labels.
+-- Transactions without one are uncategorized.
+CREATE TABLE transaction_categories (
+    fingerprint TEXT PRIMARY KEY,
+    category_id BIGINT NOT NULL REFERENCES categories (id) ON DELETE CASCADE
+);
+CREATE INDEX transaction_categories_category ON transaction_categories (category_id);
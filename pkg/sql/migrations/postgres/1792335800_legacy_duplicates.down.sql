@@ -0,0 +1 @@
+DROP TABLE legacy_duplicates;
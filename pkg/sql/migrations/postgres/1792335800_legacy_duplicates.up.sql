@@ -0,0 +1,7 @@
+-- Transactions imported before fingerprints existed were not checked for
+-- duplicates. Identical ones get distinct fingerprints when they are
+-- backfilled, and are recorded here, so they can be reviewed.
+CREATE TABLE legacy_duplicates (
+    transaction_id BIGINT PRIMARY KEY REFERENCES transactions (id) ON DELETE CASCADE,
+    duplicate_of BIGINT NOT NULL REFERENCES transactions (id) ON DELETE CASCADE
+);
@@ -0,0 +1,2 @@
+DROP INDEX transactions_fitid;
+ALTER TABLE transactions DROP COLUMN fitid;
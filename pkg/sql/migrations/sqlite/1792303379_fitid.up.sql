@@ -0,0 +1,2 @@
+ALTER TABLE transactions ADD COLUMN fitid TEXT NOT NULL DEFAULT '';
+CREATE INDEX transactions_fitid ON transactions (account, fitid);
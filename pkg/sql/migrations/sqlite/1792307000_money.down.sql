@@ -0,0 +1,66 @@
+CREATE TABLE transactions_real (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    account TEXT NOT NULL,
+    booking_date TEXT NOT NULL,
+    valuta_date TEXT NOT NULL,
+    booking_text TEXT,
+    purpose TEXT,
+    creditor_id TEXT,
+    mandate_ref TEXT,
+    customer_ref TEXT,
+    collector_ref TEXT,
+    orig_amount REAL,
+    chargeback_fee REAL,
+    beneficiary TEXT,
+    account_number TEXT,
+    bic TEXT,
+    amount REAL NOT NULL,
+    currency TEXT NOT NULL,
+    additional_details TEXT,
+    fitid TEXT NOT NULL DEFAULT ''
+);
+
+INSERT INTO transactions_real
+SELECT
+    id, account, booking_date, valuta_date, booking_text, purpose, creditor_id,
+    mandate_ref, customer_ref, collector_ref,
+    orig_amount / scale, chargeback_fee / scale,
+    beneficiary, account_number, bic,
+    amount / scale,
+    currency, additional_details, fitid
+FROM (
+    SELECT *, CASE
+        WHEN upper(currency) IN ('BHD', 'IQD', 'JOD', 'KWD', 'LYD', 'OMR', 'TND') THEN 1000.0
+        WHEN upper(currency) IN ('BIF', 'CLP', 'DJF', 'GNF', 'ISK', 'JPY', 'KMF', 'KRW', 'PYG', 'RWF', 'UGX', 'VND', 'VUV', 'XAF', 'XOF', 'XPF') THEN 1.0
+        ELSE 100.0
+    END AS scale
+    FROM transactions
+);
+
+DROP TABLE transactions;
+ALTER TABLE transactions_real RENAME TO transactions;
+CREATE INDEX transactions_fitid ON transactions (account, fitid);
+
+CREATE TABLE balances_real (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    account TEXT NOT NULL,
+    type TEXT NOT NULL,
+    date TEXT NOT NULL,
+    amount REAL NOT NULL,
+    currency TEXT NOT NULL,
+    UNIQUE (account, type, date)
+);
+
+INSERT INTO balances_real
+SELECT
+    id, account, type, date,
+    amount / CASE
+        WHEN upper(currency) IN ('BHD', 'IQD', 'JOD', 'KWD', 'LYD', 'OMR', 'TND') THEN 1000.0
+        WHEN upper(currency) IN ('BIF', 'CLP', 'DJF', 'GNF', 'ISK', 'JPY', 'KMF', 'KRW', 'PYG', 'RWF', 'UGX', 'VND', 'VUV', 'XAF', 'XOF', 'XPF') THEN 1.0
+        ELSE 100.0
+    END,
+    currency
+FROM balances;
+
+DROP TABLE balances;
+ALTER TABLE balances_real RENAME TO balances;
@@ -0,0 +1,68 @@
+-- Amounts are stored in minor units of their currency, e.g. cents.
+CREATE TABLE transactions_money (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    account TEXT NOT NULL,
+    booking_date TEXT NOT NULL,
+    valuta_date TEXT NOT NULL,
+    booking_text TEXT,
+    purpose TEXT,
+    creditor_id TEXT,
+    mandate_ref TEXT,
+    customer_ref TEXT,
+    collector_ref TEXT,
+    orig_amount INTEGER NOT NULL DEFAULT 0,
+    chargeback_fee INTEGER NOT NULL DEFAULT 0,
+    beneficiary TEXT,
+    account_number TEXT,
+    bic TEXT,
+    amount INTEGER NOT NULL,
+    currency TEXT NOT NULL,
+    additional_details TEXT,
+    fitid TEXT NOT NULL DEFAULT ''
+);
+
+INSERT INTO transactions_money
+SELECT
+    id, account, booking_date, valuta_date, booking_text, purpose, creditor_id,
+    mandate_ref, customer_ref, collector_ref,
+    CAST(ROUND(COALESCE(orig_amount, 0) * scale) AS INTEGER),
+    CAST(ROUND(COALESCE(chargeback_fee, 0) * scale) AS INTEGER),
+    beneficiary, account_number, bic,
+    CAST(ROUND(amount * scale) AS INTEGER),
+    currency, additional_details, fitid
+FROM (
+    SELECT *, CASE
+        WHEN upper(currency) IN ('BHD', 'IQD', 'JOD', 'KWD', 'LYD', 'OMR', 'TND') THEN 1000
+        WHEN upper(currency) IN ('BIF', 'CLP', 'DJF', 'GNF', 'ISK', 'JPY', 'KMF', 'KRW', 'PYG', 'RWF', 'UGX', 'VND', 'VUV', 'XAF', 'XOF', 'XPF') THEN 1
+        ELSE 100
+    END AS scale
+    FROM transactions
+);
+
+DROP TABLE transactions;
+ALTER TABLE transactions_money RENAME TO transactions;
+CREATE INDEX transactions_fitid ON transactions (account, fitid);
+
+CREATE TABLE balances_money (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    account TEXT NOT NULL,
+    type TEXT NOT NULL,
+    date TEXT NOT NULL,
+    amount INTEGER NOT NULL,
+    currency TEXT NOT NULL,
+    UNIQUE (account, type, date)
+);
+
+INSERT INTO balances_money
+SELECT
+    id, account, type, date,
+    CAST(ROUND(amount * CASE
+        WHEN upper(currency) IN ('BHD', 'IQD', 'JOD', 'KWD', 'LYD', 'OMR', 'TND') THEN 1000
+        WHEN upper(currency) IN ('BIF', 'CLP', 'DJF', 'GNF', 'ISK', 'JPY', 'KMF', 'KRW', 'PYG', 'RWF', 'UGX', 'VND', 'VUV', 'XAF', 'XOF', 'XPF') THEN 1
+        ELSE 100
+    END) AS INTEGER),
+    currency
+FROM balances;
+
+DROP TABLE balances;
+ALTER TABLE balances_money RENAME TO balances;
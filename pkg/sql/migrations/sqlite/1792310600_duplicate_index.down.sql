@@ -0,0 +1 @@
+DROP INDEX transactions_duplicate;
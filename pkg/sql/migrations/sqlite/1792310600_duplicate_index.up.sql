@@ -0,0 +1 @@
+CREATE INDEX transactions_duplicate ON transactions (account, booking_date, amount);
@@ -0,0 +1,2 @@
+DROP INDEX transactions_fingerprint;
+ALTER TABLE transactions DROP COLUMN fingerprint;
@@ -0,0 +1,4 @@
+-- The fingerprint of transactions imported before is computed on the next
+-- import, since SQLite cannot hash the purpose.
+ALTER TABLE transactions ADD COLUMN fingerprint TEXT;
+CREATE UNIQUE INDEX transactions_fingerprint ON transactions (fingerprint);
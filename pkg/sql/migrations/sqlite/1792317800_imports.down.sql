@@ -0,0 +1,39 @@
+-- SQLite cannot drop a column that references another table.
+CREATE TABLE transactions_down (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    account TEXT NOT NULL,
+    booking_date TEXT NOT NULL,
+    valuta_date TEXT NOT NULL,
+    booking_text TEXT,
+    purpose TEXT,
+    creditor_id TEXT,
+    mandate_ref TEXT,
+    customer_ref TEXT,
+    collector_ref TEXT,
+    orig_amount INTEGER NOT NULL DEFAULT 0,
+    chargeback_fee INTEGER NOT NULL DEFAULT 0,
+    beneficiary TEXT,
+    account_number TEXT,
+    bic TEXT,
+    amount INTEGER NOT NULL,
+    currency TEXT NOT NULL,
+    additional_details TEXT,
+    fitid TEXT NOT NULL DEFAULT '',
+    fingerprint TEXT
+);
+
+INSERT INTO transactions_down
+SELECT
+    id, account, booking_date, valuta_date, booking_text, purpose, creditor_id,
+    mandate_ref, customer_ref, collector_ref, orig_amount, chargeback_fee,
+    beneficiary, account_number, bic, amount, currency, additional_details,
+    fitid, fingerprint
+FROM transactions;
+
+DROP TABLE transactions;
+ALTER TABLE transactions_down RENAME TO transactions;
+CREATE INDEX transactions_fitid ON transactions (account, fitid);
+CREATE INDEX transactions_duplicate ON transactions (account, booking_date, amount);
+CREATE UNIQUE INDEX transactions_fingerprint ON transactions (fingerprint);
+
+DROP TABLE imports;
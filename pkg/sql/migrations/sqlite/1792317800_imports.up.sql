@@ -0,0 +1,15 @@
+CREATE TABLE imports (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    filename TEXT NOT NULL,
+    checksum TEXT NOT NULL,
+    format TEXT NOT NULL,
+    imported_at TEXT NOT NULL,
+    inserted INTEGER NOT NULL DEFAULT 0,
+    skipped INTEGER NOT NULL DEFAULT 0,
+    duplicates INTEGER NOT NULL DEFAULT 0,
+    rejected INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX imports_checksum ON imports (checksum);
+
+ALTER TABLE transactions ADD COLUMN import_id INTEGER REFERENCES imports (id);
+CREATE INDEX transactions_import ON transactions (import_id);
@@ -0,0 +1,14 @@
+DROP INDEX transactions_beneficiary;
+DROP INDEX transactions_account_valuta_date;
+
+UPDATE transactions SET booking_date =
+    substr(booking_date, 9, 2) || '.' || substr(booking_date, 6, 2) || '.' || substr(booking_date, 3, 2)
+WHERE booking_date LIKE '____-__-__';
+
+UPDATE transactions SET valuta_date =
+    substr(valuta_date, 9, 2) || '.' || substr(valuta_date, 6, 2) || '.' || substr(valuta_date, 3, 2)
+WHERE valuta_date LIKE '____-__-__';
+
+UPDATE balances SET date =
+    substr(date, 9, 2) || '.' || substr(date, 6, 2) || '.' || substr(date, 3, 2)
+WHERE date LIKE '____-__-__';
@@ -0,0 +1,19 @@
+-- Dates are stored as YYYY-MM-DD instead of DD.MM.YY, so they sort and can
+-- be compared. Two-digit years are read like Go does: 69-99 are 19xx.
+UPDATE transactions SET booking_date =
+    CASE WHEN substr(booking_date, 7, 2) >= '69' THEN '19' ELSE '20' END
+    || substr(booking_date, 7, 2) || '-' || substr(booking_date, 4, 2) || '-' || substr(booking_date, 1, 2)
+WHERE booking_date LIKE '__.__.__';
+
+UPDATE transactions SET valuta_date =
+    CASE WHEN substr(valuta_date, 7, 2) >= '69' THEN '19' ELSE '20' END
+    || substr(valuta_date, 7, 2) || '-' || substr(valuta_date, 4, 2) || '-' || substr(valuta_date, 1, 2)
+WHERE valuta_date LIKE '__.__.__';
+
+UPDATE balances SET date =
+    CASE WHEN substr(date, 7, 2) >= '69' THEN '19' ELSE '20' END
+    || substr(date, 7, 2) || '-' || substr(date, 4, 2) || '-' || substr(date, 1, 2)
+WHERE date LIKE '__.__.__';
+
+CREATE INDEX transactions_account_valuta_date ON transactions (account, valuta_date);
+CREATE INDEX transactions_beneficiary ON transactions (beneficiary);
@@ -0,0 +1,2 @@
+DROP TABLE transaction_labels;
+DROP TABLE labels;
@@ -0,0 +1,2 @@
+DROP TABLE transaction_categories;
+DROP TABLE categories;
@@ -0,0 +1,190 @@
+package sql
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/ibihim/banking-csv-cli/pkg/transactions"
+)
+
+// transactionColumns are the columns read by scanTransaction.
+const transactionColumns = `
+	id, account, booking_date, valuta_date, COALESCE(booking_text, ''), COALESCE(purpose, ''),
+	COALESCE(creditor_id, ''), COALESCE(mandate_ref, ''), COALESCE(customer_ref, ''),
+	COALESCE(collector_ref, ''), orig_amount, chargeback_fee, COALESCE(beneficiary, ''),
+	COALESCE(account_number, ''), COALESCE(bic, ''), amount, currency,
+	COALESCE(additional_details, ''), fitid, fingerprint, import_id
+`
+
+// sortColumns are the columns that are sorted by. Ties are broken by id.
+var sortColumns = map[transactions.SortOrder]string{
+	transactions.SortDate:            "valuta_date",
+	transactions.SortDateDesc:        "valuta_date",
+	transactions.SortAmount:          "amount",
+	transactions.SortAmountDesc:      "amount",
+	transactions.SortBeneficiary:     "COALESCE(beneficiary, '')",
+	transactions.SortBeneficiaryDesc: "COALESCE(beneficiary, '')",
+}
+
+// QueryTransactions returns the transactions selected by the query.
+func (d *Database) QueryTransactions(q *transactions.Query) (*transactions.Page, error) {
+	sort := q.Order()
+	sortColumn, ok := sortColumns[sort]
+	if !ok {
+		return nil, fmt.Errorf("unknown sort order %q", sort)
+	}
+	direction, comparison := "ASC", ">"
+	if sort.Descending() {
+		direction, comparison = "DESC", "<"
+	}
+
+	var (
+		where []string
+		args  []any
+	)
+	if !q.From.IsZero() {
+		where = append(where, "valuta_date >= ?")
+		args = append(args, q.From.Format(dateLayout))
+	}
+	if !q.To.IsZero() {
+		where = append(where, "valuta_date <= ?")
+		args = append(args, q.To.Format(dateLayout))
+	}
+	if q.Account != "" {
+		where = append(where, "account = ?")
+		args = append(args, q.Account)
+	}
+	if q.Beneficiary != "" {
+		where = append(where, "beneficiary "+d.dialect.like+` ? ESCAPE '\'`)
+		args = append(args, likePattern(q.Beneficiary))
+	}
+	for _, bound := range []struct {
+		amount     *transactions.Money
+		comparison string
+	}{
+		{q.MinAmount, ">="},
+		{q.MaxAmount, "<="},
+	} {
+		if bound.amount == nil {
+			continue
+		}
+		// Amounts of other currencies have other minor units.
+		if bound.amount.Currency != "" {
+			where = append(where, "currency = ?")
+			args = append(args, bound.amount.Currency)
+		}
+		where = append(where, "amount "+bound.comparison+" ?")
+		args = append(args, bound.amount.Minor)
+	}
+	if q.Text != "" {
+		var text []string
+		for _, column := range []string{"beneficiary", "purpose", "booking_text", "additional_details"} {
+			text = append(text, column+" "+d.dialect.like+` ? ESCAPE '\'`)
+			args = append(args, likePattern(q.Text))
+		}
+		where = append(where, "("+strings.Join(text, " OR ")+")")
+	}
+	if q.Label != "" {
+		where = append(where, labelFilter)
+		args = append(args, q.Label)
+	}
+	if q.Category != "" {
+		filter, filterArgs, err := d.categoryFilter(q.Category)
+		if err != nil {
+			return nil, err
+		}
+		where = append(where, filter)
+		args = append(args, filterArgs...)
+	}
+	if q.Cursor != "" {
+		value, id, err := transactions.DecodeCursor(q)
+		if err != nil {
+			return nil, err
+		}
+		where = append(where, fmt.Sprintf("(%s, id) %s (?, ?)", sortColumn, comparison))
+		args = append(args, value, id)
+	}
+
+	query := "SELECT " + transactionColumns + " FROM transactions"
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += fmt.Sprintf(" ORDER BY %s %s, id %s", sortColumn, direction, direction)
+	if q.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, q.Limit)
+	}
+	if q.Offset > 0 {
+		if q.Limit <= 0 {
+			query += " LIMIT " + d.dialect.noLimit
+		}
+		query += " OFFSET ?"
+		args = append(args, q.Offset)
+	}
+
+	rows, err := d.db.Query(d.rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	page := &transactions.Page{}
+	for rows.Next() {
+		t, err := scanTransaction(rows)
+		if err != nil {
+			return nil, err
+		}
+		page.Transactions = append(page.Transactions, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if err := d.loadLabels(page.Transactions); err != nil {
+		return nil, fmt.Errorf("failed to load labels: %w", err)
+	}
+	if err := d.loadCategories(page.Transactions); err != nil {
+		return nil, fmt.Errorf("failed to load categories: %w", err)
+	}
+
+	if q.Limit > 0 && len(page.Transactions) == q.Limit {
+		page.NextCursor = transactions.EncodeCursor(q, page.Transactions[len(page.Transactions)-1])
+	}
+
+	return page, nil
+}
+
+// scanTransaction reads the transactionColumns of a row.
+func scanTransaction(row interface{ Scan(...any) error }) (*transactions.Transaction, error) {
+	t := &transactions.Transaction{}
+	bookingDate, valutaDate := dateValue{}, dateValue{}
+	fingerprint := sql.NullString{}
+	importID := sql.NullInt64{}
+
+	err := row.Scan(
+		&t.ID, &t.Account, &bookingDate, &valutaDate, &t.BookingText, &t.Purpose,
+		&t.CreditorID, &t.MandateRef, &t.CustomerRef,
+		&t.CollectorRef, &t.OrigAmount.Minor, &t.ChargebackFee.Minor, &t.Beneficiary,
+		&t.AccountNumber, &t.BIC, &t.Amount.Minor, &t.Amount.Currency,
+		&t.AdditionalDetails, &t.FITID, &fingerprint, &importID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	t.Fingerprint = fingerprint.String
+	t.ImportID = importID.Int64
+	t.OrigAmount.Currency = t.Amount.Currency
+	t.ChargebackFee.Currency = t.Amount.Currency
+
+	t.BookingDate = bookingDate.Time
+	t.ValutaDate = valutaDate.Time
+
+	return t, nil
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// likePattern matches values that contain s.
+func likePattern(s string) string {
+	return "%" + likeEscaper.Replace(s) + "%"
+}
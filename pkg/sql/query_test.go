@@ -0,0 +1,118 @@
+package sql
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ibihim/banking-csv-cli/pkg/transactions"
+)
+
+func TestQueryTransactionsCursor(t *testing.T) {
+	d := newTestDatabase(t)
+
+	date := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	for i, amount := range []int64{-350, 310000, -85000, -350, -4217} {
+		_, err := d.AddTransaction(&transactions.Transaction{
+			Account:     "DE02120300000000202051",
+			BookingDate: date,
+			ValutaDate:  date,
+			Purpose:     fmt.Sprint("Transaction ", i),
+			Amount:      transactions.Money{Minor: amount, Currency: "EUR"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	min := transactions.Money{Minor: -50000, Currency: "EUR"}
+	q := &transactions.Query{Sort: transactions.SortAmountDesc, MinAmount: &min, Limit: 2}
+	var got []int64
+	for {
+		page, err := d.QueryTransactions(q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, tr := range page.Transactions {
+			got = append(got, tr.Amount.Minor)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		q.Cursor = page.NextCursor
+	}
+	if want := []int64{310000, -350, -350, -4217}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	q.Sort = transactions.SortAmount
+	if _, err := d.QueryTransactions(q); !errors.Is(err, transactions.ErrCursorMismatch) {
+		t.Errorf("got error %v, want %v", err, transactions.ErrCursorMismatch)
+	}
+}
+
+func TestQueryTransactionsLabelsAndCategories(t *testing.T) {
+	d := newTestDatabase(t)
+
+	// More transactions than fit into one IN list.
+	date := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	var ids []int64
+	for i := 0; i < maxInList+10; i++ {
+		id, err := d.AddTransaction(&transactions.Transaction{
+			Account:     "DE02120300000000202051",
+			BookingDate: date,
+			ValutaDate:  date,
+			Purpose:     fmt.Sprint("Transaction ", i),
+			Amount:      transactions.Money{Minor: -100, Currency: "EUR"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	first, last := ids[0], ids[len(ids)-1]
+	if _, err := d.LabelTransactions("Fixkosten", []int64{first, last}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.LabelTransactions("Abo", []int64{last}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.AddCategory("Wohnen/Miete"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.CategorizeTransactions("Wohnen/Miete", []int64{last}, false); err != nil {
+		t.Fatal(err)
+	}
+
+	page, err := d.QueryTransactions(&transactions.Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	labels := map[int64][]string{}
+	categories := map[int64]string{}
+	for _, tr := range page.Transactions {
+		if len(tr.Labels) > 0 {
+			labels[tr.ID] = tr.Labels
+		}
+		if tr.Category != "" {
+			categories[tr.ID] = tr.Category
+		}
+	}
+	if want := map[int64][]string{first: {"Fixkosten"}, last: {"Abo", "Fixkosten"}}; !reflect.DeepEqual(labels, want) {
+		t.Errorf("labels: got %v, want %v", labels, want)
+	}
+	if want := map[int64]string{last: "Wohnen/Miete"}; !reflect.DeepEqual(categories, want) {
+		t.Errorf("categories: got %v, want %v", categories, want)
+	}
+
+	// A category filter selects the transactions of its subcategories.
+	page, err = d.QueryTransactions(&transactions.Query{Category: "Wohnen"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Transactions) != 1 || page.Transactions[0].ID != last {
+		t.Errorf("got %d transactions of Wohnen, want only transaction %d", len(page.Transactions), last)
+	}
+}
@@ -2,7 +2,6 @@ package table
 
 import (
 	"fmt"
-	"strconv"
 
 	"github.com/charmbracelet/bubbles/table"
 	"github.com/charmbracelet/lipgloss"
@@ -67,12 +66,12 @@ func (t *Table) action(row int, action string) error {
 	return nil
 }
 
-func newRow(date, beneficiary, description string, sum float64) table.Row {
+func newRow(date, beneficiary, description string, sum transactions.Totals) table.Row {
 	return table.Row([]string{
 		date,
 		beneficiary,
 		description,
-		strconv.FormatFloat(sum, 'f', 2, 64),
+		sum.String(),
 	})
 }
 
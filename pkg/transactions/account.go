@@ -0,0 +1,82 @@
+package transactions
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// AccountType is the kind of an account.
+type AccountType string
+
+const (
+	// AccountChecking is a checking account, a Girokonto.
+	AccountChecking AccountType = "checking"
+	// AccountSavings is a savings account, a Tagesgeld- or Sparkonto.
+	AccountSavings AccountType = "savings"
+	// AccountCreditCard is the account of a credit card.
+	AccountCreditCard AccountType = "credit-card"
+	// AccountCash is the cash in a wallet.
+	AccountCash AccountType = "cash"
+)
+
+// AccountTypes are all account types.
+var AccountTypes = []AccountType{AccountChecking, AccountSavings, AccountCreditCard, AccountCash}
+
+// ParseAccountType returns the account type of the given name.
+func ParseAccountType(s string) (AccountType, error) {
+	for _, t := range AccountTypes {
+		if string(t) == s {
+			return t, nil
+		}
+	}
+
+	names := make([]string, 0, len(AccountTypes))
+	for _, t := range AccountTypes {
+		names = append(names, string(t))
+	}
+	return "", fmt.Errorf("unknown account type %q, expected one of: %s", s, strings.Join(names, ", "))
+}
+
+// ErrNoAccount is returned if an account does not exist.
+var ErrNoAccount = errors.New("account does not exist")
+
+// Account is an account that transactions are booked on.
+type Account struct {
+	// ID is the id of the account.
+	ID int64
+	// IBAN is the account number that Transaction.Account refers to. It is
+	// any unique identifier for accounts without an IBAN, like cash.
+	IBAN string
+	// Bank is the name of the bank that holds the account.
+	Bank string
+	// Nickname is a short, unique name of the account, like "Giro".
+	Nickname string
+	// Owner is the name of the owner of the account.
+	Owner string
+	// Currency is the ISO 4217 code of the currency of the account.
+	Currency string
+	// Type is the kind of the account.
+	Type AccountType
+	// OpeningBalance is the balance of the account at the beginning of the
+	// OpeningDate, before its transactions.
+	OpeningBalance Money
+	// OpeningDate is the date of the OpeningBalance, zero if it is unknown.
+	OpeningDate time.Time
+}
+
+// Name returns the nickname of the account, or its IBAN if it has none.
+func (a *Account) Name() string {
+	if a.Nickname != "" {
+		return a.Nickname
+	}
+
+	return a.IBAN
+}
+
+// NormalizeIBAN removes the spaces of an IBAN, as it is often written in
+// groups of four, and upper-cases it.
+func NormalizeIBAN(iban string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(iban), ""))
+}
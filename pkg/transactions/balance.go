@@ -0,0 +1,26 @@
+package transactions
+
+import "time"
+
+// BalanceType is the type of a balance as stated by the bank.
+type BalanceType string
+
+const (
+	// OpeningBalance is the balance at the beginning of a statement.
+	OpeningBalance BalanceType = "opening"
+	// ClosingBalance is the balance at the end of a statement.
+	ClosingBalance BalanceType = "closing"
+)
+
+type Balance struct {
+	// ID is the id of the balance.
+	ID int64
+	// Account is the account number of the account under view.
+	Account string
+	// Type is the type of the balance.
+	Type BalanceType
+	// Date is the date the balance refers to.
+	Date time.Time
+	// Amount is the balance of the account, including its currency.
+	Amount Money
+}
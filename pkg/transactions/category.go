@@ -0,0 +1,171 @@
+package transactions
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// CategorySeparator separates the names of a category path, like
+	// "Wohnen/Miete".
+	CategorySeparator = "/"
+	// Uncategorized is the category of transactions that have none. It is
+	// reserved, no category can be named like it.
+	Uncategorized = "Unkategorisiert"
+	// DirectTransactions groups the transactions of a category itself,
+	// apart from the ones of its subcategories. It is reserved, no category
+	// can be named like it.
+	DirectTransactions = "(direkt)"
+)
+
+// ErrNoCategory is returned if a category does not exist.
+var ErrNoCategory = errors.New("category does not exist")
+
+// Category is a category of transactions, like "Wohnen/Miete". Categories
+// form a tree, and each transaction has one category. Like labels,
+// categories attach to the fingerprints of transactions.
+type Category struct {
+	// ID is the id of the category.
+	ID int64
+	// ParentID is the id of the parent category, 0 for top level categories.
+	ParentID int64
+	// Name is the name of the category, unique among its siblings.
+	Name string
+	// Path is the names of the category and its ancestors, top level first,
+	// joined by CategorySeparator.
+	Path string
+	// Transactions is the number of transactions of the category, without
+	// those of its subcategories.
+	Transactions int
+}
+
+// DefaultCategories is a taxonomy of German households. The parents of a
+// category are listed before it.
+var DefaultCategories = []string{
+	"Wohnen",
+	"Wohnen/Miete",
+	"Wohnen/Nebenkosten",
+	"Wohnen/Strom",
+	"Wohnen/Internet & Telefon",
+	"Wohnen/Rundfunkbeitrag",
+	"Wohnen/Einrichtung",
+	"Lebensmittel",
+	"Lebensmittel/Supermarkt",
+	"Lebensmittel/Bäckerei",
+	"Lebensmittel/Getränke",
+	"Mobilität",
+	"Mobilität/Kraftstoff",
+	"Mobilität/ÖPNV",
+	"Mobilität/Bahn",
+	"Mobilität/Auto",
+	"Mobilität/Taxi & Carsharing",
+	"Versicherungen",
+	"Versicherungen/Haftpflicht",
+	"Versicherungen/Hausrat",
+	"Versicherungen/Krankenversicherung",
+	"Versicherungen/Kfz-Versicherung",
+	"Versicherungen/Lebensversicherung",
+	"Gesundheit",
+	"Gesundheit/Apotheke",
+	"Gesundheit/Arzt",
+	"Gesundheit/Drogerie",
+	"Einkäufe",
+	"Einkäufe/Kleidung",
+	"Einkäufe/Elektronik",
+	"Einkäufe/Online-Handel",
+	"Freizeit",
+	"Freizeit/Restaurants",
+	"Freizeit/Kultur",
+	"Freizeit/Sport",
+	"Freizeit/Urlaub",
+	"Freizeit/Abonnements",
+	"Kinder",
+	"Kinder/Kita & Schule",
+	"Kinder/Taschengeld",
+	"Bildung",
+	"Finanzen",
+	"Finanzen/Bankgebühren",
+	"Finanzen/Kredite",
+	"Finanzen/Sparen",
+	"Finanzen/Steuern",
+	"Einkommen",
+	"Einkommen/Gehalt",
+	"Einkommen/Kindergeld",
+	"Einkommen/Erstattungen",
+	"Einkommen/Zinsen",
+	"Sonstiges",
+	"Sonstiges/Bargeld",
+	"Sonstiges/Geschenke",
+	"Sonstiges/Spenden",
+	"Umbuchungen",
+}
+
+// ParseCategoryPath returns the names of a category path, top level first.
+func ParseCategoryPath(s string) ([]string, error) {
+	var names []string
+	for _, name := range strings.Split(s, CategorySeparator) {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return nil, fmt.Errorf("invalid category %q, its names must not be empty", s)
+		}
+		if name == DirectTransactions {
+			return nil, fmt.Errorf("invalid category %q, %s is reserved for the transactions of a category itself", s, DirectTransactions)
+		}
+		names = append(names, name)
+	}
+	if names[0] == Uncategorized {
+		return nil, fmt.Errorf("invalid category %q, %s is reserved for transactions without a category", s, Uncategorized)
+	}
+
+	return names, nil
+}
+
+// CategoryPath joins the names of a category path.
+func CategoryPath(names []string) string {
+	return strings.Join(names, CategorySeparator)
+}
+
+// SetCategoryPaths sets the paths of the categories by their parents, and
+// sorts them like a tree: each category is followed by its subcategories,
+// siblings are ordered by name.
+func SetCategoryPaths(categories []*Category) {
+	byID := make(map[int64]*Category, len(categories))
+	for _, c := range categories {
+		byID[c.ID] = c
+	}
+
+	names := make(map[int64][]string, len(categories))
+	for _, c := range categories {
+		var path []string
+		for p := c; p != nil; p = byID[p.ParentID] {
+			path = append([]string{p.Name}, path...)
+		}
+		names[c.ID] = path
+		c.Path = CategoryPath(path)
+	}
+
+	sort.SliceStable(categories, func(i, j int) bool {
+		a, b := names[categories[i].ID], names[categories[j].ID]
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return len(a) < len(b)
+	})
+}
+
+// Subcategories returns the ids of the category of a path and of all of its
+// subcategories. The paths of the categories must be set.
+func Subcategories(categories []*Category, path string) []int64 {
+	var ids []int64
+	for _, c := range categories {
+		if c.Path == path || strings.HasPrefix(c.Path, path+CategorySeparator) {
+			ids = append(ids, c.ID)
+		}
+	}
+
+	return ids
+}
@@ -0,0 +1,58 @@
+package transactions
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+)
+
+// Fingerprint identifies a transaction independent of the file it was
+// imported from, so the same transaction in overlapping exports is only
+// stored once. Transactions with a FITID are identified by it, others by the
+// account, the dates, the amount, the counterparty's IBAN, the mandate and
+// end-to-end references and a hash of the purpose.
+//
+// Genuinely identical transactions, like two coffees on the same day, are
+// told apart by the occurrence, which counts them within a file.
+func Fingerprint(t *Transaction, occurrence int) string {
+	h := sha256.New()
+	for _, field := range fingerprintFields(t) {
+		h.Write([]byte(field))
+		h.Write([]byte{0})
+	}
+	h.Write([]byte(strconv.Itoa(occurrence)))
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// FingerprintKey returns the fields of the fingerprint without the
+// occurrence. Transactions with the same key are identical.
+func FingerprintKey(t *Transaction) string {
+	return strings.Join(fingerprintFields(t), "\x00")
+}
+
+func fingerprintFields(t *Transaction) []string {
+	account := normalizeAccount(t.Account)
+	if t.FITID != "" {
+		return []string{account, "fitid", t.FITID}
+	}
+
+	purpose := sha256.Sum256([]byte(strings.Join(strings.Fields(t.Purpose), " ")))
+
+	return []string{
+		account,
+		t.BookingDate.Format("2006-01-02"),
+		t.ValutaDate.Format("2006-01-02"),
+		strconv.FormatInt(t.Amount.Minor, 10),
+		strings.ToUpper(t.Amount.Currency),
+		normalizeAccount(t.AccountNumber),
+		strings.TrimSpace(t.MandateRef),
+		strings.TrimSpace(t.CustomerRef),
+		hex.EncodeToString(purpose[:]),
+	}
+}
+
+func normalizeAccount(s string) string {
+	return strings.ToUpper(strings.ReplaceAll(s, " ", ""))
+}
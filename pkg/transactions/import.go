@@ -0,0 +1,101 @@
+package transactions
+
+import (
+	"errors"
+	"time"
+)
+
+// Import is a file that was imported into the database.
+type Import struct {
+	// ID is the id of the import.
+	ID int64
+	// Filename is the path of the imported file.
+	Filename string
+	// Checksum is the SHA-256 checksum of the file's content.
+	Checksum string
+	// Format is the name of the importer that read the file.
+	Format string
+	// ImportedAt is the time of the import.
+	ImportedAt time.Time
+	// Inserted is the number of transactions that were added.
+	Inserted int
+	// Skipped is the number of known transactions that were not added.
+	Skipped int
+	// Duplicates is the number of transactions that were already known.
+	Duplicates int
+	// Rejected is the number of records that could not be parsed.
+	Rejected int
+}
+
+// DuplicatePolicy decides what happens to a transaction that is already
+// known by its fingerprint.
+type DuplicatePolicy string
+
+const (
+	// DuplicateSkip does not import known transactions.
+	DuplicateSkip DuplicatePolicy = "skip"
+	// DuplicateFail aborts the import if a transaction is known.
+	DuplicateFail DuplicatePolicy = "fail"
+	// DuplicateInsert imports known transactions as further occurrences.
+	DuplicateInsert DuplicatePolicy = "insert"
+)
+
+// DuplicatePolicies are all policies.
+var DuplicatePolicies = []DuplicatePolicy{DuplicateSkip, DuplicateFail, DuplicateInsert}
+
+// ImportResult describes what happened to the transactions of an import.
+type ImportResult struct {
+	// Inserted is the number of transactions that were added.
+	Inserted int
+	// Skipped is the number of known transactions that were not added.
+	Skipped int
+	// Duplicates are the transactions that were already known. Depending on
+	// the policy they were skipped or inserted.
+	Duplicates []*Transaction
+	// Rows are the transactions of the batch in order, with what happened
+	// to them.
+	Rows []*ImportRow
+}
+
+// RowStatus is what happened to a transaction of an import.
+type RowStatus string
+
+const (
+	// RowNew is a transaction that was not known.
+	RowNew RowStatus = "new"
+	// RowDuplicate is a transaction that was known by its fingerprint.
+	RowDuplicate RowStatus = "duplicate"
+	// RowConflict is a new transaction that looks like a known one, having
+	// the same account, booking date and amount. Either one of them was
+	// changed by the bank, or they are different transactions. Conflicts
+	// are only detected by previews of imports.
+	RowConflict RowStatus = "conflict"
+)
+
+// ImportRow is a transaction of an import.
+type ImportRow struct {
+	// Status is what happened to the transaction.
+	Status RowStatus
+	// Transaction is the imported transaction.
+	Transaction *Transaction
+	// ConflictID is the id of the known transaction a conflicting one looks
+	// like.
+	ConflictID int64
+}
+
+// ImportBatch is the content of a file that is imported.
+type ImportBatch struct {
+	// Import is recorded with the counts of the result and referenced by
+	// the added transactions.
+	Import *Import
+	// Transactions are the transactions of the file.
+	Transactions []*Transaction
+	// Balances are the balances of the file.
+	Balances []*Balance
+	// Result is set by Import.
+	Result *ImportResult
+}
+
+// ErrDuplicate is returned when importing a known transaction, if the
+// policy is DuplicateFail.
+var ErrDuplicate = errors.New("duplicate transaction")
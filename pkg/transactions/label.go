@@ -0,0 +1,32 @@
+package transactions
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrNoLabel is returned if a label does not exist.
+var ErrNoLabel = errors.New("label does not exist")
+
+// Label is a custom label of transactions, like "Urlaub 2023". Labels attach
+// to the fingerprints of transactions, so they survive importing a file
+// again.
+type Label struct {
+	// ID is the id of the label.
+	ID int64
+	// Name is the unique name of the label.
+	Name string
+	// Transactions is the number of labeled transactions.
+	Transactions int
+}
+
+// ParseLabelName returns the name of a label without surrounding spaces.
+func ParseLabelName(s string) (string, error) {
+	name := strings.TrimSpace(s)
+	if name == "" {
+		return "", fmt.Errorf("invalid label %q, it must not be empty", s)
+	}
+
+	return name, nil
+}
@@ -2,48 +2,97 @@ package transactions
 
 import (
 	"strconv"
+	"strings"
 )
 
 func noop(*Sum, string) error { return nil }
 
 func NewSummary(ts []*Transaction) *Sum {
-	sum := &Sum{
-		title:   "Transactions",
-		visible: true,
+	sum := NewSum("Transactions")
+	for _, t := range ts {
+		addToSummary(sum, t)
+	}
 
-		orderedSums: []*Sum{},
-		mappedSums:  map[string]*Sum{},
+	return sum
+}
+
+// NewAccountSummary groups the transactions by account first. Accounts are
+// titled by their nickname, transactions of unknown accounts by their
+// account number.
+func NewAccountSummary(ts []*Transaction, accounts []*Account) *Sum {
+	names := map[string]string{}
+	for _, a := range accounts {
+		names[a.IBAN] = a.Name()
 	}
 
+	sum := NewSum("Transactions")
 	for _, t := range ts {
-		yearStr := strconv.Itoa(t.ValutaDate.Year())
-		if !sum.Has(yearStr) {
-			sum.AddSum(NewSum(yearStr))
+		name, ok := names[t.Account]
+		if !ok {
+			name = t.Account
 		}
-		year := sum.Sum(yearStr)
+		if !sum.Has(name) {
+			sum.AddSum(NewSum(name))
+		}
+		addToSummary(sum.Sum(name), t)
+	}
+
+	return sum
+}
 
-		monthStr := t.ValutaDate.Month().String()
-		if !year.Has(monthStr) {
-			year.AddSum(NewSum(monthStr))
+// NewCategorySummary groups the transactions along the category tree first.
+// The transactions of a category itself are grouped as DirectTransactions,
+// so that they do not mix with its subcategories. Transactions without a
+// category are grouped as Uncategorized.
+func NewCategorySummary(ts []*Transaction) *Sum {
+	sum := NewSum("Transactions")
+	for _, t := range ts {
+		path := []string{Uncategorized}
+		if t.Category != "" {
+			path = append(strings.Split(t.Category, CategorySeparator), DirectTransactions)
 		}
-		month := year.Sum(monthStr)
 
-		if !month.Has(t.Beneficiary) {
-			month.AddSum(NewSum(t.Beneficiary))
+		category := sum
+		for _, name := range path {
+			if !category.Has(name) {
+				category.AddSum(NewSum(name))
+			}
+			category = category.Sum(name)
 		}
-		beneficiary := month.Sum(t.Beneficiary)
-		beneficiary.AddSum(&Sum{
-			title: t.Purpose,
-			sum:   t.Amount,
+		addToSummary(category, t)
+	}
+
+	return sum
+}
 
-			visible: false,
+// addToSummary adds the transaction to the sum, grouped by year, month and
+// beneficiary.
+func addToSummary(sum *Sum, t *Transaction) {
+	yearStr := strconv.Itoa(t.ValutaDate.Year())
+	if !sum.Has(yearStr) {
+		sum.AddSum(NewSum(yearStr))
+	}
+	year := sum.Sum(yearStr)
 
-			orderedSums: []*Sum{},
-			mappedSums:  map[string]*Sum{},
-		})
+	monthStr := t.ValutaDate.Month().String()
+	if !year.Has(monthStr) {
+		year.AddSum(NewSum(monthStr))
 	}
+	month := year.Sum(monthStr)
 
-	return sum
+	if !month.Has(t.Beneficiary) {
+		month.AddSum(NewSum(t.Beneficiary))
+	}
+	beneficiary := month.Sum(t.Beneficiary)
+	beneficiary.AddSum(&Sum{
+		title: t.Purpose,
+		sum:   t.Amount,
+
+		visible: false,
+
+		orderedSums: []*Sum{},
+		mappedSums:  map[string]*Sum{},
+	})
 }
 
 func NewSum(title string) *Sum {
@@ -57,7 +106,7 @@ func NewSum(title string) *Sum {
 
 type Sum struct {
 	title string
-	sum   float64
+	sum   Money
 
 	visible bool
 
@@ -107,16 +156,20 @@ func (s *Sum) Has(title string) bool {
 	return ok
 }
 
-func (s *Sum) Total() float64 {
+// Total returns the totals per currency, as amounts of different
+// currencies are not added up.
+func (s *Sum) Total() Totals {
 	// if this is a leaf, return the sum.
 	if len(s.orderedSums) == 0 {
-		return s.sum
+		return Totals{s.sum}
 	}
 
 	// if this is a branch, return the sum of all children.
-	var total float64
+	var total Totals
 	for _, sum := range s.orderedSums {
-		total += sum.Total()
+		for _, t := range sum.Total() {
+			total = total.Add(t)
+		}
 	}
 	return total
 }
@@ -0,0 +1,91 @@
+package transactions
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeTree writes the titles and totals of a sum and its children down to
+// the years, indented by their depth.
+func writeTree(b *strings.Builder, s *Sum, depth int) {
+	fmt.Fprintf(b, "%s%s %s\n", strings.Repeat("  ", depth), s.Title(), s.Total())
+	for _, child := range s.Sums() {
+		if s.Title() == DirectTransactions || s.Title() == Uncategorized {
+			fmt.Fprintf(b, "%s%s %s\n", strings.Repeat("  ", depth+1), child.Title(), child.Total())
+			continue
+		}
+		writeTree(b, child, depth+1)
+	}
+}
+
+func TestNewCategorySummary(t *testing.T) {
+	march := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	sum := NewCategorySummary([]*Transaction{
+		{ValutaDate: march, Beneficiary: "Hausverwaltung", Category: "Wohnen/Miete", Amount: NewMoney(-85000, "EUR")},
+		{ValutaDate: march, Beneficiary: "Baumarkt", Category: "Wohnen", Amount: NewMoney(-4999, "EUR")},
+		{ValutaDate: march, Beneficiary: "Stadtwerke", Category: "Wohnen/2024", Amount: NewMoney(-12000, "EUR")},
+		{ValutaDate: march, Beneficiary: "Bäckerei", Amount: NewMoney(-250, "EUR")},
+	})
+
+	var b strings.Builder
+	writeTree(&b, sum, 0)
+
+	// A subcategory named like a year does not mix with the transactions of
+	// its parent.
+	want := `Transactions -1022.49
+  Wohnen -1019.99
+    Miete -850.00
+      (direkt) -850.00
+        2024 -850.00
+    (direkt) -49.99
+      2024 -49.99
+    2024 -120.00
+      (direkt) -120.00
+        2024 -120.00
+  Unkategorisiert -2.50
+    2024 -2.50
+`
+	if got := b.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestParseCategoryPathReserved(t *testing.T) {
+	for _, s := range []string{Uncategorized, DirectTransactions, "Wohnen/" + DirectTransactions} {
+		if _, err := ParseCategoryPath(s); err == nil {
+			t.Errorf("parsed reserved category %q", s)
+		}
+	}
+}
+
+func TestNewAccountSummary(t *testing.T) {
+	march := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	sum := NewAccountSummary([]*Transaction{
+		{Account: "DE02120300000000202051", ValutaDate: march, Beneficiary: "Hausverwaltung", Amount: NewMoney(-85000, "EUR")},
+		{Account: "DE89370400440532013000", ValutaDate: march, Beneficiary: "Sparplan", Amount: NewMoney(20000, "EUR")},
+		{Account: "DE02120300000000202051", ValutaDate: march, Beneficiary: "Arbeitgeber AG", Amount: NewMoney(310000, "EUR")},
+		{Account: "DE44500105175407324931", ValutaDate: march, Beneficiary: "Ryokan", Amount: NewMoney(-35000, "JPY")},
+	}, []*Account{
+		{IBAN: "DE02120300000000202051", Nickname: "Giro"},
+		{IBAN: "DE89370400440532013000"},
+	})
+
+	var got []string
+	for _, account := range sum.Sums() {
+		got = append(got, account.Title()+" "+account.Total().String())
+	}
+	// Accounts without nickname and unknown ones are titled by their IBAN.
+	want := []string{
+		"Giro 2250.00",
+		"DE89370400440532013000 200.00",
+		"DE44500105175407324931 -35000",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got, want := sum.Total().String(), "2450.00 EUR, -35000 JPY"; got != want {
+		t.Errorf("total: got %q, want %q", got, want)
+	}
+}
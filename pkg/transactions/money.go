@@ -0,0 +1,189 @@
+package transactions
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// minorUnits are the currencies that do not have two decimal places.
+var minorUnits = map[string]int{
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0,
+	"KRW": 0, "PYG": 0, "RWF": 0, "UGX": 0, "VND": 0, "VUV": 0, "XAF": 0,
+	"XOF": 0, "XPF": 0,
+}
+
+// Decimals returns the number of decimal places of a currency.
+func Decimals(currency string) int {
+	if d, ok := minorUnits[strings.ToUpper(currency)]; ok {
+		return d
+	}
+	return 2
+}
+
+// Money is an exact amount of money.
+type Money struct {
+	// Minor is the amount in minor units of the currency, e.g. cents.
+	Minor int64
+	// Currency is the ISO 4217 code of the currency.
+	Currency string
+}
+
+// NewMoney creates money from an amount in minor units.
+func NewMoney(minor int64, currency string) Money {
+	return Money{Minor: minor, Currency: currency}
+}
+
+// ParseMoney parses a decimal amount like "-1234.56". The amount must not
+// have more decimal places than the currency.
+func ParseMoney(s, currency string) (Money, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Money{Currency: currency}, nil
+	}
+
+	negative := false
+	switch s[0] {
+	case '-':
+		negative = true
+		s = s[1:]
+	case '+':
+		s = s[1:]
+	}
+
+	integer, fraction, _ := strings.Cut(s, ".")
+	decimals := Decimals(currency)
+	if len(fraction) > decimals {
+		// Trailing zeros do not change the amount, e.g. "12.3400".
+		trimmed := strings.TrimRight(fraction[decimals:], "0")
+		if trimmed != "" {
+			return Money{}, fmt.Errorf("amount %q has more than %d decimal places", s, decimals)
+		}
+		fraction = fraction[:decimals]
+	}
+	fraction += strings.Repeat("0", decimals-len(fraction))
+
+	if integer == "" {
+		integer = "0"
+	}
+	if !isDigits(integer) || (fraction != "" && !isDigits(fraction)) {
+		return Money{}, fmt.Errorf("invalid amount %q", s)
+	}
+
+	minor, err := strconv.ParseInt(integer+fraction, 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("invalid amount %q: %w", s, err)
+	}
+	if negative {
+		minor = -minor
+	}
+
+	return Money{Minor: minor, Currency: currency}, nil
+}
+
+// ErrCurrencyMismatch is returned when amounts of different currencies are
+// added, since they are not converted.
+var ErrCurrencyMismatch = errors.New("currencies do not match")
+
+// Add returns the sum of both amounts. An amount without currency, like
+// the zero value, takes the currency of the other one. It fails if the
+// currencies differ.
+func (m Money) Add(o Money) (Money, error) {
+	currency := m.Currency
+	switch {
+	case currency == "":
+		currency = o.Currency
+	case o.Currency != "" && o.Currency != currency:
+		return Money{}, fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, currency, o.Currency)
+	}
+
+	return Money{Minor: m.Minor + o.Minor, Currency: currency}, nil
+}
+
+// Neg returns the negated amount.
+func (m Money) Neg() Money {
+	return Money{Minor: -m.Minor, Currency: m.Currency}
+}
+
+// IsNegative returns true if the amount is below zero.
+func (m Money) IsNegative() bool {
+	return m.Minor < 0
+}
+
+// IsZero returns true if the amount is zero.
+func (m Money) IsZero() bool {
+	return m.Minor == 0
+}
+
+// String returns the amount as decimal, e.g. "-1234.56", without currency.
+func (m Money) String() string {
+	decimals := Decimals(m.Currency)
+
+	minor := m.Minor
+	sign := ""
+	if minor < 0 {
+		sign = "-"
+		minor = -minor
+	}
+
+	s := strconv.FormatInt(minor, 10)
+	if decimals == 0 {
+		return sign + s
+	}
+	if len(s) <= decimals {
+		s = strings.Repeat("0", decimals-len(s)+1) + s
+	}
+
+	return sign + s[:len(s)-decimals] + "." + s[len(s)-decimals:]
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// Totals are the sums of amounts per currency, ordered by currency.
+type Totals []Money
+
+// Add returns the totals with the amount added to the total of its
+// currency.
+func (ts Totals) Add(m Money) Totals {
+	for i, t := range ts {
+		if t.Currency == m.Currency {
+			ts[i].Minor += m.Minor
+			return ts
+		}
+	}
+
+	ts = append(ts, m)
+	sort.Slice(ts, func(i, j int) bool {
+		return ts[i].Currency < ts[j].Currency
+	})
+
+	return ts
+}
+
+// String returns the totals like "-12.34 EUR, 3500 JPY". A single total
+// is written without currency, like Money.String.
+func (ts Totals) String() string {
+	switch len(ts) {
+	case 0:
+		return Money{}.String()
+	case 1:
+		return ts[0].String()
+	}
+
+	totals := make([]string, len(ts))
+	for i, t := range ts {
+		totals[i] = strings.TrimSpace(t.String() + " " + t.Currency)
+	}
+
+	return strings.Join(totals, ", ")
+}
@@ -0,0 +1,125 @@
+package transactions
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseMoney(t *testing.T) {
+	for _, tc := range []struct {
+		amount   string
+		currency string
+		want     Money
+		wantErr  bool
+	}{
+		{amount: "-1234.56", currency: "EUR", want: Money{Minor: -123456, Currency: "EUR"}},
+		{amount: "+12.3", currency: "EUR", want: Money{Minor: 1230, Currency: "EUR"}},
+		{amount: ".5", currency: "EUR", want: Money{Minor: 50, Currency: "EUR"}},
+		{amount: "12.3400", currency: "EUR", want: Money{Minor: 1234, Currency: "EUR"}},
+		{amount: "", currency: "EUR", want: Money{Currency: "EUR"}},
+		{amount: "3500", currency: "JPY", want: Money{Minor: 3500, Currency: "JPY"}},
+		{amount: "1.234", currency: "KWD", want: Money{Minor: 1234, Currency: "KWD"}},
+		{amount: "12.345", currency: "EUR", wantErr: true},
+		{amount: "3500.5", currency: "JPY", wantErr: true},
+		{amount: "1,00", currency: "EUR", wantErr: true},
+		{amount: "--1", currency: "EUR", wantErr: true},
+	} {
+		t.Run(tc.amount+" "+tc.currency, func(t *testing.T) {
+			got, err := ParseMoney(tc.amount, tc.currency)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("got %v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestMoneyString(t *testing.T) {
+	for _, tc := range []struct {
+		money Money
+		want  string
+	}{
+		{NewMoney(-123456, "EUR"), "-1234.56"},
+		{NewMoney(5, "EUR"), "0.05"},
+		{NewMoney(-5, "EUR"), "-0.05"},
+		{NewMoney(3500, "JPY"), "3500"},
+		{NewMoney(1234, "KWD"), "1.234"},
+		{Money{}, "0.00"},
+	} {
+		if got := tc.money.String(); got != tc.want {
+			t.Errorf("got %q, want %q", got, tc.want)
+		}
+	}
+}
+
+func TestMoneyAdd(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		a, b    Money
+		want    Money
+		wantErr bool
+	}{
+		{name: "same currency", a: NewMoney(150, "EUR"), b: NewMoney(-50, "EUR"), want: NewMoney(100, "EUR")},
+		{name: "zero value", a: Money{}, b: NewMoney(3500, "JPY"), want: NewMoney(3500, "JPY")},
+		{name: "to zero value", a: NewMoney(3500, "JPY"), b: Money{}, want: NewMoney(3500, "JPY")},
+		{name: "mixed currencies", a: NewMoney(100, "EUR"), b: NewMoney(100, "JPY"), wantErr: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := tc.a.Add(tc.b)
+			if tc.wantErr {
+				if !errors.Is(err, ErrCurrencyMismatch) {
+					t.Fatalf("got %v and error %v, want %v", got, err, ErrCurrencyMismatch)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestTotals(t *testing.T) {
+	var totals Totals
+	for _, m := range []Money{NewMoney(-1000, "JPY"), NewMoney(250, "EUR"), NewMoney(-3500, "JPY"), NewMoney(-50, "EUR")} {
+		totals = totals.Add(m)
+	}
+
+	if got, want := totals.String(), "2.00 EUR, -4500 JPY"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got, want := (Totals{NewMoney(-1234, "EUR")}).String(), "-12.34"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got, want := Totals(nil).String(), "0.00"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestSumTotalMixedCurrencies(t *testing.T) {
+	march := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	sum := NewSummary([]*Transaction{
+		{ValutaDate: march, Beneficiary: "Ramen Shop", Amount: NewMoney(-3500, "JPY")},
+		{ValutaDate: march, Beneficiary: "Bakery", Amount: NewMoney(-250, "EUR")},
+		{ValutaDate: march, Beneficiary: "Ramen Shop", Amount: NewMoney(-1200, "JPY")},
+	})
+
+	if got, want := sum.Total().String(), "-2.50 EUR, -4700 JPY"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got, want := sum.Sum("2024").Sum("March").Sum("Ramen Shop").Total().String(), "-4700"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,195 @@
+package transactions
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SortOrder is the order of the transactions returned by a query.
+type SortOrder string
+
+const (
+	// SortDate sorts by valuta date, oldest first.
+	SortDate SortOrder = "date"
+	// SortDateDesc sorts by valuta date, newest first.
+	SortDateDesc SortOrder = "-date"
+	// SortAmount sorts by amount, smallest first.
+	SortAmount SortOrder = "amount"
+	// SortAmountDesc sorts by amount, largest first.
+	SortAmountDesc SortOrder = "-amount"
+	// SortBeneficiary sorts by beneficiary, alphabetically.
+	SortBeneficiary SortOrder = "beneficiary"
+	// SortBeneficiaryDesc sorts by beneficiary, reverse alphabetically.
+	SortBeneficiaryDesc SortOrder = "-beneficiary"
+)
+
+// SortOrders are all sort orders.
+var SortOrders = []SortOrder{
+	SortDate, SortDateDesc, SortAmount, SortAmountDesc, SortBeneficiary, SortBeneficiaryDesc,
+}
+
+// ParseSortOrder returns the sort order of the given name.
+func ParseSortOrder(s string) (SortOrder, error) {
+	for _, o := range SortOrders {
+		if string(o) == s {
+			return o, nil
+		}
+	}
+
+	names := make([]string, 0, len(SortOrders))
+	for _, o := range SortOrders {
+		names = append(names, string(o))
+	}
+	return "", fmt.Errorf("unknown sort order %q, expected one of: %s", s, strings.Join(names, ", "))
+}
+
+// Descending returns true if the order is descending.
+func (o SortOrder) Descending() bool {
+	return strings.HasPrefix(string(o), "-")
+}
+
+// Query selects transactions. Empty fields do not filter.
+type Query struct {
+	// From is the first valuta date.
+	From time.Time
+	// To is the last valuta date.
+	To time.Time
+	// Account is the account under view.
+	Account string
+	// Beneficiary is a part of the beneficiary, ignoring case.
+	Beneficiary string
+	// MinAmount is the smallest amount. Only transactions in its currency
+	// match, or in any currency if it has none.
+	MinAmount *Money
+	// MaxAmount is the largest amount, like MinAmount.
+	MaxAmount *Money
+	// Text is searched in the beneficiary, the purpose, the booking text and
+	// the additional details, ignoring case.
+	Text string
+	// Label is the name of a label of the transactions.
+	Label string
+	// Category is the path of a category of the transactions, including its
+	// subcategories. Uncategorized selects the transactions without one.
+	Category string
+
+	// Sort is the order of the transactions, SortDate by default.
+	Sort SortOrder
+	// Limit is the maximum number of transactions, 0 for all of them.
+	Limit int
+	// Offset is the number of transactions that are skipped.
+	Offset int
+	// Cursor continues after the last transaction of a previous page, see
+	// Page. It is faster than Offset on large tables.
+	Cursor string
+}
+
+// Page is the result of a query.
+type Page struct {
+	// Transactions are the transactions of the page.
+	Transactions []*Transaction
+	// NextCursor continues the query after this page. It is empty if there
+	// are no more transactions.
+	NextCursor string
+}
+
+// SortKey returns the value of the transaction that is sorted by. Ties are
+// broken by the id.
+func (o SortOrder) SortKey(t *Transaction) any {
+	switch o {
+	case SortAmount, SortAmountDesc:
+		return t.Amount.Minor
+	case SortBeneficiary, SortBeneficiaryDesc:
+		return t.Beneficiary
+	default:
+		return t.ValutaDate.Format("2006-01-02")
+	}
+}
+
+// ErrCursorMismatch is returned for a cursor of a query with another sort
+// order or other filters.
+var ErrCursorMismatch = errors.New("the cursor belongs to a query with another sort order or other filters")
+
+// Order returns the sort order of the query, SortDate by default.
+func (q *Query) Order() SortOrder {
+	if q.Sort == "" {
+		return SortDate
+	}
+
+	return q.Sort
+}
+
+// filterHash returns a short hash of the filters of the query. Limit and
+// Offset are left out, so the size of pages may change.
+func (q *Query) filterHash() string {
+	amount := func(m *Money) string {
+		if m == nil {
+			return ""
+		}
+		return strconv.FormatInt(m.Minor, 10) + " " + m.Currency
+	}
+	fields := []string{
+		q.From.Format("2006-01-02"), q.To.Format("2006-01-02"), q.Account, q.Beneficiary,
+		amount(q.MinAmount), amount(q.MaxAmount), q.Text, q.Label, q.Category,
+	}
+	sum := sha256.Sum256([]byte(strings.Join(fields, "\x00")))
+
+	return hex.EncodeToString(sum[:4])
+}
+
+// EncodeCursor returns a cursor that continues the query after the
+// transaction. It contains the sort order and a hash of the filters of the
+// query, and the sort key and the id of the transaction.
+func EncodeCursor(q *Query, t *Transaction) string {
+	order := q.Order()
+	var value string
+	switch key := order.SortKey(t).(type) {
+	case int64:
+		value = "i" + strconv.FormatInt(key, 10)
+	case string:
+		value = "s" + key
+	}
+
+	return base64.RawURLEncoding.EncodeToString([]byte(
+		string(order) + ":" + q.filterHash() + ":" + strconv.FormatInt(t.ID, 10) + ":" + value,
+	))
+}
+
+// DecodeCursor returns the sort key and the id of the transaction the cursor
+// of the query continues after. It returns ErrCursorMismatch if the cursor
+// was returned for a query with another sort order or other filters.
+func DecodeCursor(q *Query) (any, int64, error) {
+	cursor := q.Cursor
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid cursor %q: %w", cursor, err)
+	}
+
+	fields := strings.SplitN(string(b), ":", 4)
+	if len(fields) != 4 || fields[3] == "" {
+		return nil, 0, fmt.Errorf("invalid cursor %q", cursor)
+	}
+	if SortOrder(fields[0]) != q.Order() || fields[1] != q.filterHash() {
+		return nil, 0, ErrCursorMismatch
+	}
+	id, err := strconv.ParseInt(fields[2], 10, 64)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid cursor %q: %w", cursor, err)
+	}
+
+	value := fields[3]
+	if value[0] == 'i' {
+		i, err := strconv.ParseInt(value[1:], 10, 64)
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid cursor %q: %w", cursor, err)
+		}
+		return i, id, nil
+	}
+
+	return value[1:], id, nil
+}
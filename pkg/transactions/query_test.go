@@ -0,0 +1,89 @@
+package transactions
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCursor(t *testing.T) {
+	tr := &Transaction{
+		ID:          42,
+		ValutaDate:  time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		Beneficiary: "Müller: Bäckerei",
+		Amount:      Money{Minor: -350, Currency: "EUR"},
+	}
+	min := Money{Minor: -1000, Currency: "EUR"}
+
+	for _, tc := range []struct {
+		name    string
+		query   Query
+		wantKey any
+	}{
+		{name: "default order", query: Query{}, wantKey: "2024-03-01"},
+		{name: "amount", query: Query{Sort: SortAmountDesc, MinAmount: &min}, wantKey: int64(-350)},
+		{name: "beneficiary with colon", query: Query{Sort: SortBeneficiary, Text: "brot"}, wantKey: "Müller: Bäckerei"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			q := tc.query
+			q.Cursor = EncodeCursor(&tc.query, tr)
+			// The size of the pages may change.
+			q.Limit = 10
+
+			key, id, err := DecodeCursor(&q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if key != tc.wantKey || id != tr.ID {
+				t.Errorf("got %v, %d, want %v, %d", key, id, tc.wantKey, tr.ID)
+			}
+		})
+	}
+}
+
+func TestCursorMismatch(t *testing.T) {
+	tr := &Transaction{ID: 42, Beneficiary: "Bäckerei"}
+	min := Money{Minor: -1000, Currency: "EUR"}
+	other := Money{Minor: -2000, Currency: "EUR"}
+	q := Query{Account: "DE02120300000000202051", MinAmount: &min}
+	cursor := EncodeCursor(&q, tr)
+
+	for _, tc := range []struct {
+		name  string
+		query Query
+	}{
+		{name: "sort order", query: Query{Account: q.Account, MinAmount: &min, Sort: SortDateDesc}},
+		{name: "account", query: Query{Account: "DE89370400440532013000", MinAmount: &min}},
+		{name: "amount", query: Query{Account: q.Account, MinAmount: &other}},
+		{name: "no filters", query: Query{}},
+		{name: "more filters", query: Query{Account: q.Account, MinAmount: &min, Label: "Urlaub"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.query.Cursor = cursor
+			if _, _, err := DecodeCursor(&tc.query); !errors.Is(err, ErrCursorMismatch) {
+				t.Errorf("got error %v, want %v", err, ErrCursorMismatch)
+			}
+		})
+	}
+
+	// The same filters match, also if the amount is another pointer.
+	same := min
+	if _, _, err := DecodeCursor(&Query{Account: q.Account, MinAmount: &same, Cursor: cursor}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCursorInvalid(t *testing.T) {
+	hash := (&Query{}).filterHash()
+	for _, cursor := range []string{
+		"not base64!",
+		base64.RawURLEncoding.EncodeToString([]byte("abc")),
+		base64.RawURLEncoding.EncodeToString([]byte("date:" + hash + ":x:s2024-03-01")),
+		base64.RawURLEncoding.EncodeToString([]byte("date:" + hash + ":1:ix")),
+	} {
+		if _, _, err := DecodeCursor(&Query{Cursor: cursor}); err == nil || errors.Is(err, ErrCursorMismatch) {
+			t.Errorf("%q: got error %v", cursor, err)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package transactions
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ErrNoStartingBalance is returned if the balance of an account cannot be
+// computed, as it has neither an opening balance nor statement balances.
+var ErrNoStartingBalance = errors.New("account has neither an opening balance nor statement balances")
+
+// RunningBalance is the balance of an account after a transaction.
+type RunningBalance struct {
+	// Transaction is the transaction the balance includes last.
+	Transaction *Transaction
+	// Balance is the balance after the transaction.
+	Balance Money
+}
+
+// BalanceCheck compares a balance stated by the bank with the computed one.
+type BalanceCheck struct {
+	// Stated is the balance stated by the bank.
+	Stated *Balance
+	// Computed is the balance computed from the transactions.
+	Computed Money
+	// Difference is Stated minus Computed. It is positive if transactions
+	// with a positive sum are missing, or ones with a negative sum are
+	// duplicated.
+	Difference Money
+}
+
+// Gap is a date range in which the transactions do not add up to the
+// balances stated by the bank.
+type Gap struct {
+	// From is the first booking date of the range.
+	From time.Time
+	// To is the last booking date of the range.
+	To time.Time
+	// Difference is the amount that is missing in the range.
+	Difference Money
+	// Suspects are transactions of the range that would close the gap if
+	// they were duplicates.
+	Suspects []*Transaction
+}
+
+// Reconciliation is the result of Reconcile.
+type Reconciliation struct {
+	// Start is the balance all others are computed from.
+	Start *Balance
+	// Balances are the running balances, in booking order.
+	Balances []*RunningBalance
+	// Checks compare the stated closing balances with the computed ones, in
+	// order.
+	Checks []*BalanceCheck
+	// Gaps are the ranges where the difference changes.
+	Gaps []*Gap
+}
+
+// balanceAt is a point in the booking order a balance refers to.
+type balanceAt struct {
+	date time.Time
+	// afterDate is true if the balance includes the transactions of the
+	// date.
+	afterDate bool
+}
+
+// pointOf returns the point of a balance.
+func pointOf(b *Balance) balanceAt {
+	return balanceAt{date: b.Date, afterDate: b.Type == ClosingBalance}
+}
+
+// before returns true if p is earlier in the booking order than o.
+func (p balanceAt) before(o balanceAt) bool {
+	if !p.date.Equal(o.date) {
+		return p.date.Before(o.date)
+	}
+
+	return !p.afterDate && o.afterDate
+}
+
+// includes returns true if the balance at p includes the transaction.
+func (p balanceAt) includes(t *Transaction) bool {
+	if p.afterDate {
+		return !t.BookingDate.After(p.date)
+	}
+
+	return t.BookingDate.Before(p.date)
+}
+
+// Reconcile computes the running balances of the transactions of an account
+// and compares them with the closing balances stated by the bank.
+//
+// Balances refer to booking dates. A closing balance includes the
+// transactions of its date, an opening balance excludes them. Opening
+// balances are not checked: they repeat the closing balance of the previous
+// statement, and banks disagree on their dates.
+//
+// The balances start at the opening balance of the account, if it has an
+// opening date, otherwise at the earliest stated balance.
+func Reconcile(account *Account, ts []*Transaction, stated []*Balance) (*Reconciliation, error) {
+	ts = append([]*Transaction(nil), ts...)
+	sort.SliceStable(ts, func(i, j int) bool {
+		if !ts[i].BookingDate.Equal(ts[j].BookingDate) {
+			return ts[i].BookingDate.Before(ts[j].BookingDate)
+		}
+		return ts[i].ID < ts[j].ID
+	})
+
+	var closing []*Balance
+	var opening *Balance
+	for _, b := range stated {
+		switch {
+		case b.Type == ClosingBalance:
+			closing = append(closing, b)
+		case opening == nil || b.Date.Before(opening.Date):
+			opening = b
+		}
+	}
+	sort.SliceStable(closing, func(i, j int) bool {
+		return closing[i].Date.Before(closing[j].Date)
+	})
+
+	r := &Reconciliation{}
+	switch {
+	case account != nil && !account.OpeningDate.IsZero():
+		r.Start = &Balance{
+			Account: account.IBAN,
+			Type:    OpeningBalance,
+			Date:    account.OpeningDate,
+			Amount:  account.OpeningBalance,
+		}
+	case opening != nil && (len(closing) == 0 || pointOf(opening).before(pointOf(closing[0]))):
+		r.Start = opening
+	case len(closing) > 0:
+		r.Start = closing[0]
+	default:
+		return nil, ErrNoStartingBalance
+	}
+
+	// The balance after each transaction is its cumulative sum plus an
+	// offset, so that the balance at the start matches.
+	// Amounts of different currencies are not converted, so all of them
+	// need to be in the currency of the start.
+	cumulative := make([]Money, len(ts))
+	sum := Money{Currency: r.Start.Amount.Currency}
+	atStart := sum
+	start := pointOf(r.Start)
+	for i, t := range ts {
+		var err error
+		if sum, err = sum.Add(t.Amount); err != nil {
+			return nil, fmt.Errorf("transaction %d: %w", t.ID, err)
+		}
+		cumulative[i] = sum
+		if start.includes(t) {
+			atStart = sum
+		}
+	}
+	// From here on, all amounts are in the currency of the start, so they
+	// add up without errors.
+	offset, _ := r.Start.Amount.Add(atStart.Neg())
+
+	for i, t := range ts {
+		balance, _ := offset.Add(cumulative[i])
+		r.Balances = append(r.Balances, &RunningBalance{
+			Transaction: t,
+			Balance:     balance,
+		})
+	}
+
+	// computedAt returns the computed balance at a point.
+	computedAt := func(p balanceAt) Money {
+		// The transactions are sorted, so the included ones are a prefix.
+		n := sort.Search(len(ts), func(i int) bool { return !p.includes(ts[i]) })
+		if n == 0 {
+			return offset
+		}
+		computed, _ := offset.Add(cumulative[n-1])
+		return computed
+	}
+
+	previous := r.Start
+	previousDifference := Money{Currency: r.Start.Amount.Currency}
+	for _, b := range closing {
+		computed := computedAt(pointOf(b))
+		difference, err := b.Amount.Add(computed.Neg())
+		if err != nil {
+			return nil, fmt.Errorf("%s balance of %s: %w", b.Type, b.Date.Format("2006-01-02"), err)
+		}
+		check := &BalanceCheck{
+			Stated:     b,
+			Computed:   computed,
+			Difference: difference,
+		}
+		r.Checks = append(r.Checks, check)
+
+		if check.Difference.Minor != previousDifference.Minor {
+			gap, _ := check.Difference.Add(previousDifference.Neg())
+			r.Gaps = append(r.Gaps, newGap(previous, b, gap, ts))
+		}
+		previous, previousDifference = b, check.Difference
+	}
+
+	return r, nil
+}
+
+// newGap returns the gap between two balances.
+func newGap(from, to *Balance, difference Money, ts []*Transaction) *Gap {
+	// Stated balances may be older than the opening balance of the account.
+	// Their balances are computed backwards from it, so a missing
+	// transaction raises them instead of lowering them.
+	if pointOf(to).before(pointOf(from)) {
+		from, to = to, from
+		difference = difference.Neg()
+	}
+
+	gap := &Gap{From: from.Date, To: to.Date, Difference: difference}
+	// A closing balance includes the transactions of its date, an opening
+	// balance excludes them.
+	if from.Type == ClosingBalance {
+		gap.From = gap.From.AddDate(0, 0, 1)
+	}
+	if to.Type == OpeningBalance {
+		gap.To = gap.To.AddDate(0, 0, -1)
+	}
+
+	for _, t := range ts {
+		if t.BookingDate.Before(gap.From) || t.BookingDate.After(gap.To) {
+			continue
+		}
+		if t.Amount.Minor == -difference.Minor {
+			gap.Suspects = append(gap.Suspects, t)
+		}
+	}
+
+	return gap
+}
@@ -0,0 +1,168 @@
+package transactions
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestReconcileMixedCurrencies(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, time.March, d, 0, 0, 0, 0, time.UTC) }
+
+	_, err := Reconcile(nil,
+		[]*Transaction{
+			{ID: 1, BookingDate: day(2), Amount: NewMoney(-1000, "EUR")},
+			{ID: 2, BookingDate: day(3), Amount: NewMoney(-3500, "JPY")},
+		},
+		[]*Balance{
+			{Type: OpeningBalance, Date: day(1), Amount: NewMoney(10000, "EUR")},
+			{Type: ClosingBalance, Date: day(31), Amount: NewMoney(9000, "EUR")},
+		},
+	)
+	if !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("got %v, want %v", err, ErrCurrencyMismatch)
+	}
+
+	_, err = Reconcile(nil,
+		[]*Transaction{{ID: 1, BookingDate: day(2), Amount: NewMoney(-1000, "EUR")}},
+		[]*Balance{
+			{Type: OpeningBalance, Date: day(1), Amount: NewMoney(10000, "EUR")},
+			{Type: ClosingBalance, Date: day(31), Amount: NewMoney(9000, "USD")},
+		},
+	)
+	if !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("got %v, want %v", err, ErrCurrencyMismatch)
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, time.March, d, 0, 0, 0, 0, time.UTC) }
+	eur := func(minor int64) Money { return NewMoney(minor, "EUR") }
+	opening := func(d int, minor int64) *Balance {
+		return &Balance{Type: OpeningBalance, Date: day(d), Amount: eur(minor)}
+	}
+	closing := func(d int, minor int64) *Balance {
+		return &Balance{Type: ClosingBalance, Date: day(d), Amount: eur(minor)}
+	}
+
+	for _, tc := range []struct {
+		name         string
+		account      *Account
+		transactions []*Transaction
+		stated       []*Balance
+		wantStart    string
+		wantChecks   []string
+		wantGaps     []string
+	}{
+		{
+			name: "balanced",
+			transactions: []*Transaction{
+				{ID: 1, BookingDate: day(2), Amount: eur(-10000)},
+				{ID: 2, BookingDate: day(10), Amount: eur(5000)},
+			},
+			stated:     []*Balance{opening(1, 100000), closing(15, 95000)},
+			wantStart:  "opening 2024-03-01 1000.00",
+			wantChecks: []string{"2024-03-15 stated 950.00 computed 950.00 difference 0.00"},
+		},
+		{
+			// The bank booked 50.00 more than was imported.
+			name: "missing row",
+			transactions: []*Transaction{
+				{ID: 1, BookingDate: day(2), Amount: eur(-10000)},
+				{ID: 2, BookingDate: day(20), Amount: eur(-2000)},
+			},
+			stated:    []*Balance{opening(1, 100000), closing(15, 85000), closing(31, 83000)},
+			wantStart: "opening 2024-03-01 1000.00",
+			wantChecks: []string{
+				"2024-03-15 stated 850.00 computed 900.00 difference -50.00",
+				"2024-03-31 stated 830.00 computed 880.00 difference -50.00",
+			},
+			wantGaps: []string{"2024-03-01 2024-03-15 -50.00 suspects []"},
+		},
+		{
+			// The second 12.99 was imported twice, so both are suspects.
+			name: "duplicated row",
+			transactions: []*Transaction{
+				{ID: 1, BookingDate: day(3), Amount: eur(-1299)},
+				{ID: 2, BookingDate: day(3), Amount: eur(-1299)},
+				{ID: 3, BookingDate: day(5), Amount: eur(-10000)},
+				{ID: 4, BookingDate: day(12), Amount: eur(-1299)},
+			},
+			stated:    []*Balance{opening(1, 100000), closing(10, 88701), closing(20, 87402)},
+			wantStart: "opening 2024-03-01 1000.00",
+			wantChecks: []string{
+				"2024-03-10 stated 887.01 computed 874.02 difference 12.99",
+				"2024-03-20 stated 874.02 computed 861.03 difference 12.99",
+			},
+			wantGaps: []string{"2024-03-01 2024-03-10 12.99 suspects [1 2]"},
+		},
+		{
+			// The account was opened after the statement, its balances are
+			// computed backwards. The 30.00 of the 7th was imported twice.
+			name:    "opening balance after the stated balances",
+			account: &Account{IBAN: "DE02120300000000202051", OpeningDate: day(10), OpeningBalance: eur(50000)},
+			transactions: []*Transaction{
+				{ID: 1, BookingDate: day(3), Amount: eur(-2000)},
+				{ID: 2, BookingDate: day(7), Amount: eur(-3000)},
+				{ID: 3, BookingDate: day(7), Amount: eur(-3000)},
+				{ID: 4, BookingDate: day(10), Amount: eur(-4000)},
+			},
+			stated:     []*Balance{opening(1, 55000), closing(5, 53000)},
+			wantStart:  "opening 2024-03-10 500.00",
+			wantChecks: []string{"2024-03-05 stated 530.00 computed 560.00 difference -30.00"},
+			wantGaps:   []string{"2024-03-06 2024-03-09 30.00 suspects [2 3]"},
+		},
+		{
+			// The opening balance excludes the transactions of its date, the
+			// closing balance includes them, so the opening one is the start.
+			name: "opening and closing on the same day",
+			transactions: []*Transaction{
+				{ID: 1, BookingDate: day(1), Amount: eur(-1000)},
+				{ID: 2, BookingDate: day(1), Amount: eur(-1000)},
+				{ID: 3, BookingDate: day(2), Amount: eur(-500)},
+			},
+			stated:    []*Balance{closing(1, 9000), opening(1, 10000), closing(2, 8500)},
+			wantStart: "opening 2024-03-01 100.00",
+			wantChecks: []string{
+				"2024-03-01 stated 90.00 computed 80.00 difference 10.00",
+				"2024-03-02 stated 85.00 computed 75.00 difference 10.00",
+			},
+			wantGaps: []string{"2024-03-01 2024-03-01 10.00 suspects [1 2]"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := Reconcile(tc.account, tc.transactions, tc.stated)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := fmt.Sprintf("%s %s %s", r.Start.Type, r.Start.Date.Format("2006-01-02"), r.Start.Amount); got != tc.wantStart {
+				t.Errorf("start: got %q, want %q", got, tc.wantStart)
+			}
+
+			var checks []string
+			for _, c := range r.Checks {
+				checks = append(checks, fmt.Sprintf("%s stated %s computed %s difference %s",
+					c.Stated.Date.Format("2006-01-02"), c.Stated.Amount, c.Computed, c.Difference))
+			}
+			if !reflect.DeepEqual(checks, tc.wantChecks) {
+				t.Errorf("checks:\ngot  %q\nwant %q", checks, tc.wantChecks)
+			}
+
+			var gaps []string
+			for _, g := range r.Gaps {
+				var suspects []int64
+				for _, s := range g.Suspects {
+					suspects = append(suspects, s.ID)
+				}
+				gaps = append(gaps, fmt.Sprintf("%s %s %s suspects %v",
+					g.From.Format("2006-01-02"), g.To.Format("2006-01-02"), g.Difference, suspects))
+			}
+			if !reflect.DeepEqual(gaps, tc.wantGaps) {
+				t.Errorf("gaps:\ngot  %q\nwant %q", gaps, tc.wantGaps)
+			}
+		})
+	}
+}
@@ -24,19 +24,31 @@ type Transaction struct {
 	// CollectorRef is some id that seems to be specific to the creditor.
 	CollectorRef string
 	// OrigAmount ???
-	OrigAmount float64
+	OrigAmount Money
 	// ChargebackFee is the fee charged by the bank for a chargeback.
-	ChargebackFee float64
+	ChargebackFee Money
 	// Beneficiary is the name of the creditor.
 	Beneficiary string
 	// AccountNumber is the IBAN of the beneficiary.
 	AccountNumber string
 	// BIC is the Bank Identifier Code of the beneficiary.
 	BIC string
-	// Amount is the amount of the transaction.
-	Amount float64
-	// Currency is the currency of the transaction.
-	Currency string
+	// Amount is the amount of the transaction, including its currency.
+	Amount Money
 	// AdditionalDetails describes the current state of the transaction.
 	AdditionalDetails string
+	// FITID is the id the financial institution assigned to the transaction
+	// in an OFX file.
+	FITID string
+	// Fingerprint identifies the transaction across imports, see
+	// Fingerprint.
+	Fingerprint string
+	// ImportID is the id of the import that added the transaction, 0 if it
+	// was added before imports were recorded.
+	ImportID int64
+	// Labels are the names of the labels of the transaction, sorted.
+	Labels []string
+	// Category is the path of the category of the transaction, empty if it
+	// is uncategorized.
+	Category string
 }